@@ -1,30 +1,145 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/makinzm/partial-tree-copy/internal/adapters/sinks"
 	"github.com/makinzm/partial-tree-copy/internal/app"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/differ"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/redact"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
 )
 
+// Exit codes of the copy command
+const (
+	exitOK             = 0
+	exitError          = 1
+	exitUsage          = 2
+	exitNothingMatched = 3
+	exitUnreadable     = 4
+	exitOverBudget     = 5
+	exitSensitive      = 6
+)
+
+// stringList is a flag that can be repeated to collect several values
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// optionalBool is a boolean flag that points *target at its value only when it
+// is given, so that a flag left out falls back to the configuration while
+// --flag=false still overrides it
+type optionalBool struct {
+	target **bool
+}
+
+func (b optionalBool) String() string {
+	if b.target == nil || *b.target == nil {
+		return ""
+	}
+	return strconv.FormatBool(**b.target)
+}
+
+func (b optionalBool) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b.target = &v
+	return nil
+}
+
+func (b optionalBool) IsBoolFlag() bool { return true }
+
+// optionalInt is an integer flag that points *target at its value only when it
+// is given, so that a flag left out falls back to the configuration while an
+// explicit zero still overrides it
+type optionalInt struct {
+	target **int
+}
+
+func (n optionalInt) String() string {
+	if n.target == nil || *n.target == nil {
+		return ""
+	}
+	return strconv.Itoa(**n.target)
+}
+
+func (n optionalInt) Set(value string) error {
+	v, err := strconv.ParseInt(value, 0, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	i := int(v)
+	*n.target = &i
+	return nil
+}
+
+// registerCommonFlags registers the flags shared by the interactive modes and the copy command
+func registerCommonFlags(fs *flag.FlagSet, opts *app.Options) {
+	fs.StringVar(&opts.Format, "format", "",
+		"Output format for copied files ("+strings.Join(copier.FormatNames(), ", ")+", "+copier.FormatTemplate+
+			") (default \""+copier.DefaultFormat+"\")")
+	fs.StringVar(&opts.Template, "template", "", "Path of a Go text/template file used to render copied files")
+	fs.IntVar(&opts.MaxTokens, "max-tokens", 0, "Token budget for the copied payload (0 means unlimited)")
+	fs.Var(optionalBool{&opts.StrictBudget}, "strict-budget", "Refuse to copy, instead of warning, when --max-tokens is exceeded")
+	fs.BoolVar(&opts.ShowIgnored, "show-ignored", false, "Include files matched by .gitignore and other git exclude files")
+	fs.StringVar(&opts.Sink, "sink", "",
+		"Destination of the copied payload ("+strings.Join(sinks.Names(), ", ")+") (default \""+sinks.Default+"\")")
+	fs.StringVar(&opts.Profile, "profile", "", "Start with the selection saved as this profile in .partial-tree-copy/selections")
+	fs.BoolVar(&opts.Modified, "modified", false, "Select files with unstaged git changes")
+	fs.BoolVar(&opts.Staged, "staged", false, "Select files with staged git changes")
+	fs.BoolVar(&opts.Untracked, "untracked", false, "Select files git does not track (ignored files excluded)")
+	fs.StringVar(&opts.Since, "since", "", "Select files changed since the current branch forked from this git ref (e.g. main)")
+	fs.StringVar(&opts.Content, "content", "",
+		"What to copy for each file ("+strings.Join(copier.ContentModeNames(), ", ")+") (default \""+copier.DefaultContentMode+"\")")
+	fs.StringVar(&opts.DiffRef, "diff-ref", "", "Git ref diffs are computed against (default \""+differ.DefaultRef+"\")")
+	fs.Var(optionalInt{&opts.DepsDepth}, "deps-depth", "Import `levels` followed when adding the Go packages the selection imports, or that import it (0 means all)")
+	fs.Var(optionalBool{&opts.Companions}, "companions", "Select and deselect companion files, such as tests, along with their files")
+	fs.BoolVar(&opts.NoRedact, "no-redact", false, "Copy file contents verbatim instead of replacing secrets such as API keys with placeholders")
+	fs.StringVar(&opts.MaxFileSize, "max-file-size", "",
+		"Size above which files are copied as a stub with their size and hash, e.g. 512KB or 2MB (0 means no limit) (default \"1MB\")")
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "copy" {
+		os.Exit(runCopy(os.Args[2:]))
+	}
+
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: partial-tree-copy [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: partial-tree-copy [options] [path[:lines]...]\n")
+		fmt.Fprintf(os.Stderr, "       partial-tree-copy copy [options] [path[:lines]...]\n\n")
 		fmt.Fprintf(os.Stderr, "A CLI tool for selectively copying files from your project directory tree.\n\n")
 		fmt.Fprintf(os.Stderr, "Modes:\n")
-		fmt.Fprintf(os.Stderr, "  (default)  Terminal UI - navigate with keyboard, select files, copy to clipboard\n")
-		fmt.Fprintf(os.Stderr, "  --web      Browser GUI - point-and-click file selection with content preview\n\n")
+		fmt.Fprintf(os.Stderr, "  (default)  Terminal UI - navigate with keyboard, select files, copy to the --sink destination\n")
+		fmt.Fprintf(os.Stderr, "  --web      Browser GUI - point-and-click file selection with content preview\n")
+		fmt.Fprintf(os.Stderr, "  copy       Non-interactive - select files by glob patterns (see 'copy --help')\n\n")
+		fmt.Fprintf(os.Stderr, "Files given as arguments start selected; 'path:120-180,200-210' selects only those lines.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
 
-	webMode := flag.Bool("web", false, "Launch browser-based GUI instead of TUI")
-	webPort := flag.Int("port", 8080, "Port for the web UI server (used with --web)")
+	var opts app.Options
+	flag.BoolVar(&opts.WebMode, "web", false, "Launch browser-based GUI instead of TUI")
+	flag.IntVar(&opts.WebPort, "port", 8080, "Port for the web UI server (used with --web)")
+	flag.BoolVar(&opts.NoWatch, "no-watch", false, "Read each directory once instead of refreshing the tree as files change")
+	registerCommonFlags(flag.CommandLine, &opts)
 	flag.Parse()
+	opts.Files = flag.Args()
 
 	// Create and initialize the application
-	application, err := app.NewApplication(*webMode, *webPort)
+	application, err := app.NewApplication(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing application: %v\n", err)
 		os.Exit(1)
@@ -36,3 +151,83 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runCopy runs the non-interactive copy command and returns its exit code
+func runCopy(args []string) int {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: partial-tree-copy copy [options] [path[:lines]...]\n\n")
+		fmt.Fprintf(os.Stderr, "Copies files matching glob patterns, or the files given as arguments, without opening a UI.\n")
+		fmt.Fprintf(os.Stderr, "Patterns are relative to the current directory; '**' matches any number of directories.\n")
+		fmt.Fprintf(os.Stderr, "A file argument may end with line ranges to copy only those lines.\n\n")
+		fmt.Fprintf(os.Stderr, "Examples:\n")
+		fmt.Fprintf(os.Stderr, "  partial-tree-copy copy --include 'internal/**/*.go' --exclude '**/*_test.go' -o -\n")
+		fmt.Fprintf(os.Stderr, "  partial-tree-copy copy internal/app/app.go:120-180 README.md -o -\n\n")
+		fmt.Fprintf(os.Stderr, "Exit codes:\n")
+		fmt.Fprintf(os.Stderr, "  %d  success\n", exitOK)
+		fmt.Fprintf(os.Stderr, "  %d  error\n", exitError)
+		fmt.Fprintf(os.Stderr, "  %d  invalid arguments\n", exitUsage)
+		fmt.Fprintf(os.Stderr, "  %d  no files matched\n", exitNothingMatched)
+		fmt.Fprintf(os.Stderr, "  %d  some files could not be read or no longer exist (the rest was copied), or a file argument names no file\n", exitUnreadable)
+		fmt.Fprintf(os.Stderr, "  %d  refused by --strict-budget\n", exitOverBudget)
+		fmt.Fprintf(os.Stderr, "  %d  refused because sensitive files (e.g. .env) are selected and the config blocks them\n\n", exitSensitive)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	var opts app.Options
+	var copyOpts app.CopyOptions
+	var include, exclude stringList
+	registerCommonFlags(fs, &opts)
+	fs.Var(&include, "include", "Glob pattern of files to copy (repeatable; default: all files, or only the --profile and git-selected files)")
+	fs.Var(&exclude, "exclude", "Glob pattern of files to leave out (repeatable)")
+	fs.StringVar(&copyOpts.Output, "output", "", "Where to write the payload: a --sink value, - (stdout), or a file path (default: the --sink destination)")
+	fs.StringVar(&copyOpts.Output, "o", "", "Shorthand for --output")
+	// File arguments may come before, between, or after the options
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		opts.Files = append(opts.Files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	copyOpts.Include = include
+	copyOpts.Exclude = exclude
+
+	application, err := app.NewApplication(opts)
+	if errors.Is(err, app.ErrMissingFile) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUnreadable
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing application: %v\n", err)
+		return exitError
+	}
+
+	err = application.RunCopy(copyOpts)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, app.ErrNothingMatched):
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitNothingMatched
+	case errors.Is(err, app.ErrUnreadableFiles):
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUnreadable
+	case errors.Is(err, tokens.ErrBudgetExceeded):
+		fmt.Fprintf(os.Stderr, "Copy refused: %v\n", err)
+		return exitOverBudget
+	case errors.Is(err, redact.ErrSensitiveFiles):
+		fmt.Fprintf(os.Stderr, "Copy refused: %v\n", err)
+		return exitSensitive
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/makinzm/partial-tree-copy/internal/usecases/companions"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/redact"
+)
+
+// Dir is the per-project directory holding partial-tree-copy settings and state
+const Dir = ".partial-tree-copy"
+
+// FileName is the name of the configuration file inside Dir
+const FileName = "config.json"
+
+// ProfilesDir is the directory inside Dir holding saved selection profiles
+const ProfilesDir = "selections"
+
+// Config holds the per-project defaults read from .partial-tree-copy/config.json.
+// Command-line flags take precedence over these values.
+type Config struct {
+	Format       string `json:"format,omitempty"`       // Default output format name
+	Template     string `json:"template,omitempty"`     // Path of a text/template output file, relative to the project root
+	MaxTokens    int    `json:"maxTokens,omitempty"`    // Token budget for the copied payload
+	StrictBudget bool   `json:"strictBudget,omitempty"` // Refuse to copy when MaxTokens is exceeded
+	Sink         string `json:"sink,omitempty"`         // Default destination of the copied payload (e.g. "osc52")
+	Content      string `json:"content,omitempty"`      // Default content mode: "full", "diff", "full+diff", or "outline"
+	DiffRef      string `json:"diffRef,omitempty"`      // Git ref diffs are computed against
+	DepsDepth    int    `json:"depsDepth,omitempty"`    // Import levels followed when adding dependencies; 0 follows them all
+	MaxFileSize  string `json:"maxFileSize,omitempty"`  // Size above which files are copied as a stub, e.g. "2MB"; "0" means no limit
+
+	AutoCompanions bool              `json:"autoCompanions,omitempty"` // Select and deselect companion files, such as tests, with their files
+	Companions     []companions.Rule `json:"companions,omitempty"`     // Companion patterns in addition to the built-in conventions
+
+	Redact redact.Rules `json:"redact"` // Secret redaction and the handling of sensitive files
+}
+
+// Load reads the configuration from the project rooted at rootDir.
+// A missing configuration file yields an empty Config.
+func Load(rootDir string) (*Config, error) {
+	path := filepath.Join(rootDir, Dir, FileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	// Resolve relative paths against the project root
+	if cfg.Template != "" && !filepath.IsAbs(cfg.Template) {
+		cfg.Template = filepath.Join(rootDir, cfg.Template)
+	}
+
+	return &cfg, nil
+}
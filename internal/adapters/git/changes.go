@@ -0,0 +1,127 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// ChangeRepository reads file changes from the git repository containing a root directory
+type ChangeRepository struct {
+	root string
+}
+
+// NewChangeRepository creates a ChangeRepository for the repository containing rootDir
+func NewChangeRepository(rootDir string) *ChangeRepository {
+	return &ChangeRepository{root: rootDir}
+}
+
+// Changes runs "git status" and converts its paths to be relative to the root
+func (r *ChangeRepository) Changes() ([]entities.FileChange, error) {
+	top, err := r.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	top = strings.TrimSpace(top)
+
+	out, err := r.git("status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []entities.FileChange
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 4 {
+			continue
+		}
+		x, y, path := record[0], record[1], record[3:]
+
+		// Renames and copies are followed by the original path
+		if x == 'R' || x == 'C' {
+			i++
+		}
+
+		rel, ok := r.relative(top, path)
+		if !ok {
+			continue
+		}
+		changes = append(changes, entities.FileChange{
+			Path:      rel,
+			Staged:    x != ' ' && x != '?',
+			Modified:  y != ' ' && y != '?',
+			Untracked: x == '?',
+			Added:     x == 'A',
+			Deleted:   x == 'D' || y == 'D',
+		})
+	}
+	return changes, nil
+}
+
+// ChangedSince diffs the working tree against the merge base of ref and HEAD
+func (r *ChangeRepository) ChangedSince(ref string) ([]string, error) {
+	base, err := r.git("merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := r.git("diff", "--name-only", "-z", "--relative", "--diff-filter=d", strings.TrimSpace(base))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// relative converts a path relative to the repository top level into one
+// relative to the root, reporting false for paths outside the root
+func (r *ChangeRepository) relative(top, path string) (string, bool) {
+	root, err := filepath.EvalSymlinks(r.root)
+	if err != nil {
+		root = r.root
+	}
+	rel, err := filepath.Rel(root, filepath.Join(top, filepath.FromSlash(path)))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// git runs a git command in the root directory and returns its standard output
+func (r *ChangeRepository) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), &commandError{command: args[0], stderr: strings.TrimSpace(stderr.String()), err: err}
+	}
+	return string(out), nil
+}
+
+// commandError is a failed git command, reported with git's own message
+type commandError struct {
+	command string
+	stderr  string
+	err     error
+}
+
+func (e *commandError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("git %s: %s", e.command, e.stderr)
+	}
+	return fmt.Sprintf("git %s: %v", e.command, e.err)
+}
+
+func (e *commandError) Unwrap() error { return e.err }
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test against a real repository?
+//
+// git reports paths relative to the repository top level while the tool
+// works relative to the directory it was started in. Running git in a
+// scratch repository checks the porcelain parsing and the path conversion.
+
+// run executes git in dir and fails the test on error
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Starting in a subdirectory must yield paths relative to it and leave out
+// changes elsewhere in the repository.
+func TestChangeRepository_RelativeToRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	run(t, repo, "init", "-q", "-b", "main")
+	write(t, filepath.Join(repo, "sub", "kept.go"), "package sub\n")
+	write(t, filepath.Join(repo, "sub", "edited.go"), "package sub\n")
+	write(t, filepath.Join(repo, "outside.go"), "package main\n")
+	run(t, repo, "add", ".")
+	run(t, repo, "commit", "-q", "-m", "initial")
+
+	run(t, repo, "checkout", "-q", "-b", "feature")
+	write(t, filepath.Join(repo, "sub", "edited.go"), "package sub // edited\n")
+	write(t, filepath.Join(repo, "sub", "staged.go"), "package sub\n")
+	run(t, repo, "add", "sub/staged.go")
+	write(t, filepath.Join(repo, "sub", "new", "untracked.go"), "package new\n")
+	write(t, filepath.Join(repo, "outside.go"), "package main // edited\n")
+
+	changes, err := NewChangeRepository(filepath.Join(repo, "sub")).Changes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[string]entities.ChangeStatus{}
+	for _, change := range changes {
+		got[change.Path] = change.Status()
+	}
+	want := map[string]entities.ChangeStatus{
+		"edited.go":        entities.Modified,
+		"staged.go":        entities.Added,
+		"new/untracked.go": entities.Untracked,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+
+	since, err := NewChangeRepository(filepath.Join(repo, "sub")).ChangedSince("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(since, []string{"edited.go", "staged.go"}) {
+		t.Fatalf("changed since main = %v", since)
+	}
+}
+
+// Diffs must cover the three cases a review needs: a modified file, a file
+// that does not exist at the ref, and a file deleted from the working tree.
+func TestDiffRepository_NewModifiedDeleted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	run(t, repo, "init", "-q", "-b", "main")
+	write(t, filepath.Join(repo, "kept.go"), "package kept\n")
+	write(t, filepath.Join(repo, "removed.go"), "package removed\n")
+	run(t, repo, "add", ".")
+	run(t, repo, "commit", "-q", "-m", "initial")
+
+	write(t, filepath.Join(repo, "kept.go"), "package kept // edited\n")
+	write(t, filepath.Join(repo, "added.go"), "package added\n")
+	if err := os.Remove(filepath.Join(repo, "removed.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs := NewDiffRepository(repo)
+	for path, want := range map[string]bool{"kept.go": true, "removed.go": true, "added.go": false} {
+		exists, err := diffs.ExistsAt("HEAD", path)
+		if err != nil || exists != want {
+			t.Fatalf("ExistsAt(%s) = %v, %v; want %v", path, exists, err, want)
+		}
+	}
+
+	modified, err := diffs.Diff("HEAD", "kept.go")
+	if err != nil || !strings.Contains(modified, "+package kept // edited") {
+		t.Errorf("unexpected diff of kept.go (%v):\n%s", err, modified)
+	}
+	deleted, err := diffs.Diff("HEAD", "removed.go")
+	if err != nil || !strings.Contains(deleted, "deleted file mode") {
+		t.Errorf("unexpected diff of removed.go (%v):\n%s", err, deleted)
+	}
+	added, err := diffs.DiffNew("added.go")
+	if err != nil || !strings.Contains(added, "new file mode") || !strings.Contains(added, "+package added") {
+		t.Errorf("unexpected diff of added.go (%v):\n%s", err, added)
+	}
+}
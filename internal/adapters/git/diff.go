@@ -0,0 +1,44 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// DiffRepository computes diffs with the local git repository containing a
+// root directory. It never contacts a remote.
+type DiffRepository struct {
+	repo *ChangeRepository
+}
+
+// NewDiffRepository creates a DiffRepository for the repository containing rootDir
+func NewDiffRepository(rootDir string) *DiffRepository {
+	return &DiffRepository{repo: NewChangeRepository(rootDir)}
+}
+
+// ExistsAt runs "git ls-tree" to look the path up in ref
+func (r *DiffRepository) ExistsAt(ref, path string) (bool, error) {
+	out, err := r.repo.git("ls-tree", "--name-only", ref, "--", path)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Diff runs "git diff" between ref and the working tree, with paths relative to the root
+func (r *DiffRepository) Diff(ref, path string) (string, error) {
+	return r.repo.git("diff", "--relative", ref, "--", path)
+}
+
+// DiffNew runs "git diff --no-index" against /dev/null
+func (r *DiffRepository) DiffNew(path string) (string, error) {
+	out, err := r.repo.git("diff", "--no-index", "--", "/dev/null", path)
+
+	// --no-index exits with status 1 when the files differ, which they always do here
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return out, nil
+	}
+	return out, err
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ExcludeFiles returns the files git reads exclude rules from for the
+// repository containing rootDir, besides .gitignore files, in increasing
+// order of precedence: the global excludes file, then .git/info/exclude.
+// Files that do not exist are still returned; readers should skip them.
+func ExcludeFiles(rootDir string) []string {
+	var files []string
+	if global := globalExcludesFile(); global != "" {
+		files = append(files, global)
+	}
+	if exclude := infoExcludeFile(rootDir); exclude != "" {
+		files = append(files, exclude)
+	}
+	return files
+}
+
+// infoExcludeFile returns the info/exclude file of the repository containing
+// rootDir. Git resolves it, as in worktrees and submodules .git is a file
+// pointing to the git directory, and worktrees share the exclude file of their
+// main repository. Without git, a .git directory is looked for.
+func infoExcludeFile(rootDir string) string {
+	out, err := exec.Command("git", "-C", rootDir, "rev-parse", "--git-path", "info/exclude").Output()
+	if path := strings.TrimSpace(string(out)); err == nil && path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootDir, path)
+		}
+		return path
+	}
+	if gitDir := findGitDir(rootDir); gitDir != "" {
+		return filepath.Join(gitDir, "info", "exclude")
+	}
+	return ""
+}
+
+// TopLevel returns the top directory of the repository containing rootDir, and
+// the slash-separated path of rootDir below it ("a/b/", or "" at the top).
+// ok is false outside a git repository.
+func TopLevel(rootDir string) (top, prefix string, ok bool) {
+	out, err := exec.Command("git", "-C", rootDir, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return "", "", false
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return "", "", false
+	}
+	if len(lines) > 1 {
+		prefix = lines[1]
+	}
+	return lines[0], prefix, true
+}
+
+// globalExcludesFile returns core.excludesFile, or git's default location when it is not set
+func globalExcludesFile() string {
+	if out, err := exec.Command("git", "config", "--get", "core.excludesFile").Output(); err == nil {
+		if path := strings.TrimSpace(string(out)); path != "" {
+			return expandHome(path)
+		}
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// findGitDir walks up from dir looking for a .git directory
+func findGitDir(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ".git")
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
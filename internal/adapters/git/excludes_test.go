@@ -0,0 +1,42 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// In a worktree .git is a file pointing to the git directory, and the exclude
+// file lives in the main repository; looking for a .git directory skips it.
+// TopLevel must report where the tool runs below the top, so the .gitignore
+// files above it apply.
+func TestExcludeFilesAndTopLevel_Worktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main")
+	run(t, dir, "init", "-q", "main")
+	write(t, filepath.Join(main, "a", "x.go"), "package a\n")
+	run(t, main, "add", ".")
+	run(t, main, "commit", "-q", "-m", "init")
+	tree := filepath.Join(dir, "tree")
+	run(t, main, "worktree", "add", "-q", tree)
+
+	files := ExcludeFiles(filepath.Join(tree, "a"))
+	want := filepath.Join(main, ".git", "info", "exclude")
+	if len(files) == 0 || files[len(files)-1] != want {
+		t.Errorf("expected the exclude file %s of the main repository, got %v", want, files)
+	}
+
+	top, prefix, ok := TopLevel(filepath.Join(tree, "a"))
+	if !ok || top != tree || prefix != "a/" {
+		t.Errorf("expected top %s and prefix a/, got %q %q (ok=%v)", tree, top, prefix, ok)
+	}
+	if _, _, ok := TopLevel(dir); ok {
+		t.Error("a directory outside any repository has no top level")
+	}
+}
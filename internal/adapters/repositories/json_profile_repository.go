@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	domain "github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// profileFile is the on-disk representation of a selection profile
+type profileFile struct {
+	Paths []string `json:"paths"` // Root-relative paths with forward slashes
+}
+
+// JSONProfileRepository stores each selection profile as <dir>/<name>.json
+type JSONProfileRepository struct {
+	dir string
+}
+
+// NewJSONProfileRepository creates a repository storing profiles in dir
+func NewJSONProfileRepository(dir string) *JSONProfileRepository {
+	return &JSONProfileRepository{dir: dir}
+}
+
+// SaveProfile writes the profile file, creating the directory if needed
+func (r *JSONProfileRepository) SaveProfile(name string, paths []string) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(profileFile{Paths: paths}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path(name), append(data, '\n'), 0644)
+}
+
+// LoadProfile reads the profile file
+func (r *JSONProfileRepository) LoadProfile(name string) ([]string, error) {
+	data, err := os.ReadFile(r.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrProfileNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var profile profileFile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", r.path(name), err)
+	}
+	return profile.Paths, nil
+}
+
+// ListProfiles returns the names of the *.json files in the directory
+func (r *JSONProfileRepository) ListProfiles() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// path returns the file holding the profile called name
+func (r *JSONProfileRepository) path(name string) string {
+	return filepath.Join(r.dir, name+".json")
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 
@@ -65,3 +66,28 @@ func (r *OSFileRepository) GetRelativePath(target, base string) (string, error)
 func (r *OSFileRepository) WriteToClipboard(content string) error {
 	return clipboard.WriteAll(content)
 }
+
+// Open opens a file for reading it in pieces
+func (r *OSFileRepository) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// ReadHead reads up to n bytes from the start of a file and returns them with the file's size
+func (r *OSFileRepository) ReadHead(path string, n int) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	head := make([]byte, min(int64(n), info.Size()))
+	read, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, 0, err
+	}
+	return head[:read], info.Size(), nil
+}
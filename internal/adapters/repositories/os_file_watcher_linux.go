@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// watchMask selects the inotify events that change the entries of a directory or the contents of its files
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_CLOSE_WRITE | unix.IN_ONLYDIR
+
+// OSFileWatcher is a file watcher implementation using inotify
+type OSFileWatcher struct {
+	fd     int      // The inotify instance, kept apart from file so reads stay non-blocking
+	file   *os.File // Reads events through the runtime poller; closing it ends the read loop
+	events chan entities.FileEvent
+	done   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	dirs   map[int]string // Watched directories by watch descriptor
+}
+
+// NewOSFileWatcher creates an OSFileWatcher that watches no directory yet
+func NewOSFileWatcher() (*OSFileWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &OSFileWatcher{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan entities.FileEvent, 64),
+		done:   make(chan struct{}),
+		dirs:   make(map[int]string),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+// Add starts watching the entries of the directory at path
+func (w *OSFileWatcher) Add(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wd, err := unix.InotifyAddWatch(w.fd, path, watchMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	w.dirs[wd] = path
+	return nil
+}
+
+// Events returns the channel changes are delivered on
+func (w *OSFileWatcher) Events() <-chan entities.FileEvent {
+	return w.events
+}
+
+// Close stops watching every directory
+func (w *OSFileWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
+
+// readEvents delivers the events of the inotify instance until it is closed
+func (w *OSFileWatcher) readEvents() {
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		if !w.parse(buf[:n]) {
+			return
+		}
+	}
+}
+
+// parse delivers the events read into buf; it returns false once the watcher is closed
+func (w *OSFileWatcher) parse(buf []byte) bool {
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + unix.SizeofInotifyEvent
+		offset = nameStart + int(raw.Len)
+		if offset > len(buf) {
+			break
+		}
+		name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+
+		if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+			if !w.send(entities.FileEvent{Op: entities.EventsLost}) {
+				return false
+			}
+			continue
+		}
+
+		w.mu.Lock()
+		dir, ok := w.dirs[int(raw.Wd)]
+		if raw.Mask&unix.IN_IGNORED != 0 {
+			// The directory was removed or unmounted, and its watch with it
+			delete(w.dirs, int(raw.Wd))
+		}
+		w.mu.Unlock()
+		if !ok || name == "" {
+			continue
+		}
+
+		event := entities.FileEvent{Path: filepath.Join(dir, name)}
+		switch {
+		case raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			event.Op = entities.FileCreated
+		case raw.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+			event.Op = entities.FileRemoved
+		case raw.Mask&unix.IN_CLOSE_WRITE != 0:
+			event.Op = entities.FileWritten
+		default:
+			continue
+		}
+		if !w.send(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// send delivers an event, giving up when the watcher is closed
+func (w *OSFileWatcher) send(event entities.FileEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-w.done:
+		return false
+	}
+}
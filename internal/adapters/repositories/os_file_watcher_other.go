@@ -0,0 +1,35 @@
+//go:build !linux
+
+package repositories
+
+import (
+	"errors"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// errWatchUnsupported is returned where there is no file watcher implementation
+var errWatchUnsupported = errors.New("watching files is only supported on Linux")
+
+// OSFileWatcher is a file watcher that is not available on this platform
+type OSFileWatcher struct{}
+
+// NewOSFileWatcher returns an error, as files cannot be watched on this platform
+func NewOSFileWatcher() (*OSFileWatcher, error) {
+	return nil, errWatchUnsupported
+}
+
+// Add returns an error, as files cannot be watched on this platform
+func (w *OSFileWatcher) Add(path string) error {
+	return errWatchUnsupported
+}
+
+// Events returns a channel that never delivers
+func (w *OSFileWatcher) Events() <-chan entities.FileEvent {
+	return nil
+}
+
+// Close does nothing
+func (w *OSFileWatcher) Close() error {
+	return nil
+}
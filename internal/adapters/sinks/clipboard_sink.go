@@ -0,0 +1,23 @@
+package sinks
+
+import (
+	"github.com/makinzm/partial-tree-copy/internal/adapters/clipboard"
+)
+
+// ClipboardSink writes the payload to the system clipboard
+type ClipboardSink struct {
+	service *clipboard.ClipboardService
+}
+
+// NewClipboardSink creates a new ClipboardSink
+func NewClipboardSink() *ClipboardSink {
+	return &ClipboardSink{service: clipboard.NewClipboardService()}
+}
+
+// Name returns a short description of the destination
+func (s *ClipboardSink) Name() string { return Clipboard }
+
+// Write copies the payload to the system clipboard
+func (s *ClipboardSink) Write(content string) error {
+	return s.service.WriteToClipboard(content)
+}
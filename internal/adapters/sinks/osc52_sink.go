@@ -0,0 +1,60 @@
+package sinks
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// OSC52Sink copies the payload through the OSC 52 terminal escape sequence.
+// The terminal emulator sets the clipboard, so it works over SSH and inside
+// containers where no clipboard utility is available.
+type OSC52Sink struct {
+	// open returns the terminal to write the sequence to
+	open func() (io.WriteCloser, error)
+}
+
+// NewOSC52Sink creates a new OSC52Sink writing to the controlling terminal
+func NewOSC52Sink() *OSC52Sink {
+	return &OSC52Sink{open: openTerminal}
+}
+
+// Name returns a short description of the destination
+func (s *OSC52Sink) Name() string { return OSC52 }
+
+// Write emits the escape sequence, wrapped for tmux or screen when running inside them
+func (s *OSC52Sink) Write(content string) error {
+	seq := osc52.New(content)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+
+	terminal, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer terminal.Close()
+
+	_, err = seq.WriteTo(terminal)
+	return err
+}
+
+// openTerminal opens the controlling terminal, falling back to stderr
+func openTerminal() (io.WriteCloser, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		return tty, nil
+	}
+	return nopCloser{os.Stderr}, nil
+}
+
+// nopCloser keeps a shared stream such as stderr open when the sink is done
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
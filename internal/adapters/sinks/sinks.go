@@ -0,0 +1,46 @@
+package sinks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Sink names accepted by New
+const (
+	Clipboard = "clipboard"
+	Stdout    = "stdout"
+	OSC52     = "osc52"
+	Tmux      = "tmux"
+	File      = "file" // Used as "file:PATH"
+)
+
+// Default is the sink used when none is configured
+const Default = Clipboard
+
+// Names returns the accepted sink specifications for help texts
+func Names() []string {
+	return []string{Clipboard, Stdout, OSC52, Tmux, File + ":PATH"}
+}
+
+// New creates the sink described by spec: "clipboard", "stdout" (or "-"),
+// "osc52", "tmux", or "file:PATH". An empty spec selects Default.
+func New(spec string) (repositories.OutputSink, error) {
+	switch spec {
+	case "", Clipboard:
+		return NewClipboardSink(), nil
+	case Stdout, "-":
+		return NewWriterSink(Stdout, os.Stdout), nil
+	case OSC52:
+		return NewOSC52Sink(), nil
+	case Tmux:
+		return NewTmuxSink(), nil
+	}
+
+	if path, ok := strings.CutPrefix(spec, File+":"); ok && path != "" {
+		return NewFileSink(path), nil
+	}
+	return nil, fmt.Errorf("unknown sink %q (available: %s)", spec, strings.Join(Names(), ", "))
+}
@@ -0,0 +1,60 @@
+package sinks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Why test the sink factory?
+//
+// The sink comes from a flag or the config file, so a typo must fail loudly
+// instead of silently writing somewhere else, and "file:PATH" must reach the
+// named file untouched.
+
+// Every documented name must resolve, and unknown names must be rejected.
+func TestNew_ParsesSpecs(t *testing.T) {
+	for spec, name := range map[string]string{
+		"":          Clipboard,
+		"clipboard": Clipboard,
+		"stdout":    Stdout,
+		"-":         Stdout,
+		"osc52":     OSC52,
+		"tmux":      Tmux,
+		"file:a.md": "file:a.md",
+	} {
+		sink, err := New(spec)
+		if err != nil {
+			t.Fatalf("New(%q) returned error: %v", spec, err)
+		}
+		if sink.Name() != name {
+			t.Errorf("New(%q) returned sink %q, want %q", spec, sink.Name(), name)
+		}
+	}
+
+	for _, spec := range []string{"clipbaord", "file:", "file"} {
+		if _, err := New(spec); err == nil {
+			t.Errorf("New(%q) should fail", spec)
+		}
+	}
+}
+
+// A file sink replaces the whole file so repeated copies do not accumulate.
+func TestFileSink_ReplacesContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(path, []byte("previous content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewFileSink(path).Write("payload"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "payload" {
+		t.Fatalf("file contains %q, want %q", data, "payload")
+	}
+}
@@ -0,0 +1,31 @@
+package sinks
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// TmuxSink loads the payload into the tmux paste buffer
+type TmuxSink struct{}
+
+// NewTmuxSink creates a new TmuxSink
+func NewTmuxSink() *TmuxSink {
+	return &TmuxSink{}
+}
+
+// Name returns a short description of the destination
+func (s *TmuxSink) Name() string { return Tmux }
+
+// Write runs "tmux load-buffer -" with the payload on stdin
+func (s *TmuxSink) Write(content string) error {
+	cmd := exec.Command("tmux", "load-buffer", "-")
+	cmd.Stdin = strings.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tmux load-buffer: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
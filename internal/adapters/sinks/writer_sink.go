@@ -0,0 +1,44 @@
+package sinks
+
+import (
+	"io"
+	"os"
+)
+
+// WriterSink writes the payload to an io.Writer such as stdout
+type WriterSink struct {
+	name string
+	w    io.Writer
+}
+
+// NewWriterSink creates a new WriterSink named name writing to w
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+// Name returns a short description of the destination
+func (s *WriterSink) Name() string { return s.name }
+
+// Write writes the payload to the writer
+func (s *WriterSink) Write(content string) error {
+	_, err := io.WriteString(s.w, content)
+	return err
+}
+
+// FileSink writes the payload to a file, replacing its content
+type FileSink struct {
+	path string
+}
+
+// NewFileSink creates a new FileSink writing to path
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Name returns a short description of the destination
+func (s *FileSink) Name() string { return File + ":" + s.path }
+
+// Write replaces the content of the file with the payload
+func (s *FileSink) Write(content string) error {
+	return os.WriteFile(s.path, []byte(content), 0644)
+}
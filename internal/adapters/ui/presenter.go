@@ -5,17 +5,36 @@ import (
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/makinzm/partial-tree-copy/internal/adapters/ui/tui"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/changes"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/navigator"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/preview"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/profiles"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/search"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
 )
 
 // UIPresenter is responsible for handling the presentation layer
 type UIPresenter struct {
-	navigator *navigator.FileNavigator
-	selector  *selector.FileSelector
-	copier    *copier.FileCopier
+	navigator  *navigator.FileNavigator
+	selector   *selector.FileSelector
+	copier     *copier.FileCopier
+	estimator  *tokens.Estimator
+	profiles   *profiles.Manager
+	changes    *changes.Tracker
+	searcher   *search.Searcher
+	resolver   *deps.Resolver
+	previewer  *preview.Previewer
+	classifier *classify.Classifier
+	watcher    *watch.Watcher // Refreshes the tree as files change; nil reads each directory once
+	profile    string         // Profile selected when the UI starts; empty starts with no selection
+	paths      []string       // Root-relative paths added to the selection when the UI starts
 }
 
 // NewUIPresenter creates a new UIPresenter
@@ -23,11 +42,27 @@ func NewUIPresenter(
 	navigator *navigator.FileNavigator,
 	selector *selector.FileSelector,
 	copier *copier.FileCopier,
+	estimator *tokens.Estimator,
+	profiles *profiles.Manager,
+	changes *changes.Tracker,
+	searcher *search.Searcher,
+	resolver *deps.Resolver,
+	previewer *preview.Previewer,
+	classifier *classify.Classifier,
+	profile string,
 ) *UIPresenter {
 	return &UIPresenter{
-		navigator: navigator,
-		selector:  selector,
-		copier:    copier,
+		navigator:  navigator,
+		selector:   selector,
+		copier:     copier,
+		estimator:  estimator,
+		profiles:   profiles,
+		changes:    changes,
+		searcher:   searcher,
+		resolver:   resolver,
+		previewer:  previewer,
+		classifier: classifier,
+		profile:    profile,
 	}
 }
 
@@ -38,23 +73,96 @@ func (p *UIPresenter) StartUI() error {
 		p.navigator,
 		p.selector,
 		p.copier,
-		20, // Maximum visible rows
+		p.estimator,
+		p.profiles,
+		p.changes,
+		p.searcher,
+		p.resolver,
+		p.previewer,
+		p.classifier,
+		tui.DefaultVisibleRows, // Replaced by the terminal size once it is known
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create UI model: %w", err)
 	}
+	model.Watcher = p.watcher
+
+	// Start from the requested profile; missing files are shown in the status message
+	if p.profile != "" {
+		if err := model.LoadProfile(p.profile); err != nil {
+			return fmt.Errorf("failed to load profile: %w", err)
+		}
+	}
+
+	// Add the files requested on the command line, e.g. with --modified
+	p.selector.SelectPaths(model.Root, p.paths)
+
+	// Badges are best effort; outside a git repository no file is marked
+	if p.changes != nil {
+		_ = p.changes.Refresh()
+	}
+
+	// Draw on the terminal even when stdout is piped, e.g. to the stdout sink
+	var options []tea.ProgramOption
+	output := os.Stdout
+	if !isTerminal(os.Stdout) {
+		if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+			defer tty.Close()
+			options = append(options, tea.WithOutput(tty))
+			output = tty
+		}
+	}
+
+	// Lay out the first frame for the real terminal size; later changes arrive as WindowSizeMsg
+	if width, height, err := term.GetSize(output.Fd()); err == nil {
+		model.Width, model.Height = width, height
+	}
 
 	// Initialize BubbleTea program
-	program := tea.NewProgram(*model)
+	program := tea.NewProgram(*model, options...)
 
 	// Start the program
-	if _, err := program.Run(); err != nil {
+	finalModel, err := program.Run()
+	if err != nil {
 		return fmt.Errorf("error running UI: %w", err)
 	}
 
+	m, ok := finalModel.(tui.Model)
+	if !ok {
+		return nil
+	}
+
+	// Write the payload now that the terminal is restored
+	if m.CopyRequested {
+		if err := p.copier.WritePayload(m.Result.Payload); err != nil {
+			return fmt.Errorf("failed to copy to %s: %w", p.copier.SinkName(), err)
+		}
+	}
+
+	// Report anything the user should know about the copy
+	if m.ExitMessage != "" {
+		fmt.Fprintln(os.Stderr, m.ExitMessage)
+	}
+
 	return nil
 }
 
+// SelectOnStart adds the files at the root-relative paths to the selection when the UI starts
+func (p *UIPresenter) SelectOnStart(paths []string) {
+	p.paths = append(p.paths, paths...)
+}
+
+// SetWatcher refreshes the tree as files change; the watcher should observe the directories the navigator reads
+func (p *UIPresenter) SetWatcher(watcher *watch.Watcher) {
+	p.watcher = watcher
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // HandleError presents an error message and exits the application
 func (p *UIPresenter) HandleError(err error, message string) {
 	fmt.Fprintf(os.Stderr, "%s: %v\n", message, err)
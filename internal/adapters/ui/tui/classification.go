@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+)
+
+// FileClass returns the class of a file node, inspecting the file the first
+// time it is shown. Directories, declarations, and unreadable files have none.
+func (m *Model) FileClass(node *entities.FileNode) (classify.Class, bool) {
+	if m.Classifier == nil || node.IsDir || node.Symbol != nil {
+		return classify.Class{}, false
+	}
+	if class, ok := m.classes[node.Path]; ok {
+		return class, true
+	}
+	class, err := m.Classifier.Inspect(node.Path)
+	if err != nil {
+		return classify.Class{}, false
+	}
+	m.classes[node.Path] = class
+	return class, true
+}
+
+// classBadge returns the badge of a file that is not copied as plain UTF-8 text, or ""
+func (m *Model) classBadge(node *entities.FileNode) string {
+	class, ok := m.FileClass(node)
+	if !ok {
+		return ""
+	}
+	switch {
+	case class.Kind == classify.Binary:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Render("[binary]")
+	case class.Kind == classify.Oversized:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("[" + classify.FormatSize(class.Size) + "]")
+	case class.Transcoded():
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("110")).Render("[" + class.Encoding + "]")
+	}
+	return ""
+}
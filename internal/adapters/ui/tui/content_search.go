@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/search"
+)
+
+// SearchPane is the state of the file content search pane
+type SearchPane struct {
+	Query    string         // Pattern typed so far
+	Regex    bool           // Interpret the query as a regular expression
+	Results  search.Results // Files matching the last search
+	Searched bool           // Results are up to date with Query and Regex
+	Cursor   int            // Highlighted file in Results
+	Error    string         // Why the last search failed, e.g. an invalid regular expression
+}
+
+// OpenSearch opens the content search pane, keeping the previous query and results
+func (m *Model) OpenSearch() {
+	if m.Searcher == nil {
+		return
+	}
+	m.clearMessages()
+	if m.Search == nil {
+		m.Search = &SearchPane{}
+	}
+	m.SearchOpen = true
+}
+
+// UpdateSearch handles a key press while the content search pane is open
+func (m *Model) UpdateSearch(msg tea.KeyMsg) {
+	p := m.Search
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.SearchOpen = false
+	case "enter":
+		// Run the search, or jump to the highlighted file once the results are current
+		if !p.Searched {
+			m.RunSearch()
+			return
+		}
+		if hit, ok := p.Selected(); ok {
+			if node := m.Selector.FindNode(m.Root, hit.Path); node != nil {
+				m.Navigator.Reveal(node)
+				m.Cursor = node
+				m.FocusRight = false
+			}
+		}
+		m.SearchOpen = false
+	case "tab":
+		// Toggle the highlighted file
+		if hit, ok := p.Selected(); ok {
+			if node := m.Selector.FindNode(m.Root, hit.Path); node != nil {
+				m.Selector.ToggleSelect(node)
+			}
+		}
+	case "ctrl+a":
+		// Add every matching file
+		if p.Searched && len(p.Results.Files) > 0 {
+			missing := m.Selector.SelectPaths(m.Root, p.Results.Paths())
+			m.InfoMessage = fmt.Sprintf("Selected %d files matching %q", len(p.Results.Files)-len(missing), p.Query)
+		}
+	case "ctrl+r":
+		p.Regex = !p.Regex
+		p.Searched = false
+	case "up", "ctrl+p", "ctrl+k":
+		if p.Cursor > 0 {
+			p.Cursor--
+		}
+	case "down", "ctrl+n", "ctrl+j":
+		if p.Cursor < len(p.Results.Files)-1 {
+			p.Cursor++
+		}
+	case "backspace":
+		if runes := []rune(p.Query); len(runes) > 0 {
+			p.Query = string(runes[:len(runes)-1])
+			p.Searched = false
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			p.Query += string(msg.Runes)
+			p.Searched = false
+		}
+	}
+}
+
+// RunSearch scans the file contents for the query of the search pane
+func (m *Model) RunSearch() {
+	p := m.Search
+	results, err := m.Searcher.Search(search.Query{Pattern: p.Query, Regex: p.Regex})
+	p.Results, p.Cursor, p.Searched, p.Error = results, 0, true, ""
+	if err != nil {
+		p.Error = err.Error()
+	}
+}
+
+// Selected returns the highlighted file, if any
+func (p *SearchPane) Selected() (search.FileHit, bool) {
+	if !p.Searched || p.Cursor < 0 || p.Cursor >= len(p.Results.Files) {
+		return search.FileHit{}, false
+	}
+	return p.Results.Files[p.Cursor], true
+}
+
+// buildSearchView renders the content search pane in place of the tree view (left panel).
+// Every file shows its first matching line; the highlighted file shows all of its previews.
+func (m *Model) buildSearchView(maxLines int, width int) string {
+	p := m.Search
+	var s strings.Builder
+
+	mode := "literal"
+	if p.Regex {
+		mode = "regex"
+	}
+	s.WriteString("Search contents [" + mode + "]: > " + p.Query + "█\n")
+
+	switch {
+	case p.Error != "":
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(p.Error) + "\n")
+		return s.String()
+	case !p.Searched:
+		s.WriteString("Press 'Enter' to search, Ctrl+'r' for regex\n")
+		return s.String()
+	case len(p.Results.Files) == 0:
+		s.WriteString("No matching files\n")
+		return s.String()
+	}
+
+	count := strconv.Itoa(len(p.Results.Files))
+	if p.Results.Truncated {
+		count += "+"
+	}
+	s.WriteString(count + " matching files, Ctrl+'a' to select all\n")
+
+	// Lay out every file with its previews, then scroll so the highlighted file is in view
+	highlight := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	var lines []string
+	cursorFirst, cursorLast := 0, 0
+	for i, hit := range p.Results.Files {
+		line := "  "
+		if i == p.Cursor {
+			line = highlight.Render("> ")
+			cursorFirst = len(lines)
+		}
+		indicator := "[ ]"
+		if node := m.Selector.FindNode(m.Root, hit.Path); node != nil {
+			indicator = selectionIndicator(m.Selector.SelectionState(node))
+		}
+		suffix := fmt.Sprintf(" (%d)", hit.Count)
+		path := highlightMatches(hit.Path, nil, width-len("  [ ] ")-len(suffix), highlight)
+		line += indicator + " " + path + suffix
+		lines = append(lines, line)
+
+		previews := hit.Matches
+		if i != p.Cursor && len(previews) > 1 {
+			previews = previews[:1]
+		}
+		for _, match := range previews {
+			lines = append(lines, renderPreview(match, width, highlight))
+		}
+		if i == p.Cursor {
+			cursorLast = len(lines) - 1
+		}
+	}
+
+	visibleCount := maxLines - 2
+	startIdx := 0
+	if cursorLast >= visibleCount {
+		startIdx = cursorLast - visibleCount + 1
+	}
+	if startIdx > cursorFirst {
+		startIdx = cursorFirst
+	}
+	endIdx := startIdx + visibleCount
+	if endIdx > len(lines) {
+		endIdx = len(lines)
+	}
+	for _, line := range lines[startIdx:endIdx] {
+		s.WriteString(line + "\n")
+	}
+
+	return s.String()
+}
+
+// renderPreview renders a matching line with the match highlighted, cut to width
+func renderPreview(match search.Match, width int, style lipgloss.Style) string {
+	prefix := fmt.Sprintf("      %d: ", match.Line)
+	before, hit, after := match.Text[:match.Start], match.Text[match.Start:match.End], match.Text[match.End:]
+
+	// Keep the match in view by cutting the text before it
+	room := width - len(prefix)
+	if runes := []rune(before); len(runes) > room/3 && room > 3 {
+		before = "…" + string(runes[len(runes)-room/3+1:])
+	}
+
+	var s strings.Builder
+	for _, part := range []struct {
+		text      string
+		highlight bool
+	}{{before, false}, {hit, true}, {after, false}} {
+		runes := []rune(part.text)
+		if len(runes) > room {
+			runes = runes[:max(room, 0)]
+		}
+		room -= len(runes)
+		if part.highlight {
+			s.WriteString(style.Render(string(runes)))
+		} else {
+			s.WriteString(string(runes))
+		}
+	}
+
+	return lipgloss.NewStyle().Faint(true).Render(prefix) + s.String()
+}
@@ -0,0 +1,148 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
+)
+
+// DepsPane previews the files that following the Go imports of the selection adds
+type DepsPane struct {
+	Direction deps.Direction // Add what the selection imports, or what imports it
+	Depth     int            // Import levels followed; deps.Unlimited follows them all
+	Files     []deps.File    // Files that confirming adds to the selection
+	Error     string         // Why the imports could not be followed, e.g. no go.mod
+	Scroll    int            // First file shown
+}
+
+// OpenDeps previews the files added by following the imports of the selected Go files in direction
+func (m *Model) OpenDeps(direction deps.Direction) {
+	if m.Resolver == nil {
+		return
+	}
+	m.clearMessages()
+	m.Deps = &DepsPane{Direction: direction, Depth: m.Resolver.Depth()}
+	m.ResolveDeps()
+}
+
+// ResolveDeps recomputes the files of the dependency pane for its direction and depth
+func (m *Model) ResolveDeps() {
+	p := m.Deps
+	p.Files, p.Error, p.Scroll = nil, "", 0
+
+	var selected []string
+	for _, node := range m.GetAllSelectedNodes() {
+		selected = append(selected, filepath.ToSlash(m.getRelativePath(node.Path, m.Root.Path)))
+	}
+	files, err := m.Resolver.Expand(selected, p.Direction, p.Depth)
+	if err != nil {
+		p.Error = err.Error()
+		return
+	}
+	p.Files = files
+}
+
+// UpdateDeps handles a key press while the dependency pane is open
+func (m *Model) UpdateDeps(msg tea.KeyMsg) {
+	p := m.Deps
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.Deps = nil
+	case "enter":
+		// Add the previewed files to the selection
+		paths := make([]string, len(p.Files))
+		for i, file := range p.Files {
+			paths[i] = file.Path
+		}
+		missing := m.Selector.SelectPaths(m.Root, paths)
+		m.Deps = nil
+		m.InfoMessage = fmt.Sprintf("Added %d files %s", len(paths)-len(missing), directionLabel(p.Direction))
+	case "tab":
+		if p.Direction == deps.Imports {
+			p.Direction = deps.Importers
+		} else {
+			p.Direction = deps.Imports
+		}
+		m.ResolveDeps()
+	case "+", "=":
+		if p.Depth != deps.Unlimited {
+			p.Depth++
+			m.ResolveDeps()
+		}
+	case "-":
+		if p.Depth == deps.Unlimited {
+			p.Depth = max(p.maxDepth(), 1)
+		} else if p.Depth > 1 {
+			p.Depth--
+		}
+		m.ResolveDeps()
+	case "0":
+		p.Depth = deps.Unlimited
+		m.ResolveDeps()
+	case "up", "k":
+		if p.Scroll > 0 {
+			p.Scroll--
+		}
+	case "down", "j":
+		if p.Scroll < len(p.Files)-1 {
+			p.Scroll++
+		}
+	}
+}
+
+// maxDepth returns the depth of the farthest file found
+func (p *DepsPane) maxDepth() int {
+	depth := 0
+	for _, file := range p.Files {
+		depth = max(depth, file.Depth)
+	}
+	return depth
+}
+
+// directionLabel describes which files a direction adds
+func directionLabel(direction deps.Direction) string {
+	if direction == deps.Importers {
+		return "importing the selected packages"
+	}
+	return "imported by the selection"
+}
+
+// buildDepsView renders the files the dependency pane would add in place of the tree view (left panel)
+func (m *Model) buildDepsView(maxLines int, width int) string {
+	p := m.Deps
+	var s strings.Builder
+
+	depth := "all levels"
+	if p.Depth != deps.Unlimited {
+		depth = fmt.Sprintf("depth %d", p.Depth)
+	}
+	s.WriteString(truncate("Add files "+directionLabel(p.Direction)+" ["+depth+"]", width) + "\n")
+	s.WriteString(truncate("'Enter' add, 'Tab' imports/importers, '+'/'-' depth, '0' all levels, 'Esc' cancel", width) + "\n")
+
+	switch {
+	case p.Error != "":
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(truncate(p.Error, width)) + "\n")
+		return s.String()
+	case len(p.Files) == 0 && len(m.Selector.GetSelection()) == 0:
+		s.WriteString("Select Go files of the module first\n")
+		return s.String()
+	case len(p.Files) == 0:
+		s.WriteString("No other files found\n")
+		return s.String()
+	}
+
+	s.WriteString(fmt.Sprintf("%d files:\n", len(p.Files)))
+	visibleCount := max(maxLines-3, 1)
+	end := min(p.Scroll+visibleCount, len(p.Files))
+	levelStyle := lipgloss.NewStyle().Faint(true)
+	for _, file := range p.Files[p.Scroll:end] {
+		level := fmt.Sprintf("  %d ", file.Depth)
+		s.WriteString(levelStyle.Render(level) + truncateLeft(file.Path, width-len(level)) + "\n")
+	}
+
+	return s.String()
+}
@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/fuzzy"
+)
+
+// finderLimit caps the number of ranked results kept for display
+const finderLimit = 200
+
+// Finder is the state of the fuzzy file finder overlay
+type Finder struct {
+	Query   string               // Text typed so far
+	Nodes   []*entities.FileNode // Every searchable node, including those in unexpanded directories
+	Paths   []string             // Root-relative paths of Nodes, the fuzzy match candidates
+	Results []fuzzy.Result       // Ranked matches of Query; Index refers to Nodes
+	Cursor  int                  // Highlighted result
+}
+
+// OpenFinder opens the fuzzy finder over every path under the root
+func (m *Model) OpenFinder() {
+	m.clearMessages()
+	m.Finder = m.newFinder("")
+}
+
+// newFinder lists every path under the root and ranks them against query
+func (m *Model) newFinder(query string) *Finder {
+	nodes := m.Navigator.AllNodes(m.Root)
+	paths := make([]string, len(nodes))
+	for i, node := range nodes {
+		paths[i] = m.getRelativePath(node.Path, m.Root.Path)
+		if node.IsDir {
+			paths[i] += "/"
+		}
+	}
+
+	f := &Finder{Query: query, Nodes: nodes, Paths: paths}
+	f.rank()
+	return f
+}
+
+// UpdateFinder handles a key press while the finder is open
+func (m *Model) UpdateFinder(msg tea.KeyMsg) {
+	f := m.Finder
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.Finder = nil
+	case "enter":
+		// Jump to the highlighted result in the tree
+		if node := f.Selected(); node != nil {
+			m.Navigator.Reveal(node)
+			m.Cursor = node
+			m.FocusRight = false
+		}
+		m.Finder = nil
+	case "tab":
+		// Toggle the highlighted result and keep searching
+		if node := f.Selected(); node != nil {
+			m.Selector.ToggleSelect(node)
+		}
+	case "up", "ctrl+p", "ctrl+k":
+		if f.Cursor > 0 {
+			f.Cursor--
+		}
+	case "down", "ctrl+n", "ctrl+j":
+		if f.Cursor < len(f.Results)-1 {
+			f.Cursor++
+		}
+	case "backspace":
+		if runes := []rune(f.Query); len(runes) > 0 {
+			f.Query = string(runes[:len(runes)-1])
+			f.rank()
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			f.Query += string(msg.Runes)
+			f.rank()
+		}
+	}
+}
+
+// Selected returns the node of the highlighted result, or nil when nothing matches
+func (f *Finder) Selected() *entities.FileNode {
+	if f.Cursor < 0 || f.Cursor >= len(f.Results) {
+		return nil
+	}
+	return f.Nodes[f.Results[f.Cursor].Index]
+}
+
+// rank matches the query against every path and moves the cursor to the best match
+func (f *Finder) rank() {
+	f.Results = fuzzy.Rank(strings.TrimSpace(f.Query), f.Paths, finderLimit)
+	f.Cursor = 0
+}
+
+// buildFinderView renders the finder in place of the tree view (left panel)
+func (m *Model) buildFinderView(maxLines int, width int) string {
+	f := m.Finder
+	var s strings.Builder
+
+	s.WriteString("Find: > " + f.Query + "█\n")
+	s.WriteString(strconv.Itoa(len(f.Results)))
+	if len(f.Results) == finderLimit {
+		s.WriteString("+")
+	}
+	s.WriteString("/" + strconv.Itoa(len(f.Paths)) + " paths\n")
+
+	// Keep the highlighted result in view
+	visibleCount := maxLines - 2
+	startIdx := 0
+	if f.Cursor >= visibleCount {
+		startIdx = f.Cursor - visibleCount + 1
+	}
+	endIdx := startIdx + visibleCount
+	if endIdx > len(f.Results) {
+		endIdx = len(f.Results)
+	}
+
+	highlight := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	for i := startIdx; i < endIdx; i++ {
+		result := f.Results[i]
+		node := f.Nodes[result.Index]
+
+		line := "  "
+		if i == f.Cursor {
+			line = highlight.Render("> ")
+		}
+		line += selectionIndicator(m.Selector.SelectionState(node)) + " "
+		line += highlightMatches(f.Paths[result.Index], result.Positions, width-len("  [ ] "), highlight)
+		s.WriteString(line + "\n")
+	}
+
+	if len(f.Results) == 0 {
+		s.WriteString("\nNo matching files\n")
+	}
+
+	return s.String()
+}
+
+// highlightMatches renders path with the matched bytes highlighted, cutting
+// the start of paths longer than width so the base name stays visible
+func highlightMatches(path string, positions []int, width int, style lipgloss.Style) string {
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	var s strings.Builder
+	offset := 0
+	if runes := []rune(path); width > 1 && len(runes) > width {
+		offset = len(string(runes[:len(runes)-width+1]))
+		s.WriteString("…")
+	}
+	for i, r := range path[offset:] {
+		if matched[offset+i] {
+			s.WriteString(style.Render(string(r)))
+		} else {
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
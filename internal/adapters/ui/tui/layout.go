@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// DefaultVisibleRows is a sensible number of panel lines until the terminal reports its size
+const DefaultVisibleRows = 20
+
+// Layout limits
+const (
+	defaultWidth  = 120 // Terminal width assumed until the first WindowSizeMsg
+	compactWidth  = 80  // Narrower terminals show one panel at a time
+	compactHeight = 24  // Shorter terminals show the one-line help unless '?' is pressed
+	minTreeWidth  = 30
+	maxTreeWidth  = 60
+	panelGap      = 1 // Columns between the left and the right panel
+	minPanelRows  = 5 // Panels keep at least this many lines, even if the terminal is shorter
+)
+
+// layout describes where everything goes for the current terminal size
+type layout struct {
+	compact    bool     // Show one panel at a time, at the full width
+	treeWidth  int      // Width of the left panel
+	rightWidth int      // Width of the right panel
+	rows       int      // Lines available to each panel
+	footer     []string // Prompt, messages, and help text shown below the panels
+}
+
+// layout sizes the panels to the terminal. Until the terminal reports its size,
+// the panels get MaxVisibleRows lines and a width of defaultWidth is assumed.
+func (m *Model) layout() layout {
+	width := m.Width
+	if width == 0 {
+		width = defaultWidth
+	}
+
+	l := layout{compact: width < compactWidth}
+	shortHelp := !m.ShowHelp && (l.compact || (m.Height > 0 && m.Height < compactHeight))
+	l.footer = m.buildFooter(width, shortHelp)
+
+	if m.Height == 0 {
+		l.rows = m.MaxVisibleRows
+	} else {
+		l.rows = max(m.Height-len(l.footer), minPanelRows)
+	}
+
+	if l.compact {
+		l.treeWidth, l.rightWidth = width, width
+	} else {
+		l.treeWidth = min(max(width*2/5, minTreeWidth), maxTreeWidth)
+		l.rightWidth = width - l.treeWidth - panelGap
+	}
+	return l
+}
+
+// buildFooter returns the lines below the panels, each fitted to width
+func (m *Model) buildFooter(width int, shortHelp bool) []string {
+	var lines []string
+
+	// Show the profile name being typed above the help text
+	if m.Prompt != NoPrompt {
+		lines = append(lines, wrap(m.PromptLabel()+m.PromptInput+"█", width)...)
+	}
+
+	// Show confirmations and status messages (e.g. a refused copy) above the help text
+	if m.InfoMessage != "" {
+		lines = append(lines, wrap(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("42")).
+			Render(m.InfoMessage), width)...)
+	}
+	if m.StatusMessage != "" {
+		lines = append(lines, wrap(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render(m.StatusMessage), width)...)
+	}
+
+	if shortHelp {
+		return append(lines, truncate("'w' copy & quit, 'Space' select, 'Enter' open, 'h'/'l' panels, '/' find, '?' all keys", width))
+	}
+
+	lines = append(lines, "", "How to use")
+	for _, help := range []string{
+		"Press 'w'/Ctrl+'c' to quit and copy, 'Space' to select file/dir, 'Enter' to expand/collapse dir or list the declarations of a Go file",
+		"Navigation: 'h'/'l' to switch panels, 'j'/'k' to move up/down, 'J'/'K' to jump between directories",
+		"Preview: 'p' to preview the file under the cursor, 'l' then 'j'/'k' or Ctrl+'d'/Ctrl+'u' to move through it",
+		"Lines: 'v' in the preview to mark the first and then the last line to select, 'Esc' to cancel, 'x' to select the whole file again",
+		"Output: 'f' to cycle format (plain, markdown, xml, json, and template when configured), 'd' to cycle full/diff/full+diff/outline, 'i' to show/hide ignored files",
+		"Find: '/' or Ctrl+'p' to fuzzy-find any path, Ctrl+'f' to search file contents ('Tab' to select, 'Enter' to jump, 'Esc' to close)",
+		"Selections: 's' to save the selection under a name, 'o' to load a saved selection, 'g' to select files changed in git",
+		"Dependencies: 'e' to add the Go packages the selection imports, 'E' to add the files importing it (previewed before they are added)",
+		"Tests: 't' to add the tests of the selected files, or remove them when all are there, 'T' to make tests follow the files you select",
+	} {
+		lines = append(lines, wrap(help, width)...)
+	}
+	return lines
+}
+
+// wrap breaks text into lines of at most width cells
+func wrap(text string, width int) []string {
+	return strings.Split(lipgloss.NewStyle().Width(width).Render(text), "\n")
+}
+
+// truncate cuts text to width cells, ending it with an ellipsis when it is too long
+func truncate(text string, width int) string {
+	return ansi.Truncate(text, width, "…")
+}
+
+// truncateLeft cuts the start of text to fit width cells, so the end of a path stays visible
+func truncateLeft(text string, width int) string {
+	over := ansi.StringWidth(text) - width
+	if over <= 0 || width < 1 {
+		return text
+	}
+	return ansi.TruncateLeft(text, over+1, "…")
+}
@@ -3,23 +3,73 @@ package tui
 import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/changes"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/navigator"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/preview"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/profiles"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/search"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
+)
+
+// PromptKind identifies the text input the user is typing, if any
+type PromptKind int
+
+const (
+	NoPrompt          PromptKind = iota // Keys control the tree
+	SaveProfilePrompt                   // Typing the name to save the selection under
+	LoadProfilePrompt                   // Typing the name of the profile to load
 )
 
 // Model represents the state of the file tree viewer
 type Model struct {
 	Root           *entities.FileNode // Root node of the file tree
 	Cursor         *entities.FileNode // Current position of the cursor in the tree
-	MaxVisibleRows int                // Maximum number of visible rows in the tree view
+	MaxVisibleRows int                // Lines of each panel until the terminal reports its size
 	FocusRight     bool               // Indicates if the right pane is focused
 	RightScroll    int                // Scroll position of the right pane
+	StatusMessage  string             // Warning shown above the help text (e.g. a refused copy)
+	InfoMessage    string             // Confirmation shown above the help text (e.g. a saved profile)
+	CopyRefused    bool               // The last copy was refused or failed; another copy key press quits without copying
+	Prompt         PromptKind         // Text input in progress
+	PromptInput    string             // Text typed into the prompt so far
+	Finder         *Finder            // Fuzzy finder overlay; nil when closed
+	Search         *SearchPane        // Content search pane; kept while closed so the last results stay available
+	SearchOpen     bool               // The content search pane is shown in place of the tree
+	Deps           *DepsPane          // Preview of the files added by following Go imports; nil when closed
+	ShowPreview    bool               // The right panel previews the file under the cursor instead of listing the selection
+	PreviewScroll  int                // First line shown in the preview
+	PreviewLine    int                // Line under the preview cursor, counted from 0
+	PreviewMark    int                // Line a range mark started at, counted from 0
+	Marking        bool               // A line range is being marked in the preview
+	PreviewNode    *entities.FileNode // File the preview scroll, cursor, and mark apply to; other files are previewed from the top
+	Width          int                // Terminal width reported by the last WindowSizeMsg; 0 until then
+	Height         int                // Terminal height reported by the last WindowSizeMsg; 0 until then
+	ShowHelp       bool               // Show the full help even where space is short
+	ExitMessage    string             // Message printed to stderr after the program exits
+	CopyRequested  bool               // The user quit with a copy; Payload is written once the terminal is restored
+	Result         copier.CopyResult  // Rendered selection waiting to be written to the output sink, with what it holds
+	Sensitive      []string           // Copied files named like credentials, reported after the program exits
 
 	// Use cases
-	Navigator *navigator.FileNavigator
-	Selector  *selector.FileSelector
-	Copier    *copier.FileCopier
+	Navigator  *navigator.FileNavigator
+	Selector   *selector.FileSelector
+	Copier     *copier.FileCopier
+	Estimator  *tokens.Estimator
+	Profiles   *profiles.Manager
+	Changes    *changes.Tracker
+	Searcher   *search.Searcher
+	Resolver   *deps.Resolver
+	Previewer  *preview.Previewer
+	Classifier *classify.Classifier
+	Watcher    *watch.Watcher // Refreshes the tree as files change on disk; nil reads each directory once
+
+	previewCache *previewCache             // Highlighted content of the last previewed file
+	classes      map[string]classify.Class // Classes of the files shown so far, by path
 }
 
 // NewModel creates a new Model with the given use cases and settings
@@ -27,6 +77,13 @@ func NewModel(
 	navigator *navigator.FileNavigator,
 	selector *selector.FileSelector,
 	copier *copier.FileCopier,
+	estimator *tokens.Estimator,
+	profileManager *profiles.Manager,
+	changeTracker *changes.Tracker,
+	searcher *search.Searcher,
+	resolver *deps.Resolver,
+	previewer *preview.Previewer,
+	classifier *classify.Classifier,
 	maxVisibleRows int,
 ) (*Model, error) {
 	// Build the root node
@@ -44,10 +101,22 @@ func NewModel(
 		Navigator:      navigator,
 		Selector:       selector,
 		Copier:         copier,
+		Estimator:      estimator,
+		Profiles:       profileManager,
+		Changes:        changeTracker,
+		Searcher:       searcher,
+		Resolver:       resolver,
+		Previewer:      previewer,
+		Classifier:     classifier,
+		previewCache:   &previewCache{},
+		classes:        make(map[string]classify.Class),
 	}, nil
 }
 
-// Init initializes the model
+// Init initializes the model, starting to wait for changes to the files on disk
 func (m Model) Init() tea.Cmd {
-	return nil
+	if m.Watcher == nil {
+		return nil
+	}
+	return waitForChanges(m.Watcher)
 }
@@ -0,0 +1,283 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/preview"
+)
+
+// Preview rendering settings
+const (
+	previewStyle = "monokai" // Chroma style used for syntax highlighting
+	previewTab   = "    "    // Tabs are expanded so column widths stay predictable
+)
+
+// previewCache holds the highlighted lines of the last previewed file.
+// The model refers to it by pointer, so every copy of the model shares it.
+type previewCache struct {
+	path    string
+	content preview.Preview
+	lines   []string // Highlighted lines
+	err     error
+}
+
+// TogglePreview switches the right panel between the selection list and the file preview
+func (m *Model) TogglePreview() {
+	if m.Previewer == nil {
+		return
+	}
+	m.ShowPreview = !m.ShowPreview
+	if !m.ShowPreview && len(m.Selector.GetSelection()) == 0 {
+		m.FocusRight = false
+	}
+}
+
+// MovePreviewCursor moves the line cursor of the preview by delta lines, scrolling to keep it in view
+func (m *Model) MovePreviewCursor(delta int) {
+	lines := m.previewLines()
+	if m.PreviewNode != m.Cursor {
+		start := min(m.previewStart(), max(len(lines)-1, 0))
+		m.PreviewNode, m.PreviewScroll, m.PreviewLine, m.Marking = m.Cursor, start, start, false
+	}
+
+	m.PreviewLine += delta
+	if m.PreviewLine > len(lines)-1 {
+		m.PreviewLine = len(lines) - 1
+	}
+	if m.PreviewLine < 0 {
+		m.PreviewLine = 0
+	}
+
+	// The title takes the first line of the panel
+	visibleCount := max(m.layout().rows-1, 1)
+	if m.PreviewLine < m.PreviewScroll {
+		m.PreviewScroll = m.PreviewLine
+	}
+	if m.PreviewLine >= m.PreviewScroll+visibleCount {
+		m.PreviewScroll = m.PreviewLine - visibleCount + 1
+	}
+}
+
+// MarkLines starts marking a line range at the preview cursor, or selects the
+// lines from the mark to the cursor when a mark is already set
+func (m *Model) MarkLines() {
+	if !m.ShowPreview || len(m.previewLines()) == 0 {
+		return
+	}
+	m.clearMessages()
+	m.MovePreviewCursor(0)
+
+	if !m.Marking {
+		// Keep j/k on the preview while the range is marked
+		m.Marking, m.PreviewMark, m.FocusRight = true, m.PreviewLine, true
+		m.InfoMessage = fmt.Sprintf("Marking from line %d: move with 'j'/'k' and press 'v' again to select the lines, 'Esc' to cancel", m.PreviewLine+1)
+		return
+	}
+
+	r := entities.LineRange{Start: min(m.PreviewMark, m.PreviewLine) + 1, End: max(m.PreviewMark, m.PreviewLine) + 1}
+	m.Selector.AddRange(m.previewFile(), r)
+	m.Marking = false
+	m.InfoMessage = "Selected lines " + r.String() + " of " + m.getRelativePath(m.Cursor.Path, m.Root.Path)
+}
+
+// CancelMark drops a line range mark in progress
+func (m *Model) CancelMark() {
+	if m.Marking {
+		m.Marking = false
+		m.clearMessages()
+	}
+}
+
+// SelectWholeFile drops the line ranges of the file under the cursor, so all of it is copied
+func (m *Model) SelectWholeFile() {
+	file := m.previewFile()
+	if file == nil || !file.Selected || len(file.Ranges) == 0 {
+		return
+	}
+	m.Selector.SetRanges(file, nil)
+	m.clearMessages()
+	m.InfoMessage = "Selected all of " + m.getRelativePath(m.Cursor.Path, m.Root.Path)
+}
+
+// previewFile returns the file under the cursor, or the file of the declaration under it
+func (m *Model) previewFile() *entities.FileNode {
+	if m.Cursor != nil && m.Cursor.Symbol != nil {
+		return m.Cursor.Parent
+	}
+	return m.Cursor
+}
+
+// previewStart returns the line the preview of the node under the cursor starts at:
+// the first line of a declaration, or the top of a file
+func (m *Model) previewStart() int {
+	if m.Cursor.Symbol != nil {
+		return m.Cursor.Symbol.Lines.Start - 1
+	}
+	return 0
+}
+
+// previewOffset returns the first preview line shown; moving to another node starts at its first line
+func (m *Model) previewOffset() int {
+	if m.PreviewNode != m.Cursor {
+		return min(m.previewStart(), max(len(m.previewCache.lines)-1, 0))
+	}
+	return m.PreviewScroll
+}
+
+// previewCursor returns the line under the preview cursor, or -1 while the preview has no focus
+func (m *Model) previewCursor() int {
+	switch {
+	case !m.FocusRight:
+		return -1
+	case m.PreviewNode != m.Cursor:
+		return m.previewOffset()
+	}
+	return m.PreviewLine
+}
+
+// previewLines returns the highlighted lines of the file under the cursor, or nil for directories
+func (m *Model) previewLines() []string {
+	if m.Cursor == nil || m.Cursor.IsDir {
+		return nil
+	}
+	m.loadPreview(m.Cursor)
+	return m.previewCache.lines
+}
+
+// loadPreview reads and highlights node unless it is already cached
+func (m *Model) loadPreview(node *entities.FileNode) {
+	if m.previewCache.path == node.Path {
+		return
+	}
+
+	loaded, err := m.Previewer.Load(node.Path)
+	*m.previewCache = previewCache{path: node.Path, content: loaded, err: err}
+	if err == nil {
+		m.previewCache.lines = highlightLines(node.Path, loaded.Lines)
+	}
+}
+
+// buildPreviewView renders the file under the cursor with line numbers (right panel)
+func (m *Model) buildPreviewView(maxLines int, width int) string {
+	var s strings.Builder
+
+	if m.Cursor == nil || m.Cursor.IsDir {
+		s.WriteString("Preview\n\nMove the cursor to a file to preview it\n")
+		return s.String()
+	}
+
+	lines := m.previewLines()
+	file := m.previewFile()
+	title := "Preview: " + m.getRelativePath(file.Path, m.Root.Path)
+	if enc := m.previewCache.content.Encoding; enc != "" && enc != classify.UTF8 {
+		title += " (" + enc + ")"
+	}
+	switch {
+	case m.previewCache.err != nil:
+		s.WriteString(ansi.Truncate(title, width, "…") + "\n\n")
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Cannot read file: "+m.previewCache.err.Error()) + "\n")
+		return s.String()
+	case m.previewCache.content.Binary:
+		s.WriteString(ansi.Truncate(title, width, "…") + "\n\nBinary file not shown\n")
+		return s.String()
+	}
+
+	// Show which lines are in view after the title
+	start := m.previewOffset()
+	visibleCount := maxLines - 1
+	end := min(start+visibleCount, len(lines))
+	position := fmt.Sprintf(" [%d-%d/%d]", start+1, end, len(lines))
+	if m.previewCache.content.Truncated {
+		position = fmt.Sprintf(" [%d-%d/%d+, truncated]", start+1, end, len(lines))
+	}
+	s.WriteString(ansi.Truncate(title, width-len(position), "…") + position + "\n")
+
+	// Line numbers show the selected lines in green and the lines being marked in reverse
+	gutter := len(strconv.Itoa(len(lines)))
+	cursor := m.previewCursor()
+	numberStyle := lipgloss.NewStyle().Faint(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	markStyle := lipgloss.NewStyle().Reverse(true)
+	for i := start; i < end; i++ {
+		marker := " "
+		if i == cursor {
+			marker = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(">")
+		}
+		number := fmt.Sprintf("%*d ", gutter, i+1)
+
+		style := numberStyle
+		switch {
+		case m.Marking && m.PreviewNode == m.Cursor && cursor >= 0 && i >= min(m.PreviewMark, cursor) && i <= max(m.PreviewMark, cursor):
+			style = markStyle
+		case file.Selected && inRanges(file.Ranges, i+1):
+			style = selectedStyle
+		}
+		s.WriteString(marker + style.Render(number))
+		s.WriteString(ansi.Truncate(lines[i], width-1-len(number), "") + "\x1b[0m\n")
+	}
+
+	return s.String()
+}
+
+// inRanges reports whether line is one of the selected lines; no ranges select every line
+func inRanges(ranges []entities.LineRange, line int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if r.Contains(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightLines colors lines with the chroma lexer matching the file name.
+// Files no lexer recognizes are shown as plain text.
+func highlightLines(path string, lines []string) []string {
+	plain := make([]string, len(lines))
+	for i, line := range lines {
+		plain[i] = strings.ReplaceAll(line, "\t", previewTab)
+	}
+
+	text := strings.Join(plain, "\n")
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		lexer = lexers.Analyse(text)
+	}
+	if lexer == nil {
+		return plain
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, text)
+	if err != nil {
+		return plain
+	}
+	tokenLines := chroma.SplitTokensIntoLines(iterator.Tokens())
+
+	style := styles.Get(previewStyle)
+	highlighted := make([]string, 0, len(plain))
+	for _, tokens := range tokenLines {
+		var line strings.Builder
+		if err := formatters.TTY256.Format(&line, style, chroma.Literator(tokens...)); err != nil {
+			return plain
+		}
+		highlighted = append(highlighted, strings.ReplaceAll(line.String(), "\n", ""))
+	}
+	if len(highlighted) < len(plain) {
+		// The lexer dropped trailing empty lines
+		highlighted = append(highlighted, plain[len(highlighted):]...)
+	}
+	return highlighted[:len(plain)]
+}
@@ -1,22 +1,74 @@
 package tui
 
 import (
+	"errors"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/redact"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
 )
 
 // Update handles user input and updates the model state
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.Width, m.Height = msg.Width, msg.Height
+
+	case treeChangedMsg:
+		m.ApplyChanges(watch.Batch(msg))
+		return m, waitForChanges(m.Watcher)
+
 	case tea.KeyMsg:
+		if m.Prompt != NoPrompt {
+			m.UpdatePrompt(msg)
+			return m, nil
+		}
+		if m.Finder != nil {
+			m.UpdateFinder(msg)
+			return m, nil
+		}
+		if m.SearchOpen {
+			m.UpdateSearch(msg)
+			return m, nil
+		}
+		if m.Deps != nil {
+			m.UpdateDeps(msg)
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "w":
 			// Copy selection and quit
-			_ = m.CopySelection()
+			err := m.CopySelection()
+			if errors.Is(err, tokens.ErrBudgetExceeded) || errors.Is(err, redact.ErrSensitiveFiles) {
+				// A second press after a refusal quits without copying
+				if m.CopyRefused {
+					m.ExitMessage = "Nothing copied: " + err.Error()
+					return m, tea.Quit
+				}
+				m.CopyRefused = true
+				m.StatusMessage = "Copy refused: " + err.Error() +
+					". Deselect files, or press again to quit without copying."
+				return m, nil
+			}
+			if err != nil {
+				// Stay open so the selection is not lost; a second press quits, as the error may persist
+				if m.CopyRefused {
+					m.ExitMessage = "Nothing copied: " + err.Error()
+					return m, tea.Quit
+				}
+				m.CopyRefused = true
+				m.StatusMessage = "Copy failed: " + err.Error() + ". Press again to quit without copying."
+				return m, nil
+			}
+			m.ExitMessage = m.CopyReport()
 			return m, tea.Quit
 
 		case "L", "l":
-			// Move focus to right panel if there are selections
-			if len(m.Selector.GetSelection()) > 0 {
+			// Move focus to right panel if there are selections or a preview to scroll
+			if len(m.Selector.GetSelection()) > 0 || m.ShowPreview {
 				m.FocusRight = true
 			}
 
@@ -25,7 +77,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.FocusRight = false
 
 		case "up", "k":
-			if m.FocusRight {
+			if m.FocusRight && m.ShowPreview {
+				// Move the preview cursor
+				m.MovePreviewCursor(-1)
+			} else if m.FocusRight {
 				// Scroll up in right panel
 				if m.RightScroll > 0 {
 					m.RightScroll--
@@ -36,7 +91,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "down", "j":
-			if m.FocusRight {
+			if m.FocusRight && m.ShowPreview {
+				// Move the preview cursor
+				m.MovePreviewCursor(1)
+			} else if m.FocusRight {
 				// Scroll down in right panel
 				selectedNodes := m.GetAllSelectedNodes()
 				if m.RightScroll < len(selectedNodes)-1 {
@@ -61,19 +119,99 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "enter":
 			if !m.FocusRight {
-				// Toggle expand for directories, select for files
-				if m.Cursor.IsDir {
+				// Toggle expand for directories and Go files, select for other files and declarations
+				if m.Cursor.IsDir || m.Navigator.HasSymbols(m.Cursor) {
 					m.ToggleExpand()
 				} else {
 					m.ToggleSelect()
 				}
 			}
 
-		case "space":
+		case " ", "space":
 			if !m.FocusRight {
-				// Toggle selection
+				// Toggle selection (recursively for directories)
 				m.ToggleSelect()
 			}
+
+		case "f":
+			// Cycle through the output formats
+			m.CycleFormat()
+
+		case "d":
+			// Cycle between full files, diffs, both, and Go outlines
+			m.CycleContentMode()
+
+		case "i":
+			// Show or hide ignored entries
+			m.ToggleShowIgnored()
+
+		case "g":
+			// Select the files changed according to git
+			m.SelectChanged()
+
+		case "e":
+			// Preview the in-module packages the selected Go files import
+			m.OpenDeps(deps.Imports)
+
+		case "E":
+			// Preview the files importing the packages of the selection
+			m.OpenDeps(deps.Importers)
+
+		case "t":
+			// Pair the selected files with their tests, or unpair them
+			m.PairCompanions()
+
+		case "T":
+			// Let tests follow the files being selected, or stop them
+			m.ToggleAutoCompanions()
+
+		case "s":
+			// Save the selection as a named profile
+			m.StartPrompt(SaveProfilePrompt)
+
+		case "o":
+			// Replace the selection with a saved profile
+			m.StartPrompt(LoadProfilePrompt)
+
+		case "/", "ctrl+p":
+			// Search every path under the root
+			m.OpenFinder()
+
+		case "p":
+			// Switch the right panel between the selection and the file preview
+			m.TogglePreview()
+
+		case "ctrl+d":
+			// Scroll the preview down half a page
+			if m.ShowPreview {
+				m.MovePreviewCursor(m.layout().rows / 2)
+			}
+
+		case "ctrl+u":
+			// Scroll the preview up half a page
+			if m.ShowPreview {
+				m.MovePreviewCursor(-m.layout().rows / 2)
+			}
+
+		case "v":
+			// Mark the start, then the end, of a line range to select in the preview
+			m.MarkLines()
+
+		case "esc":
+			// Cancel a line range mark
+			m.CancelMark()
+
+		case "x":
+			// Select the whole file instead of its line ranges
+			m.SelectWholeFile()
+
+		case "?":
+			// Show or hide the full help on small terminals
+			m.ShowHelp = !m.ShowHelp
+
+		case "ctrl+f":
+			// Search file contents
+			m.OpenSearch()
 		}
 	}
 
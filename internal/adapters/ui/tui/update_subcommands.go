@@ -1,7 +1,13 @@
 package tui
 
 import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/changes"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
 )
 
 // GetVisibleNodes returns all currently visible nodes based on expansion state
@@ -52,20 +58,225 @@ func (m *Model) MoveCursorDown() {
 	}
 }
 
-// ToggleExpand toggles expansion state of current directory
+// ToggleExpand toggles expansion state of current directory, or lists the declarations of the current Go file
 func (m *Model) ToggleExpand() {
+	expanding := !m.Cursor.Expanded
 	m.Navigator.ToggleExpand(m.Cursor)
+	if expanding && !m.Cursor.IsDir && !m.Cursor.Expanded {
+		m.clearMessages()
+		m.StatusMessage = "No declarations found in " + m.getRelativePath(m.Cursor.Path, m.Root.Path) + "; press 'Space' to select the file"
+	}
+}
+
+// ToggleShowIgnored shows or hides ignored entries, keeping the cursor on a visible node
+func (m *Model) ToggleShowIgnored() {
+	m.Navigator.SetShowIgnored(!m.Navigator.ShowIgnored())
+	m.Cursor = m.Navigator.NearestVisible(m.Cursor)
 }
 
 // ToggleSelect toggles selection state of current file
 func (m *Model) ToggleSelect() {
 	m.Selector.ToggleSelect(m.Cursor)
+	m.clearMessages()
+}
+
+// clearMessages removes the status and info messages once the selection changes
+func (m *Model) clearMessages() {
+	m.StatusMessage = ""
+	m.InfoMessage = ""
+	m.CopyRefused = false
+}
+
+// StartPrompt starts typing a profile name
+func (m *Model) StartPrompt(kind PromptKind) {
+	if m.Profiles == nil {
+		return
+	}
+	m.clearMessages()
+	m.Prompt = kind
+	m.PromptInput = ""
+}
+
+// UpdatePrompt edits the prompt input, confirming it with Enter and cancelling with Esc
+func (m *Model) UpdatePrompt(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		kind, name := m.Prompt, strings.TrimSpace(m.PromptInput)
+		m.Prompt = NoPrompt
+		if name == "" {
+			return
+		}
+		if kind == SaveProfilePrompt {
+			m.SaveProfile(name)
+		} else {
+			_ = m.LoadProfile(name) // Failures are shown in the status message
+		}
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.Prompt = NoPrompt
+	case tea.KeyBackspace:
+		if runes := []rune(m.PromptInput); len(runes) > 0 {
+			m.PromptInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.PromptInput += string(msg.Runes)
+	}
+}
+
+// SaveProfile stores the current selection under name
+func (m *Model) SaveProfile(name string) {
+	if err := m.Profiles.Save(name, m.Root); err != nil {
+		m.StatusMessage = "Failed to save profile: " + err.Error()
+		return
+	}
+	m.InfoMessage = fmt.Sprintf("Saved %d files as profile %q", len(m.Selector.GetSelection()), name)
+}
+
+// LoadProfile replaces the current selection with the profile called name,
+// reporting files of the profile that no longer exist
+func (m *Model) LoadProfile(name string) error {
+	result, err := m.Profiles.Load(name, m.Root)
+	if err != nil {
+		m.StatusMessage = "Failed to load profile: " + err.Error()
+		return err
+	}
+	m.RightScroll = 0
+	m.InfoMessage = fmt.Sprintf("Loaded %d files from profile %q", result.Selected, name)
+	if len(result.Missing) > 0 {
+		m.StatusMessage = fmt.Sprintf("%d files of the profile no longer exist: %s",
+			len(result.Missing), strings.Join(result.Missing, ", "))
+	}
+	return nil
+}
+
+// SelectChanged adds every modified, staged, and untracked file to the selection
+func (m *Model) SelectChanged() {
+	if m.Changes == nil {
+		return
+	}
+	m.clearMessages()
+
+	paths, err := m.Changes.Paths(changes.SourceChanged)
+	if err != nil {
+		m.StatusMessage = "Failed to read git status: " + err.Error()
+		return
+	}
+	missing := m.Selector.SelectPaths(m.Root, paths)
+	m.InfoMessage = fmt.Sprintf("Selected %d changed files", len(paths)-len(missing))
+}
+
+// PairCompanions adds the tests and other companions of the selected files, or removes them when every pair is complete
+func (m *Model) PairCompanions() {
+	m.clearMessages()
+	added, removed := m.Selector.PairCompanions(m.Root)
+	switch {
+	case added > 0:
+		m.InfoMessage = fmt.Sprintf("Added %d companion files", added)
+	case removed > 0:
+		m.InfoMessage = fmt.Sprintf("Removed %d companion files", removed)
+	default:
+		m.InfoMessage = "No companion files found for the selection"
+	}
 }
 
-// CopySelection copies all selected files to clipboard
+// ToggleAutoCompanions switches whether companions are selected and deselected with their files
+func (m *Model) ToggleAutoCompanions() {
+	m.clearMessages()
+	m.Selector.SetAutoCompanions(!m.Selector.AutoCompanions())
+	if m.Selector.AutoCompanions() {
+		m.InfoMessage = "Companion files now follow the files you select"
+	} else {
+		m.InfoMessage = "Companion files no longer follow the files you select"
+	}
+}
+
+// PromptLabel returns the text shown before the prompt input
+func (m *Model) PromptLabel() string {
+	if m.Prompt == SaveProfilePrompt {
+		return "Save selection as profile: "
+	}
+
+	label := "Load profile"
+	if names, err := m.Profiles.Names(); err == nil && len(names) > 0 {
+		label += " (" + strings.Join(names, ", ") + ")"
+	}
+	return label + ": "
+}
+
+// EstimateTokens returns the token counts of the selected files in display order
+func (m *Model) EstimateTokens() tokens.Estimate {
+	if m.Estimator == nil {
+		return tokens.Estimate{}
+	}
+	return m.Estimator.Estimate(m.GetAllSelectedNodes())
+}
+
+// BudgetWarning returns a warning when the copied payload is over a non-refusing
+// token budget, counted as rendered with diffs, outlines, and redaction
+func (m *Model) BudgetWarning() string {
+	budget := m.Copier.Budget()
+	total := m.Result.Tokens
+	if budget.Refuse || !budget.Exceeded(total) {
+		return ""
+	}
+	return fmt.Sprintf("Warning: copied ~%d tokens, over the budget of %d", total, budget.Max)
+}
+
+// CopyReport returns what the user should know about a copy that went ahead,
+// one item per line: the totals, the files left out with the reason, a budget
+// overrun, sensitive files, files copied as a stub, and redacted secrets
+func (m *Model) CopyReport() string {
+	result := m.Result
+	lines := []string{"Copied " + result.Summary() + " to " + m.Copier.SinkName()}
+	for _, file := range result.Skipped {
+		lines = append(lines, "Skipped "+file.Path+": "+file.Reason)
+	}
+	if warning := m.BudgetWarning(); warning != "" {
+		lines = append(lines, warning)
+	}
+	if len(m.Sensitive) > 0 {
+		lines = append(lines, "Warning: copied files named like credentials: "+strings.Join(m.Sensitive, ", "))
+	}
+	if stubs := result.Stubs(); len(stubs) > 0 {
+		lines = append(lines, "Copied as a stub with size and hash: "+strings.Join(stubs, ", "))
+	}
+	if result.Redacted > 0 {
+		lines = append(lines, "Redacted "+result.RedactedSecrets())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CopySelection checks the selection for sensitive files, renders it, and
+// checks the token budget. The payload is written to the output sink after the
+// program exits, so sinks that share the terminal (stdout, OSC 52) do not
+// collide with the UI.
 func (m *Model) CopySelection() error {
-	selection := m.Selector.GetSelection()
-	return m.Copier.CopySelectionToClipboard(selection)
+	sensitive, err := m.Copier.CheckSensitive(m.Selector.GetSelection())
+	if err != nil {
+		return err
+	}
+	m.Sensitive = sensitive
+
+	result, err := m.Copier.RenderSelection(m.Selector.GetSelection())
+	if err != nil {
+		return err
+	}
+	if err := m.Copier.CheckBudget(result.Payload); err != nil {
+		return err
+	}
+
+	m.CopyRequested = true
+	m.Result = result
+	return nil
+}
+
+// CycleFormat switches the copier to the next available output format
+func (m *Model) CycleFormat() {
+	m.Copier.CycleFormatter()
+}
+
+// CycleContentMode switches between copying full files, diffs, both, and Go outlines
+func (m *Model) CycleContentMode() {
+	m.Copier.CycleContentMode()
 }
 
 // MoveToPreviousDirectory moves to the previous directory in the tree
@@ -1,36 +1,54 @@
 package tui
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
 // View renders the model as a string
 func (m Model) View() string {
-	// Number of lines to display in each panel
-	maxLines := m.MaxVisibleRows - 4 // Reserve 4 rows for help text
-
-	// Build the tree view (left panel)
-	leftView := m.buildTreeView(maxLines)
-
-	// Build the selection view (right panel)
-	rightView := m.buildSelectionView(maxLines)
-
-	// Set fixed width for the tree view
-	treeViewWidth := 50 // Adjust as needed
-
-	// Apply styles to panels
-	leftStyle := lipgloss.NewStyle().Width(treeViewWidth)
-	rightStyle := lipgloss.NewStyle()
-
-	// Join panels horizontally
-	combinedView := lipgloss.JoinHorizontal(lipgloss.Top,
-		leftStyle.Render(leftView),
-		rightStyle.Render(rightView))
-
-	// Add help text at bottom
-	helpText := "\nHow to use\n" +
-		"Press 'w'/Ctrl+'c' to quit and copy, 'Space' to select file, 'Enter' to expand/collapse dir\n" +
-		"Navigation: 'h'/'l' to switch panels, 'j'/'k' to move up/down, 'J'/'K' to jump between directories"
-
-	return combinedView + helpText
+	// Size the panels to the terminal
+	l := m.layout()
+
+	// Build the tree view (left panel), or the fuzzy finder, content search, or dependency preview while open
+	var leftView string
+	if m.Finder != nil {
+		leftView = m.buildFinderView(l.rows, l.treeWidth)
+	} else if m.SearchOpen {
+		leftView = m.buildSearchView(l.rows, l.treeWidth)
+	} else if m.Deps != nil {
+		leftView = m.buildDepsView(l.rows, l.treeWidth)
+	} else {
+		leftView = m.buildTreeView(l.rows, l.treeWidth)
+	}
+
+	// Build the selection view (right panel), or the preview of the file under the cursor
+	var rightView string
+	if m.ShowPreview {
+		rightView = m.buildPreviewView(l.rows, l.rightWidth)
+	} else {
+		rightView = m.buildSelectionView(l.rows, l.rightWidth)
+	}
+	leftView = strings.TrimSuffix(leftView, "\n")
+	rightView = strings.TrimSuffix(rightView, "\n")
+
+	// Lines are already cut to the panel widths; MaxWidth only guards against wrapping
+	var combinedView string
+	switch {
+	case l.compact && m.FocusRight && m.Finder == nil && !m.SearchOpen && m.Deps == nil:
+		// Narrow terminals show only the focused panel; overlays replace the tree
+		combinedView = lipgloss.NewStyle().MaxWidth(l.rightWidth).Render(rightView)
+	case l.compact:
+		combinedView = lipgloss.NewStyle().MaxWidth(l.treeWidth).Render(leftView)
+	default:
+		// Join panels horizontally, keeping a gap between them
+		leftWidth := l.treeWidth + panelGap
+		combinedView = lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(leftWidth).MaxWidth(leftWidth).Render(leftView),
+			lipgloss.NewStyle().MaxWidth(l.rightWidth).Render(rightView))
+	}
+
+	// Add the prompt, messages, and help text at the bottom
+	return combinedView + "\n" + strings.Join(l.footer, "\n")
 }
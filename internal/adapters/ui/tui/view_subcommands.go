@@ -1,16 +1,20 @@
 package tui
 
 import (
+	"fmt"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
 )
 
-// buildTreeView constructs the tree view (left panel)
-func (m *Model) buildTreeView(maxLines int) string {
+// buildTreeView constructs the tree view (left panel) in at most maxLines lines of width cells
+func (m *Model) buildTreeView(maxLines int, width int) string {
 	// Get all visible nodes
 	visibleNodes := m.GetVisibleNodes()
 
@@ -23,27 +27,28 @@ func (m *Model) buildTreeView(maxLines int) string {
 		}
 	}
 
-	// Determine display range
+	// Determine display range, leaving room for the breadcrumbs and the "..." markers
+	nodeRows := max(maxLines-4, 1)
 	startIdx := 0
 	endIdx := len(visibleNodes)
 
-	if cursorIdx >= 0 && len(visibleNodes) > maxLines {
+	if cursorIdx >= 0 && len(visibleNodes) > nodeRows {
 		// Center the cursor in view
-		halfHeight := maxLines / 2
+		halfHeight := nodeRows / 2
 
 		if cursorIdx > halfHeight {
 			startIdx = cursorIdx - halfHeight
 		}
 
-		if startIdx+maxLines > len(visibleNodes) {
-			startIdx = len(visibleNodes) - maxLines
+		if startIdx+nodeRows > len(visibleNodes) {
+			startIdx = len(visibleNodes) - nodeRows
 		}
 
 		if startIdx < 0 {
 			startIdx = 0
 		}
 
-		endIdx = startIdx + maxLines
+		endIdx = startIdx + nodeRows
 		if endIdx > len(visibleNodes) {
 			endIdx = len(visibleNodes)
 		}
@@ -53,7 +58,7 @@ func (m *Model) buildTreeView(maxLines int) string {
 	var s strings.Builder
 
 	// Add breadcrumb path at top
-	s.WriteString("Path: " + m.formatBreadcrumbs() + "\n\n")
+	s.WriteString("Path: " + truncateLeft(m.formatBreadcrumbs(), width-len("Path: ")) + "\n\n")
 
 	// Indicate if there are hidden nodes above
 	if startIdx > 0 {
@@ -64,7 +69,7 @@ func (m *Model) buildTreeView(maxLines int) string {
 	for i := startIdx; i < endIdx; i++ {
 		node := visibleNodes[i]
 		level := m.GetNodeLevel(node)
-		s.WriteString(m.renderSingleNode(node, level, node == m.Cursor, !m.FocusRight))
+		s.WriteString(m.renderSingleNode(node, level, node == m.Cursor, !m.FocusRight, width))
 	}
 
 	// Indicate if there are hidden nodes below
@@ -75,21 +80,31 @@ func (m *Model) buildTreeView(maxLines int) string {
 	return s.String()
 }
 
-// buildSelectionView constructs the selection view (right panel)
-func (m *Model) buildSelectionView(maxLines int) string {
+// buildSelectionView constructs the selection view (right panel) in at most maxLines lines of width cells
+func (m *Model) buildSelectionView(maxLines int, width int) string {
 	var s strings.Builder
 
-	// Add title with selection count
-	s.WriteString("Selected Files (" + strconv.Itoa(len(m.Selector.GetSelection())) + "):\n\n")
+	// Add title with selection count, output format, and content mode unless files are copied in full
+	options := "format: " + m.Copier.Formatter().Name()
+	switch content := m.Copier.ContentMode(); {
+	case copier.NeedsDiff(content):
+		options += ", content: " + content + " vs " + m.Copier.DiffRef()
+	case content != copier.ContentFull:
+		options += ", content: " + content
+	}
+	s.WriteString(truncate("Selected Files ("+strconv.Itoa(len(m.Selector.GetSelection()))+")"+
+		" ["+options+"]:", width) + "\n")
 
 	// Show message if no files are selected
 	if len(m.Selector.GetSelection()) == 0 {
-		s.WriteString("No files selected\n")
+		s.WriteString("\nNo files selected\n")
 		return s.String()
 	}
 
-	// Get all selected nodes
+	// Get all selected nodes and their token counts
 	selectedNodes := m.GetAllSelectedNodes()
+	estimate := m.EstimateTokens()
+	s.WriteString(truncate(m.formatTokenTotal(estimate.Total), width) + "\n")
 
 	// Determine display range based on scroll position
 	startIdx := m.RightScroll
@@ -98,7 +113,7 @@ func (m *Model) buildSelectionView(maxLines int) string {
 	}
 
 	// Calculate visible range
-	visibleCount := maxLines - 2 // Subtract title and empty line
+	visibleCount := max(maxLines-4, 1) // Subtract title, token total, and the "..." markers
 	endIdx := startIdx + visibleCount
 	if endIdx > len(selectedNodes) {
 		endIdx = len(selectedNodes)
@@ -118,8 +133,8 @@ func (m *Model) buildSelectionView(maxLines int) string {
 
 		node := selectedNodes[i]
 
-		// Create relative path
-		relPath := m.getRelativePath(node.Path, m.Root.Path)
+		// Create relative path, followed by the selected line ranges of partial files
+		relPath := linerange.Join(m.getRelativePath(node.Path, m.Root.Path), node.Ranges)
 
 		// Format line with index
 		numStr := strconv.Itoa(i + 1)
@@ -128,7 +143,16 @@ func (m *Model) buildSelectionView(maxLines int) string {
 			padding = ""
 		}
 
-		line := numStr + "." + padding + relPath
+		// Cut long paths at the start so the file name and token count stay visible
+		suffix := ""
+		if i < len(estimate.Files) {
+			suffix = fmt.Sprintf(" (~%d)", estimate.Files[i].Tokens)
+		}
+		if badge := m.classBadge(node); badge != "" {
+			suffix += " " + badge
+		}
+		prefix := numStr + "." + padding
+		line := prefix + truncateLeft(relPath, width-2-len(prefix)-lipgloss.Width(suffix)) + suffix
 
 		// Highlight current scroll position if right panel is focused
 		if m.FocusRight && i == m.RightScroll {
@@ -150,8 +174,24 @@ func (m *Model) buildSelectionView(maxLines int) string {
 	return s.String()
 }
 
+// formatTokenTotal renders the total token count, highlighting it when over budget
+func (m *Model) formatTokenTotal(total int) string {
+	budget := m.Copier.Budget()
+	text := fmt.Sprintf("~%d tokens", total)
+	if budget.Max > 0 {
+		text += fmt.Sprintf(" / %d budget", budget.Max)
+	}
+	if budget.Exceeded(total) {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render(text + " (over budget)")
+	}
+	return text
+}
+
 // renderSingleNode renders a single node for the tree view
-func (m *Model) renderSingleNode(node *entities.FileNode, level int, isCursor bool, isFocused bool) string {
+// Lines longer than width are cut with an ellipsis.
+func (m *Model) renderSingleNode(node *entities.FileNode, level int, isCursor bool, isFocused bool, width int) string {
 	prefix := strings.Repeat("  ", level)
 	line := prefix
 
@@ -169,22 +209,67 @@ func (m *Model) renderSingleNode(node *entities.FileNode, level int, isCursor bo
 		line += "  "
 	}
 
-	// Render node based on type and state
-	if node.IsDir {
+	// Render node based on type and state; files selected by line ranges count as partially selected
+	state := m.Selector.SelectionState(node)
+	if state == selector.FullySelected && len(node.Ranges) > 0 {
+		state = selector.PartiallySelected
+	}
+	label := selectionIndicator(state) + " "
+	switch {
+	case node.Symbol != nil:
+		label += lipgloss.NewStyle().Foreground(lipgloss.Color("110")).Render(node.Symbol.Name)
+	case node.IsDir:
 		if node.Expanded {
-			line += "📂 " + filepath.Base(node.Path) + "\n"
+			label += "📂 " + filepath.Base(node.Path)
 		} else {
-			line += "📁 " + filepath.Base(node.Path) + "\n"
+			label += "📁 " + filepath.Base(node.Path)
 		}
-	} else {
-		if node.Selected {
-			line += "[✓] " + filepath.Base(node.Path) + "\n"
-		} else {
-			line += "[ ] " + filepath.Base(node.Path) + "\n"
+	default:
+		label += filepath.Base(node.Path)
+	}
+
+	// Dim ignored entries, which are only listed when ignored entries are shown
+	if node.Ignored {
+		label = lipgloss.NewStyle().Faint(true).Render(label)
+	}
+
+	// Mark files that differ from git HEAD
+	if !node.IsDir && node.Symbol == nil && m.Changes != nil {
+		if status := m.Changes.Status(node.Path); status != entities.Unchanged {
+			label += " " + changeBadgeStyle(status).Render(status.Badge())
 		}
 	}
 
-	return line
+	// Mark binary, oversized, and transcoded files, which are not copied byte for byte
+	if badge := m.classBadge(node); badge != "" {
+		label += " " + badge
+	}
+
+	return truncate(line+label, width) + "\n"
+}
+
+// changeBadgeStyle returns the style of a git status badge
+func changeBadgeStyle(status entities.ChangeStatus) lipgloss.Style {
+	switch status {
+	case entities.Added:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	case entities.Untracked:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	default:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	}
+}
+
+// selectionIndicator returns the checkbox shown for a selection state
+func selectionIndicator(state selector.SelectionState) string {
+	switch state {
+	case selector.FullySelected:
+		return "[✓]"
+	case selector.PartiallySelected:
+		return "[~]"
+	default:
+		return "[ ]"
+	}
 }
 
 // formatBreadcrumbs creates a breadcrumb navigation string
@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
+)
+
+// treeChangedMsg carries a burst of changes to the files on disk
+type treeChangedMsg watch.Batch
+
+// waitForChanges waits for the next burst of changes off the UI goroutine
+func waitForChanges(watcher *watch.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := watcher.Next()
+		if !ok {
+			return nil
+		}
+		return treeChangedMsg(batch)
+	}
+}
+
+// ApplyChanges updates the tree with a burst of changes to the files on disk.
+// Expanded directories and selected files that still exist stay as they are;
+// removed files leave the selection, and a cursor on a removed node, or on a
+// declaration listed again, moves to the closest node that is left. Selected
+// declarations of written files follow their new lines, and those that are
+// gone leave the selection with a status message. Cached counts, classes, and previews of
+// changed files, and the imports of the module, are dropped so they are read again.
+func (m *Model) ApplyChanges(batch watch.Batch) {
+	stale := m.selectedSymbols(batch.Written)
+	removed := watch.Apply(m.Navigator, m.Root, batch)
+	var gone []string
+	for node, old := range stale {
+		if dropped := m.Selector.RemapSymbols(node, old); len(dropped) > 0 {
+			gone = append(gone, m.getRelativePath(node.Path, m.Root.Path)+": "+strings.Join(dropped, ", "))
+		}
+	}
+	if len(gone) > 0 {
+		slices.Sort(gone)
+		m.StatusMessage = "Removed from the selection, as they are no longer declared: " + strings.Join(gone, "; ")
+	}
+	for _, node := range removed {
+		m.Selector.Forget(node)
+		forEachNode(node, func(gone *entities.FileNode) { m.forgetFile(gone.Path) })
+	}
+	for _, path := range batch.Written {
+		m.forgetFile(path)
+	}
+	if batch.Lost {
+		if m.Estimator != nil {
+			m.Estimator.Reset()
+		}
+		m.classes = make(map[string]classify.Class)
+		*m.previewCache = previewCache{}
+	}
+	if m.Resolver != nil {
+		m.Resolver.Reset()
+	}
+	m.Cursor = m.Navigator.NearestVisible(nearestAttached(m.Cursor))
+	if m.PreviewNode != nil && nearestAttached(m.PreviewNode) != m.PreviewNode {
+		m.PreviewNode = nil
+	}
+
+	// Created and deleted files change their badges; badges are best effort
+	if m.Changes != nil {
+		_ = m.Changes.Refresh()
+	}
+	if m.Finder != nil && (len(batch.Dirs) > 0 || len(batch.Ignores) > 0 || batch.Lost) {
+		m.Finder = m.newFinder(m.Finder.Query)
+	}
+}
+
+// selectedSymbols returns the declarations listed under the written files
+// selected by line ranges, before they are read again
+func (m *Model) selectedSymbols(written []string) map[*entities.FileNode][]entities.Symbol {
+	stale := make(map[*entities.FileNode][]entities.Symbol)
+	selection := m.Selector.GetSelection()
+	for _, path := range written {
+		node := selection[path]
+		if node == nil || !node.Expanded || len(node.Ranges) == 0 {
+			continue
+		}
+		for _, child := range node.Children {
+			if child.Symbol != nil {
+				stale[node] = append(stale[node], *child.Symbol)
+			}
+		}
+	}
+	return stale
+}
+
+// forgetFile drops what was cached about the file at path
+func (m *Model) forgetFile(path string) {
+	if m.Estimator != nil {
+		m.Estimator.Forget(path)
+	}
+	delete(m.classes, path)
+	if m.previewCache.path == path {
+		*m.previewCache = previewCache{}
+	}
+}
+
+// forEachNode calls fn for node and every node loaded under it
+func forEachNode(node *entities.FileNode, fn func(*entities.FileNode)) {
+	fn(node)
+	for _, child := range node.Children {
+		forEachNode(child, fn)
+	}
+}
+
+// nearestAttached returns node, or the closest ancestor still in the tree when
+// node or one of its ancestors was removed from its parent's children
+func nearestAttached(node *entities.FileNode) *entities.FileNode {
+	nearest := node
+	for child := node; child.Parent != nil; child = child.Parent {
+		if !slices.Contains(child.Parent.Children, child) {
+			nearest = child.Parent
+		}
+	}
+	return nearest
+}
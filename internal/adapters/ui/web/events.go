@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
+)
+
+// TreeWatcher reports the changes to the directories it watches in bursts
+type TreeWatcher interface {
+	Watch(path string) error
+	Next() (watch.Batch, bool)
+}
+
+// changeEvent is a burst of changes sent to the page by /api/events, with root-relative paths
+type changeEvent struct {
+	Dirs    []string `json:"dirs,omitempty"`    // Directories whose entries were created or removed
+	Written []string `json:"written,omitempty"` // Files whose contents were written
+	Lost    bool     `json:"lost,omitempty"`    // Changes were dropped or ignore rules changed; the page reloads the tree
+}
+
+// subscriber is a page listening to /api/events. Bursts that arrive while
+// the previous one is being sent are merged, so a slow page misses nothing.
+type subscriber struct {
+	mu      sync.Mutex
+	pending changeEvent
+	notify  chan struct{}
+}
+
+// add merges a burst into the changes waiting to be sent
+func (s *subscriber) add(event changeEvent) {
+	s.mu.Lock()
+	s.pending.Dirs = append(s.pending.Dirs, event.Dirs...)
+	s.pending.Written = append(s.pending.Written, event.Written...)
+	s.pending.Lost = s.pending.Lost || event.Lost
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the changes waiting to be sent, leaving none
+func (s *subscriber) take() changeEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := s.pending
+	s.pending = changeEvent{}
+	return event
+}
+
+// watchDirectory watches a directory listed to the page; without a watcher the page is not refreshed
+func (h *Handler) watchDirectory(path string) {
+	if h.opts.Watcher != nil {
+		_ = h.opts.Watcher.Watch(path) // A directory that cannot be watched is refreshed on reload
+	}
+}
+
+// broadcastChanges sends every burst of changes to the pages listening, until the watcher is closed
+func (h *Handler) broadcastChanges() {
+	root, err := h.repo.GetCurrentDirectory()
+	if err != nil {
+		return
+	}
+	for {
+		batch, ok := h.opts.Watcher.Next()
+		if !ok {
+			return
+		}
+		// Any entry under a changed .gitignore may be shown or hidden now
+		if h.opts.Ignore != nil {
+			for _, dir := range batch.Ignores {
+				h.opts.Ignore.Forget(dir)
+			}
+		}
+		// Imports are read again on the next expansion
+		if h.opts.Deps != nil {
+			h.opts.Deps.Reset()
+		}
+		event := changeEvent{
+			Dirs:    relativePaths(root, batch.Dirs),
+			Written: relativePaths(root, batch.Written),
+			Lost:    batch.Lost || len(batch.Ignores) > 0,
+		}
+		h.mu.Lock()
+		for sub := range h.subscribers {
+			sub.add(event)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// relativePaths returns the slash-separated paths relative to root of those under it
+func relativePaths(root string, paths []string) []string {
+	var rel []string
+	for _, path := range paths {
+		r, err := filepath.Rel(root, path)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+// handleEvents streams the changes to the files on disk as server-sent events,
+// one JSON changeEvent per burst, so the page refreshes the directories it
+// shows. It answers 404 when files are not watched.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Watcher == nil {
+		http.Error(w, "file watching is not enabled", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := &subscriber{notify: make(chan struct{}, 1)}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.subscribers, sub)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.notify:
+			data, err := json.Marshal(sub.take())
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
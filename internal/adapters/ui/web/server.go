@@ -2,16 +2,28 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/atotto/clipboard"
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/outline"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/profiles"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/search"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
 )
 
 // TreeNode represents a file/directory in the JSON tree response
@@ -19,25 +31,141 @@ type TreeNode struct {
 	Name     string     `json:"name"`
 	Path     string     `json:"path"`
 	IsDir    bool       `json:"isDir"`
+	Ignored  bool       `json:"ignored,omitempty"`
+	Status   string     `json:"status,omitempty"`   // Git status badge: "M", "A", or "?"
+	Kind     string     `json:"kind,omitempty"`     // "binary" or "oversized" for files copied as a stub
+	Encoding string     `json:"encoding,omitempty"` // Encoding of text files transcoded to UTF-8, e.g. "shift_jis"
+	Size     int64      `json:"size,omitempty"`     // Size in bytes of files copied as a stub
 	Children []TreeNode `json:"children,omitempty"`
+
+	ChildCount int `json:"childCount,omitempty"` // Visible entries of a directory listed by /api/children
+}
+
+// treePage is one page of the entries of a directory in the /api/children response
+type treePage struct {
+	Path     string     `json:"path"`
+	Children []TreeNode `json:"children"`
+	Total    int        `json:"total"`          // Visible entries in the directory
+	Offset   int        `json:"offset"`         // Index of the first entry of the page
+	Next     int        `json:"next,omitempty"` // Offset of the next page; 0 on the last page
+}
+
+// Page sizes of /api/children
+const (
+	defaultPageSize = 200
+	maxPageSize     = 1000
+)
+
+// FileClassifier tells text files from binary and oversized ones, and decodes text to UTF-8
+type FileClassifier interface {
+	// Inspect returns the class of the file at path from its beginning
+	Inspect(path string) (classify.Class, error)
+
+	// ReadText returns the file at path as Text does, without loading binary and oversized files into memory
+	ReadText(path string) (string, classify.Class, error)
+}
+
+// ChangeTracker reports version control changes of files
+type ChangeTracker interface {
+	Refresh() error
+	Status(path string) entities.ChangeStatus
+	Paths(source string) ([]string, error)
+	IsDeleted(path string) bool
+}
+
+// ContentSearcher finds the files whose contents match a query
+type ContentSearcher interface {
+	Search(query search.Query) (search.Results, error)
+}
+
+// DependencyResolver follows the Go imports between the files of the module
+type DependencyResolver interface {
+	Expand(selected []string, direction deps.Direction, depth int) ([]deps.File, error)
+	Depth() int
+	Reset()
 }
 
-// Handler handles HTTP requests for the web UI
+// CompanionMatcher pairs files with their companions, such as their tests
+type CompanionMatcher interface {
+	Follow(paths []string, exists func(path string) bool) []string
+	Pair(selected []string, exists func(path string) bool) (add, remove []string)
+}
+
+// IgnoreMatcher decides whether a path is excluded by ignore rules
+type IgnoreMatcher interface {
+	IsIgnored(path string, isDir bool) bool
+
+	// Forget drops the rules read from the ignore file of a directory, so they are read again
+	Forget(dir string)
+}
+
+// Options configures the behavior of the web UI
+type Options struct {
+	Format    string                         // Default output format for /api/copy (see copier.FormatNames)
+	Template  *copier.TemplateFormatter      // User-defined template offered as the "template" format, if any
+	Tokenizer tokens.Tokenizer               // Tokenizer for /api/tokens and the budget; defaults to the approximation
+	Budget    tokens.Budget                  // Token budget applied to /api/copy
+	Ignore    IgnoreMatcher                  // Matcher for entries hidden from /api/tree unless ?ignored=1; nil hides nothing
+	Sink      repositories.OutputSink        // Destination of /api/copy; nil means the clipboard of the file repository
+	Profiles  repositories.ProfileRepository // Storage of saved selections; nil disables /api/profiles
+	Profile   string                         // Profile the page starts with; empty starts with no selection
+	Changes   ChangeTracker                  // Source of git status badges and /api/changes; nil disables both
+	Selected  []string                       // Root-relative paths selected when the page loads, optionally with line ranges
+	Content   string                         // Default content mode for /api/copy (see copier.ContentModeNames)
+	Diff      copier.DiffSource              // Source of diffs for the diff content modes; nil offers only full content
+	Search    ContentSearcher                // Backend of /api/search; nil disables content search
+	Deps      DependencyResolver             // Backend of /api/deps; nil disables adding dependencies
+
+	Companions     CompanionMatcher // Backend of /api/companions; nil disables pairing companion files
+	AutoCompanions bool             // Whether companions follow the files toggled when the page loads
+	Redactor       copier.Redactor  // Hides secrets in /api/copy and flags sensitive files; nil copies verbatim
+	Classifier     FileClassifier   // Decodes text and stubs out binary and oversized files; nil serves raw bytes
+	Watcher        TreeWatcher      // Reports changes to the directories listed by /api/children over /api/events; nil disables both
+}
+
+// Handler handles HTTP requests for the web UI. Files are read through the
+// same repository and use cases as the terminal UI, so both apply the same
+// ignore, selection, and formatting rules.
 type Handler struct {
-	rootDir string
-	mux     *http.ServeMux
+	repo repositories.FileRepository
+	opts Options
+	mux  *http.ServeMux
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{} // Pages listening to /api/events
 }
 
-// NewHandler creates a new web UI handler rooted at the given directory
-func NewHandler(rootDir string) *Handler {
+// NewHandler creates a new web UI handler serving the current directory of repo
+func NewHandler(repo repositories.FileRepository, opts Options) *Handler {
+	if opts.Tokenizer == nil {
+		opts.Tokenizer = tokens.NewApproxTokenizer()
+	}
 	h := &Handler{
-		rootDir: rootDir,
-		mux:     http.NewServeMux(),
+		repo:        repo,
+		opts:        opts,
+		mux:         http.NewServeMux(),
+		subscribers: make(map[*subscriber]struct{}),
 	}
 	h.mux.HandleFunc("/api/tree", h.handleTree)
+	h.mux.HandleFunc("/api/children", h.handleChildren)
+	h.mux.HandleFunc("/api/files", h.handleFiles)
 	h.mux.HandleFunc("/api/file", h.handleFile)
+	h.mux.HandleFunc("/api/symbols", h.handleSymbols)
 	h.mux.HandleFunc("/api/copy", h.handleCopy)
+	h.mux.HandleFunc("/api/formats", h.handleFormats)
+	h.mux.HandleFunc("/api/tokens", h.handleTokens)
+	h.mux.HandleFunc("/api/profiles", h.handleProfiles)
+	h.mux.HandleFunc("/api/profiles/", h.handleProfile)
+	h.mux.HandleFunc("/api/changes", h.handleChanges)
+	h.mux.HandleFunc("/api/selection", h.handleSelection)
+	h.mux.HandleFunc("/api/search", h.handleSearch)
+	h.mux.HandleFunc("/api/deps", h.handleDeps)
+	h.mux.HandleFunc("/api/companions", h.handleCompanions)
+	h.mux.HandleFunc("/api/events", h.handleEvents)
 	h.mux.HandleFunc("/", h.handleIndex)
+	if opts.Watcher != nil {
+		go h.broadcastChanges()
+	}
 	return h
 }
 
@@ -45,50 +173,169 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mux.ServeHTTP(w, r)
 }
 
+// handleTree returns the whole tree at once. The page loads it one level at a
+// time from /api/children instead, which stays fast in huge repositories.
 func (h *Handler) handleTree(w http.ResponseWriter, r *http.Request) {
-	tree := h.buildTree(h.rootDir, "")
+	ws, ok := h.openWorkspace(w, r.URL.Query().Get("ignored") == "1")
+	if !ok {
+		return
+	}
+	if h.opts.Changes != nil {
+		_ = h.opts.Changes.Refresh() // Outside a git repository no file gets a badge
+	}
+	tree := h.buildTree(ws, ws.root, ".")
+	tree.Name = filepath.Base(ws.root.Path)
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(tree)
 }
 
-func (h *Handler) buildTree(fullPath, relPath string) TreeNode {
-	name := filepath.Base(fullPath)
-	if relPath == "" {
-		relPath = "."
+// buildTree converts node and the entries under it, loading directories as it
+// descends. Ignored entries are flagged, and left out entirely (without
+// descending into them) unless the workspace shows ignored entries.
+func (h *Handler) buildTree(ws *workspace, node *entities.FileNode, relPath string) TreeNode {
+	tree := h.treeNode(node, relPath)
+	for _, child := range ws.visibleChildren(node) {
+		tree.Children = append(tree.Children, h.buildTree(ws, child, joinPath(relPath, child.Name)))
 	}
+	return tree
+}
 
-	node := TreeNode{
-		Name: name,
-		Path: relPath,
+// treeNode converts a single node, without its children
+func (h *Handler) treeNode(node *entities.FileNode, relPath string) TreeNode {
+	tree := TreeNode{
+		Name:    node.Name,
+		Path:    relPath,
+		IsDir:   node.IsDir,
+		Ignored: node.Ignored,
+	}
+	if !node.IsDir {
+		if h.opts.Changes != nil {
+			tree.Status = h.opts.Changes.Status(node.Path).Badge()
+		}
+		h.classifyNode(&tree, node.Path)
 	}
+	return tree
+}
 
-	info, err := os.Stat(fullPath)
+// handleChildren returns a page of the entries of the directory at ?path=, the
+// root when empty, each directory with the number of its own entries, so the
+// page reads only the directories that are expanded. ?offset= and ?limit= page
+// through huge directories; ?ignored=1 includes ignored entries. Git status is
+// refreshed with the first page of the root, which the page loads first. The
+// directory and its subdirectories are watched, so /api/events reports changes
+// to the entries and counts listed.
+func (h *Handler) handleChildren(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dirPath := query.Get("path")
+	if dirPath == "" {
+		dirPath = "."
+	}
+	relPath, ok := cleanPath(dirPath)
+	if !ok {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	offset, limit, err := pageRange(query.Get("offset"), query.Get("limit"))
 	if err != nil {
-		return node
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if !info.IsDir() {
-		return node
+	ws, ok := h.openWorkspace(w, query.Get("ignored") == "1")
+	if !ok {
+		return
+	}
+	dir := ws.selector.FindNode(ws.root, relPath)
+	if dir == nil || !dir.IsDir {
+		http.Error(w, "directory not found: "+relPath, http.StatusNotFound)
+		return
+	}
+	if h.opts.Changes != nil && relPath == "." && offset == 0 {
+		_ = h.opts.Changes.Refresh() // Outside a git repository no file gets a badge
 	}
 
-	node.IsDir = true
-	entries, err := os.ReadDir(fullPath)
-	if err != nil {
-		return node
+	h.watchDirectory(dir.Path)
+	children := ws.visibleChildren(dir)
+	start, end := min(offset, len(children)), min(offset+limit, len(children))
+	page := treePage{Path: relPath, Children: []TreeNode{}, Total: len(children), Offset: offset}
+	for _, child := range children[start:end] {
+		node := h.treeNode(child, joinPath(relPath, child.Name))
+		if child.IsDir {
+			h.watchDirectory(child.Path)
+			node.ChildCount = len(ws.visibleChildren(child))
+		}
+		page.Children = append(page.Children, node)
 	}
+	if end < len(children) {
+		page.Next = end
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
 
-	for _, entry := range entries {
-		childRel := entry.Name()
-		if relPath != "." {
-			childRel = relPath + "/" + entry.Name()
+// pageRange parses the ?offset= and ?limit= of a page; empty values select
+// the first page of defaultPageSize entries, and limits over maxPageSize are capped
+func pageRange(offsetParam, limitParam string) (offset, limit int, err error) {
+	limit = defaultPageSize
+	if offsetParam != "" {
+		if offset, err = strconv.Atoi(offsetParam); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", offsetParam)
 		}
-		child := h.buildTree(filepath.Join(fullPath, entry.Name()), childRel)
-		node.Children = append(node.Children, child)
 	}
+	if limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", limitParam)
+		}
+	}
+	return offset, min(limit, maxPageSize), nil
+}
+
+// handleFiles returns the files that toggling the directory at ?path= selects,
+// as in the terminal UI: every visible file under it, including the files of
+// directories that are not loaded yet. ?ignored=1 includes ignored files.
+func (h *Handler) handleFiles(w http.ResponseWriter, r *http.Request) {
+	dirPath := r.URL.Query().Get("path")
+	if dirPath == "" {
+		dirPath = "."
+	}
+	relPath, ok := cleanPath(dirPath)
+	if !ok {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	ws, ok := h.openWorkspace(w, r.URL.Query().Get("ignored") == "1")
+	if !ok {
+		return
+	}
+	dir := ws.selector.FindNode(ws.root, relPath)
+	if dir == nil || !dir.IsDir {
+		http.Error(w, "directory not found: "+relPath, http.StatusNotFound)
+		return
+	}
+
+	ws.selector.ToggleSelect(dir)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"paths": ws.selector.RelativePaths(ws.root)})
+}
 
-	return node
+// classifyNode marks a file that is copied as a stub or transcoded to UTF-8
+func (h *Handler) classifyNode(node *TreeNode, fullPath string) {
+	if h.opts.Classifier == nil {
+		return
+	}
+	class, err := h.opts.Classifier.Inspect(fullPath)
+	switch {
+	case err != nil:
+	case class.Kind != classify.Text:
+		node.Kind, node.Size = class.Kind.String(), class.Size
+	case class.Transcoded():
+		node.Encoding = class.Encoding
+	}
 }
 
+// handleFile returns the file at ?path= as UTF-8 text, or the stub copied
+// instead of a binary or oversized file. The X-File-Kind and X-File-Encoding
+// headers tell which.
 func (h *Handler) handleFile(w http.ResponseWriter, r *http.Request) {
 	relPath := r.URL.Query().Get("path")
 	if relPath == "" {
@@ -97,30 +344,85 @@ func (h *Handler) handleFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Prevent path traversal
-	cleaned := filepath.Clean(relPath)
-	if strings.HasPrefix(cleaned, "..") || filepath.IsAbs(cleaned) {
+	cleaned, ok := cleanPath(relPath)
+	if !ok {
 		http.Error(w, "invalid path", http.StatusBadRequest)
 		return
 	}
+	ws, ok := h.openWorkspace(w, true)
+	if !ok {
+		return
+	}
+	node := ws.file(cleaned)
+	if node == nil {
+		http.Error(w, "file not found: "+cleaned, http.StatusNotFound)
+		return
+	}
+
+	if h.opts.Classifier == nil {
+		content, err := h.repo.ReadFile(node.Path)
+		if err != nil {
+			http.Error(w, "failed to read file: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(content)
+		return
+	}
+	text, class, err := h.opts.Classifier.ReadText(node.Path)
+	if err != nil {
+		http.Error(w, "failed to read file: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-File-Kind", class.Kind.String())
+	w.Header().Set("X-File-Encoding", class.Encoding)
+	_, _ = io.WriteString(w, text)
+}
 
-	fullPath := filepath.Join(h.rootDir, cleaned)
+// symbol is a top-level declaration in the /api/symbols response
+type symbol struct {
+	Name  string `json:"name"`
+	Start int    `json:"start"` // First line, including the doc comment
+	End   int    `json:"end"`
+}
 
-	// Ensure the resolved path is within rootDir
-	absRoot, _ := filepath.Abs(h.rootDir)
-	absPath, _ := filepath.Abs(fullPath)
-	if !strings.HasPrefix(absPath, absRoot) {
-		http.Error(w, "invalid path", http.StatusBadRequest)
+// handleSymbols returns the top-level declarations of the Go file at ?path=,
+// with the number of lines of the file
+func (h *Handler) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	relPath, ok := cleanPath(r.URL.Query().Get("path"))
+	if !ok || !outline.Supported(relPath) {
+		http.Error(w, "path of a Go file required", http.StatusBadRequest)
+		return
+	}
+	ws, ok := h.openWorkspace(w, true)
+	if !ok {
+		return
+	}
+	node := ws.file(relPath)
+	if node == nil {
+		http.Error(w, "file not found: "+relPath, http.StatusNotFound)
 		return
 	}
 
-	content, err := os.ReadFile(fullPath)
+	content, err := h.repo.ReadFile(node.Path)
 	if err != nil {
 		http.Error(w, "failed to read file: "+err.Error(), http.StatusNotFound)
 		return
 	}
+	declarations, err := outline.Declarations(relPath, content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	_, _ = w.Write(content)
+	symbols, lines := []symbol{}, 0
+	for _, decl := range declarations {
+		symbols = append(symbols, symbol{Name: decl.Name, Start: decl.Lines.Start, End: decl.Lines.End})
+		lines = decl.FileLines
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"symbols": symbols, "lines": lines})
 }
 
 func (h *Handler) handleCopy(w http.ResponseWriter, r *http.Request) {
@@ -129,6 +431,109 @@ func (h *Handler) handleCopy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var req struct {
+		Paths   []string `json:"paths"`
+		Format  string   `json:"format"`
+		Content string   `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	fileCopier, err := h.newCopier(req.Format, req.Content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ws, ok := h.openWorkspace(w, true)
+	if !ok {
+		return
+	}
+
+	// Paths may end with line ranges ("app.go:120-180") to copy only those
+	// lines. Sensitive files are refused, or reported, before anything is read.
+	selection, invalid := ws.selectSpecs(req.Paths)
+	sensitive, err := fileCopier.CheckSensitive(selection)
+	if err != nil {
+		http.Error(w, "copy refused: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Files that cannot be copied are reported with the reason
+	result, err := fileCopier.RenderSelection(selection)
+	if err != nil {
+		http.Error(w, "failed to format selection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Skipped = append(result.Skipped, invalid...)
+	if err := fileCopier.CheckBudget(result.Payload); err != nil {
+		http.Error(w, "copy refused: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err := fileCopier.WritePayload(result.Payload); err != nil {
+		http.Error(w, "failed to copy to "+fileCopier.SinkName()+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]any{
+		"status":    "ok",
+		"sink":      fileCopier.SinkName(),
+		"copied":    result.Copied,
+		"skipped":   result.Skipped,
+		"bytes":     result.Bytes,
+		"tokens":    result.Tokens,
+		"redacted":  result.Redacted,
+		"sensitive": orEmpty(sensitive),
+		"stubs":     result.Stubs(),
+	}
+	if budget := fileCopier.Budget(); budget.Exceeded(result.Tokens) {
+		resp["warning"] = fmt.Sprintf("copied ~%d tokens, over the budget of %d", result.Tokens, budget.Max)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// newCopier creates the copier of one copy, configured like the copier of the
+// terminal UI, rendering with the named format and content mode, or with the
+// defaults of the page when they are empty
+func (h *Handler) newCopier(format, content string) (*copier.FileCopier, error) {
+	if format == "" {
+		format = h.opts.Format
+	}
+	formatter, err := h.formatter(format)
+	if err != nil {
+		return nil, err
+	}
+	if content == "" {
+		content = h.opts.Content
+	}
+
+	fileCopier := copier.NewFileCopier(h.repo)
+	fileCopier.SetBudget(h.opts.Tokenizer, h.opts.Budget)
+	fileCopier.SetSink(h.opts.Sink)
+	fileCopier.SetDiffSource(h.opts.Diff)
+	fileCopier.SetRedactor(h.opts.Redactor)
+	fileCopier.SetClassifier(h.opts.Classifier)
+	fileCopier.SetFormatter(formatter)
+	if err := fileCopier.SetContentMode(content); err != nil {
+		return nil, err
+	}
+	return fileCopier, nil
+}
+
+// tokenCount is the token count of one file in the /api/tokens response
+type tokenCount struct {
+	Path   string `json:"path"`
+	Tokens int    `json:"tokens"`
+}
+
+func (h *Handler) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	var req struct {
 		Paths []string `json:"paths"`
 	}
@@ -137,37 +542,362 @@ func (h *Handler) handleCopy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	absRoot, _ := filepath.Abs(h.rootDir)
+	ws, ok := h.openWorkspace(w, true)
+	if !ok {
+		return
+	}
+	estimator := tokens.NewEstimator(h.repo, h.opts.Tokenizer)
+	estimator.SetClassifier(h.opts.Classifier)
 
-	var builder strings.Builder
-	for _, relPath := range req.Paths {
-		cleaned := filepath.Clean(relPath)
-		if strings.HasPrefix(cleaned, "..") || filepath.IsAbs(cleaned) {
-			continue
-		}
-		fullPath := filepath.Join(h.rootDir, cleaned)
-		absPath, _ := filepath.Abs(fullPath)
-		if !strings.HasPrefix(absPath, absRoot) {
-			continue
+	// Files with line ranges count only those lines; binary and oversized files
+	// count their stub, and files that cannot be read count as zero tokens
+	files := []tokenCount{}
+	total := 0
+	for _, spec := range req.Paths {
+		file := tokenCount{Path: spec}
+		if _, ranges, node, ok := ws.resolve(spec); ok && node != nil {
+			ws.selector.SetRanges(node, ranges)
+			file.Tokens = estimator.Estimate([]*entities.FileNode{node}).Total
 		}
+		files = append(files, file)
+		total += file.Tokens
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"files":    files,
+		"total":    total,
+		"max":      h.opts.Budget.Max,
+		"strict":   h.opts.Budget.Refuse,
+		"exceeded": h.opts.Budget.Exceeded(total),
+	})
+}
+
+func (h *Handler) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Profiles == nil {
+		http.Error(w, "profiles are not available", http.StatusNotFound)
+		return
+	}
 
-		content, err := os.ReadFile(fullPath)
+	names, err := h.opts.Profiles.ListProfiles()
+	if err != nil {
+		http.Error(w, "failed to list profiles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"profiles": names,
+		"initial":  h.opts.Profile,
+	})
+}
+
+// handleProfile loads (GET) or saves (POST) the profile named in the URL path.
+// Loading reports the paths of the profile that no longer name a file under "missing".
+func (h *Handler) handleProfile(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Profiles == nil {
+		http.Error(w, "profiles are not available", http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/profiles/")
+	if err := profiles.ValidateName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		paths, err := h.opts.Profiles.LoadProfile(name)
+		if errors.Is(err, repositories.ErrProfileNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			continue
+			http.Error(w, "failed to load profile: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ws, ok := h.openWorkspace(w, true)
+		if !ok {
+			return
+		}
+		existing, missing := []string{}, []string{}
+		for _, spec := range paths {
+			if _, _, node, ok := ws.resolve(spec); !ok || node == nil {
+				missing = append(missing, spec)
+				continue
+			}
+			existing = append(existing, spec)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"paths":   existing,
+			"missing": missing,
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Paths []string `json:"paths"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		for _, spec := range req.Paths {
+			if _, ok := cleanPath(spec); !ok {
+				http.Error(w, "invalid path: "+spec, http.StatusBadRequest)
+				return
+			}
+		}
+		sort.Strings(req.Paths)
+		if err := h.opts.Profiles.SaveProfile(name, req.Paths); err != nil {
+			http.Error(w, "failed to save profile: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "ok", "count": len(req.Paths)})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleChanges returns the files of a git status source (see changes.SourceNames)
+func (h *Handler) handleChanges(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Changes == nil {
+		http.Error(w, "git status is not available", http.StatusNotFound)
+		return
+	}
+
+	paths, err := h.opts.Changes.Paths(r.URL.Query().Get("source"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if paths == nil {
+		paths = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"paths": paths})
+}
+
+// handleSelection returns the files selected when the page loads
+func (h *Handler) handleSelection(w http.ResponseWriter, r *http.Request) {
+	paths := h.opts.Selected
+	if paths == nil {
+		paths = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"paths": paths})
+}
+
+// searchMatch is a matching line in the /api/search response
+type searchMatch struct {
+	Line  int    `json:"line"`
+	Text  string `json:"text"`
+	Start int    `json:"start"` // Byte offset of the match in text
+	End   int    `json:"end"`
+}
+
+// searchHit is a matching file in the /api/search response
+type searchHit struct {
+	Path    string        `json:"path"`
+	Count   int           `json:"count"`
+	Matches []searchMatch `json:"matches"`
+}
+
+// handleSearch returns the files whose contents match ?q=, a regular expression when ?regex=1
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Search == nil {
+		http.Error(w, "content search is not available", http.StatusNotFound)
+		return
+	}
+
+	query := search.Query{
+		Pattern: r.URL.Query().Get("q"),
+		Regex:   r.URL.Query().Get("regex") == "1",
+	}
+	results, err := h.opts.Search.Search(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files := []searchHit{}
+	for _, file := range results.Files {
+		hit := searchHit{Path: file.Path, Count: file.Count, Matches: []searchMatch{}}
+		for _, match := range file.Matches {
+			hit.Matches = append(hit.Matches, searchMatch(match))
 		}
+		files = append(files, hit)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"files":     files,
+		"truncated": results.Truncated,
+	})
+}
+
+// dependency is a file added by following imports in the /api/deps response
+type dependency struct {
+	Path    string `json:"path"`
+	Package string `json:"package"`
+	Depth   int    `json:"depth"`
+}
+
+// handleDeps returns the files that following the Go imports of the posted
+// selection adds, without selecting them: the packages it imports, or with
+// "importers" set the files importing it. A GET returns the default depth.
+func (h *Handler) handleDeps(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Deps == nil {
+		http.Error(w, "dependencies are not available", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"depth": h.opts.Deps.Depth()})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Paths     []string `json:"paths"`
+		Importers bool     `json:"importers"`
+		Depth     *int     `json:"depth"` // Import levels to follow, 0 for all; the default when missing
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	depth := h.opts.Deps.Depth()
+	if req.Depth != nil {
+		depth = max(*req.Depth, deps.Unlimited)
+	}
+	direction := deps.Imports
+	if req.Importers {
+		direction = deps.Importers
+	}
+
+	ws, ok := h.openWorkspace(w, true)
+	if !ok {
+		return
+	}
+
+	// Line ranges do not matter for imports, which are read from the whole file
+	var selected []string
+	for _, spec := range req.Paths {
+		if relPath, _, _, ok := ws.resolve(spec); ok {
+			selected = append(selected, relPath)
+		}
+	}
+	found, err := h.opts.Deps.Expand(selected, direction, depth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	files := []dependency{}
+	for _, file := range found {
+		files = append(files, dependency(file))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"files": files, "depth": depth})
+}
 
-		builder.WriteString("★★ The contents of " + relPath + " is below.\n")
-		builder.Write(content)
-		builder.WriteString("\n\n")
+// handleCompanions finds companion files, such as tests, without selecting them.
+// A GET returns whether companions follow toggled files by default. A POST
+// returns the companions of the posted paths, or with "pair" set the paths to
+// add and remove to pair the posted selection with its companions.
+func (h *Handler) handleCompanions(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Companions == nil {
+		http.Error(w, "companion files are not available", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"auto": h.opts.AutoCompanions})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Paths []string `json:"paths"`
+		Pair  bool     `json:"pair"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if err := clipboard.WriteAll(builder.String()); err != nil {
-		http.Error(w, "failed to copy to clipboard: "+err.Error(), http.StatusInternalServerError)
+	ws, ok := h.openWorkspace(w, false)
+	if !ok {
 		return
 	}
 
+	// Companions pair whole files, whatever lines of them are selected
+	var paths []string
+	for _, spec := range req.Paths {
+		if relPath, _, _, ok := ws.resolve(spec); ok {
+			paths = append(paths, relPath)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	if !req.Pair {
+		found := h.opts.Companions.Follow(paths, ws.isSelectable)
+		_ = json.NewEncoder(w).Encode(map[string]any{"companions": orEmpty(found)})
+		return
+	}
+	add, remove := h.opts.Companions.Pair(paths, ws.isSelectable)
+	_ = json.NewEncoder(w).Encode(map[string]any{"add": orEmpty(add), "remove": orEmpty(remove)})
+}
+
+// orEmpty returns paths, or an empty slice that encodes as [] instead of null
+func orEmpty(paths []string) []string {
+	if paths == nil {
+		return []string{}
+	}
+	return paths
+}
+
+// formatter resolves a format name, including the configured template
+func (h *Handler) formatter(name string) (copier.Formatter, error) {
+	if name == copier.FormatTemplate && h.opts.Template != nil {
+		return h.opts.Template, nil
+	}
+	return copier.NewFormatter(name)
+}
+
+func (h *Handler) handleFormats(w http.ResponseWriter, r *http.Request) {
+	current := h.opts.Format
+	if current == "" {
+		current = copier.DefaultFormat
+	}
+	formats := copier.FormatNames()
+	if h.opts.Template != nil {
+		formats = append(formats, copier.FormatTemplate)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	contents := copier.AvailableContentModes(h.opts.Diff != nil)
+	content := h.opts.Content
+	if content == "" {
+		content = copier.DefaultContentMode
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"formats":        formats,
+		"default":        current,
+		"contents":       contents,
+		"defaultContent": content,
+	})
 }
 
 func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -175,9 +905,9 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprint(w, indexHTML)
 }
 
-// StartServer starts the web UI server and opens the browser
-func StartServer(rootDir string, port int) error {
-	handler := NewHandler(rootDir)
+// StartServer starts the web UI server for the current directory of repo and opens the browser
+func StartServer(repo repositories.FileRepository, port int, opts Options) error {
+	handler := NewHandler(repo, opts)
 
 	// Find available port if default is taken
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
@@ -226,8 +956,14 @@ const indexHTML = `<!DOCTYPE html>
   header h1 { font-size: 18px; color: #7aa2f7; }
   .header-right { display: flex; align-items: center; gap: 12px; }
   .selected-count { font-size: 14px; color: #9ece6a; }
+  .token-count { font-size: 13px; color: #565f89; }
+  .toggle-label { font-size: 13px; color: #565f89; display: flex; align-items: center; gap: 4px; cursor: pointer; }
+  .token-count.over { color: #f7768e; font-weight: 600; }
   button { background: #7aa2f7; color: #1a1b26; border: none; padding: 8px 16px; border-radius: 6px; cursor: pointer; font-size: 14px; font-weight: 600; }
   button:hover { background: #89b4fa; }
+  select { background: #1a1b26; color: #c0caf5; border: 1px solid #3b4261; padding: 7px 8px; border-radius: 6px; font-size: 13px; }
+  button.secondary { background: #3b4261; color: #c0caf5; }
+  button.secondary:hover { background: #565f89; }
   button:disabled { background: #3b4261; color: #565f89; cursor: default; }
   .container { display: flex; flex: 1; overflow: hidden; }
   .tree-panel { width: 350px; min-width: 250px; overflow-y: auto; border-right: 1px solid #3b4261; padding: 8px 0; }
@@ -236,14 +972,40 @@ const indexHTML = `<!DOCTYPE html>
   .preview-content { flex: 1; overflow: auto; padding: 0; }
   .preview-content pre { margin: 0; padding: 12px; font-family: 'JetBrains Mono', 'Fira Code', monospace; font-size: 13px; line-height: 1.6; white-space: pre; }
   .line-num { display: inline-block; width: 45px; text-align: right; padding-right: 12px; color: #3b4261; user-select: none; }
+  .line-num[data-line] { cursor: pointer; }
+  .line-num[data-line]:hover { color: #7aa2f7; }
+  .line-num.picked { color: #9ece6a; background: #283457; }
+  .line-num.marking { color: #1a1b26; background: #e0af68; }
+  .preview-header button { margin-left: 12px; padding: 2px 10px; font-size: 12px; }
   .tree-item { display: flex; align-items: center; padding: 3px 8px; cursor: pointer; user-select: none; }
   .tree-item:hover { background: #24283b; }
   .tree-item.active { background: #283457; }
+  .tree-item.ignored { opacity: 0.45; }
+  .tree-more { color: #7aa2f7; font-size: 12px; }
+  .child-count { font-size: 11px; color: #565f89; margin-left: 6px; }
   .tree-toggle { width: 18px; text-align: center; font-size: 11px; color: #565f89; flex-shrink: 0; }
   .tree-icon { margin-right: 6px; font-size: 14px; flex-shrink: 0; }
   .tree-name { font-size: 13px; flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+  .tree-symbol { font-family: 'JetBrains Mono', 'Fira Code', monospace; font-size: 12px; color: #7dcfff; flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
   .tree-check { width: 16px; height: 16px; margin-right: 6px; accent-color: #7aa2f7; flex-shrink: 0; }
+  .git-badge { font-size: 12px; font-weight: 600; margin-left: 6px; flex-shrink: 0; }
+  .git-modified { color: #e0af68; }
+  .git-added { color: #9ece6a; }
+  .git-untracked { color: #565f89; }
+  .class-badge { font-size: 11px; margin-left: 6px; padding: 0 4px; border-radius: 3px; background: #24283b; color: #7dcfff; flex-shrink: 0; }
+  .class-binary { color: #565f89; }
+  .class-oversized { color: #e0af68; }
   .no-preview { display: flex; align-items: center; justify-content: center; height: 100%; color: #565f89; font-size: 14px; }
+  .search-bar { display: flex; align-items: center; gap: 8px; padding: 8px 16px; background: #24283b; border-bottom: 1px solid #3b4261; }
+  .search-bar input[type=text] { flex: 1; background: #1a1b26; color: #c0caf5; border: 1px solid #3b4261; padding: 6px 8px; border-radius: 6px; font-size: 13px; }
+  .search-summary { display: flex; align-items: center; justify-content: space-between; padding: 10px 16px; font-size: 13px; color: #565f89; }
+  .search-hit { padding: 6px 16px; border-top: 1px solid #24283b; }
+  .search-path { display: flex; align-items: center; font-size: 13px; cursor: pointer; }
+  .search-path:hover { color: #7aa2f7; }
+  .search-count { color: #565f89; margin-left: 6px; }
+  .search-line { font-family: 'JetBrains Mono', 'Fira Code', monospace; font-size: 12px; color: #a9b1d6; white-space: pre; overflow: hidden; text-overflow: ellipsis; padding-left: 22px; }
+  .search-line mark { background: #e0af68; color: #1a1b26; border-radius: 2px; }
+  .deps-depth { color: #565f89; margin-left: 6px; font-size: 12px; }
   .toast { position: fixed; bottom: 20px; right: 20px; background: #9ece6a; color: #1a1b26; padding: 12px 20px; border-radius: 8px; font-weight: 600; opacity: 0; transition: opacity 0.3s; pointer-events: none; }
   .toast.show { opacity: 1; }
 </style>
@@ -252,41 +1014,655 @@ const indexHTML = `<!DOCTYPE html>
 <header>
   <h1>Partial Tree Copy</h1>
   <div class="header-right">
+    <label class="toggle-label"><input type="checkbox" id="showIgnored" onchange="loadTree()"> Show ignored</label>
     <span class="selected-count" id="selectedCount">0 files selected</span>
-    <button id="copyBtn" disabled onclick="copySelected()">Copy to Clipboard</button>
+    <span class="token-count" id="tokenCount"></span>
+    <select id="changesSelect" title="Select files changed in git" onchange="selectChanges(this.value)">
+      <option value="">Select changed…</option>
+      <option value="modified">Modified</option>
+      <option value="staged">Staged</option>
+      <option value="untracked">Untracked</option>
+      <option value="changed">All changes</option>
+    </select>
+    <select id="profileSelect" title="Load a saved selection" onchange="loadProfile(this.value)"></select>
+    <button class="secondary" id="saveProfileBtn" onclick="saveProfile()">Save profile</button>
+    <button class="secondary deps-btn" title="Add the Go packages the selected files import" onclick="previewDeps(false)">+ Imports</button>
+    <button class="secondary deps-btn" title="Add the Go files importing the selected packages" onclick="previewDeps(true)">+ Importers</button>
+    <button class="secondary companions-ctl" title="Add the tests of the selected files, or remove them when every pair is complete" onclick="pairCompanions()">Pair tests</button>
+    <label class="toggle-label companions-ctl" title="Select and deselect tests with their files"><input type="checkbox" id="autoCompanions"> Tests follow</label>
+    <select id="formatSelect" title="Output format"></select>
+    <select id="contentSelect" title="Copy full files, diffs against the git ref, or both"></select>
+    <button id="copyBtn" disabled onclick="copySelected()">Copy</button>
   </div>
 </header>
 <div class="container">
   <div class="tree-panel" id="treePanel"></div>
   <div class="preview-panel">
+    <form class="search-bar" id="searchBar" onsubmit="event.preventDefault(); searchContents()">
+      <input type="text" id="searchInput" placeholder="Search file contents…">
+      <label class="toggle-label"><input type="checkbox" id="searchRegex"> Regex</label>
+      <button class="secondary" type="submit">Search</button>
+    </form>
     <div class="preview-header" id="previewHeader">Select a file to preview</div>
     <div class="preview-content" id="previewContent">
       <div class="no-preview">Click a file to view its contents</div>
     </div>
   </div>
 </div>
-<div class="toast" id="toast">Copied to clipboard!</div>
+<div class="toast" id="toast"></div>
 
 <script>
-const state = { tree: null, selected: new Set(), activeFile: null };
+// selected holds file paths; ranges maps a selected path to its [start, end] line ranges, if any
+const state = { tree: null, selected: new Set(), ranges: new Map(), activeFile: null, search: null, lines: null, encoding: null, mark: null, depsDepth: 0 };
 
 async function init() {
-  const res = await fetch('/api/tree');
-  state.tree = await res.json();
+  await loadTree();
+  watchChanges();
+  checkSearch();
+  checkDeps();
+  checkCompanions();
+  loadFormats();
+  const initial = await loadProfiles();
+  if (initial) await loadProfile(initial);
+  const res = await fetch('/api/selection');
+  if (res.ok) addToSelection((await res.json()).paths);
+}
+
+// addToSelection selects the given files and reveals them in the tree, loading
+// the directories on the way. A path may end with line ranges ("app.go:120-180")
+// to select only those lines.
+async function addToSelection(specs) {
+  const paths = specs.map(spec => {
+    const { path, ranges } = parseSpec(spec);
+    state.selected.add(path);
+    if (ranges.length > 0) state.ranges.set(path, ranges);
+    else state.ranges.delete(path);
+    return path;
+  });
+  renderTree();
+  updateCount();
+  for (const path of paths) await expandAncestors(path);
+  renderTree();
+}
+
+// deselect removes a file, and its line ranges, from the selection
+function deselect(path) {
+  state.selected.delete(path);
+  state.ranges.delete(path);
+}
+
+// parseSpec splits "app.go:120-180,200" into the path and its line ranges, as the CLI does
+function parseSpec(spec) {
+  const i = spec.lastIndexOf(':');
+  if (i < 0 || !/^[0-9][0-9,-]*$/.test(spec.slice(i + 1))) return { path: spec, ranges: [] };
+  const ranges = spec.slice(i + 1).split(',').map(part => {
+    const [start, end] = part.split('-').map(Number);
+    return [start, end === undefined ? start : end];
+  });
+  return { path: spec.slice(0, i), ranges: normalizeRanges(ranges) };
+}
+
+// normalizeRanges sorts line ranges and merges the overlapping and adjacent ones
+function normalizeRanges(ranges) {
+  const merged = [];
+  ranges.filter(([start, end]) => start >= 1 && end >= start)
+    .sort((a, b) => a[0] - b[0])
+    .forEach(([start, end]) => {
+      const last = merged[merged.length - 1];
+      if (last && start <= last[1] + 1) last[1] = Math.max(last[1], end);
+      else merged.push([start, end]);
+    });
+  return merged;
+}
+
+function formatRanges(ranges) {
+  return ranges.map(([start, end]) => start === end ? String(start) : start + '-' + end).join(',');
+}
+
+// selectionSpecs returns the selected files, with their line ranges, as sent to the server
+function selectionSpecs() {
+  return Array.from(state.selected).map(path => {
+    const ranges = state.ranges.get(path);
+    return ranges ? path + ':' + formatRanges(ranges) : path;
+  });
+}
+
+async function selectChanges(source) {
+  if (!source) return;
+  document.getElementById('changesSelect').value = '';
+  const res = await fetch('/api/changes?source=' + encodeURIComponent(source));
+  if (!res.ok) {
+    alert('Failed to read git status: ' + await res.text());
+    return;
+  }
+  const paths = (await res.json()).paths;
+  addToSelection(paths);
+  showToast('Selected ' + paths.length + ' changed file' + (paths.length !== 1 ? 's' : ''));
+}
+
+// loadProfiles fills the profile list and returns the profile to start with, if any
+async function loadProfiles() {
+  const select = document.getElementById('profileSelect');
+  const res = await fetch('/api/profiles');
+  if (!res.ok) {
+    select.style.display = 'none';
+    document.getElementById('saveProfileBtn').style.display = 'none';
+    return '';
+  }
+  const data = await res.json();
+  select.innerHTML = '<option value="">Load profile…</option>' +
+    data.profiles.map(name => '<option value="' + escapeHtml(name) + '">' + escapeHtml(name) + '</option>').join('');
+  return data.initial;
+}
+
+async function loadProfile(name) {
+  if (!name) return;
+  document.getElementById('profileSelect').value = '';
+  const res = await fetch('/api/profiles/' + encodeURIComponent(name));
+  if (!res.ok) {
+    alert('Failed to load profile: ' + await res.text());
+    return;
+  }
+  const data = await res.json();
+  state.selected = new Set();
+  state.ranges = new Map();
+  addToSelection(data.paths);
+  if (data.missing.length > 0) {
+    alert(data.missing.length + ' file(s) of profile "' + name + '" no longer exist:\n' + data.missing.join('\n'));
+  } else {
+    showToast('Loaded profile ' + name);
+  }
+}
+
+async function saveProfile() {
+  const name = prompt('Save the selection as profile:');
+  if (!name) return;
+  const res = await fetch('/api/profiles/' + encodeURIComponent(name), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ paths: selectionSpecs() })
+  });
+  if (!res.ok) {
+    alert('Failed to save profile: ' + await res.text());
+    return;
+  }
+  await loadProfiles();
+  showToast('Saved profile ' + name);
+}
+
+// checkSearch hides the search bar when the server has no content search
+async function checkSearch() {
+  const res = await fetch('/api/search');
+  if (res.status === 404) document.getElementById('searchBar').style.display = 'none';
+}
+
+async function searchContents() {
+  const q = document.getElementById('searchInput').value;
+  if (!q) return;
+  const regex = document.getElementById('searchRegex').checked;
+  const res = await fetch('/api/search?q=' + encodeURIComponent(q) + (regex ? '&regex=1' : ''));
+  if (!res.ok) {
+    alert('Search failed: ' + await res.text());
+    return;
+  }
+  state.search = Object.assign({ query: q }, await res.json());
+  state.activeFile = null;
+  state.lines = null;
+  renderTree();
+  renderSearchResults();
+}
+
+// checkDeps hides the dependency buttons when the server cannot resolve imports
+async function checkDeps() {
+  const res = await fetch('/api/deps');
+  if (!res.ok) {
+    document.querySelectorAll('.deps-btn').forEach(btn => btn.style.display = 'none');
+    return;
+  }
+  state.depsDepth = (await res.json()).depth;
+}
+
+// checkCompanions hides the companion controls when the server cannot pair files, and restores the default of following them
+async function checkCompanions() {
+  const res = await fetch('/api/companions');
+  if (!res.ok) {
+    document.querySelectorAll('.companions-ctl').forEach(el => el.style.display = 'none');
+    return;
+  }
+  document.getElementById('autoCompanions').checked = (await res.json()).auto;
+}
+
+// pairCompanions adds the companions, such as tests, of the selected files, or removes them when every pair is complete
+async function pairCompanions() {
+  const res = await fetch('/api/companions', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ paths: selectionSpecs(), pair: true })
+  });
+  if (!res.ok) return;
+  const data = await res.json();
+  data.remove.forEach(deselect);
+  addToSelection(data.add);
+  if (data.add.length > 0) showToast('Added ' + data.add.length + ' companion file' + (data.add.length !== 1 ? 's' : ''));
+  else if (data.remove.length > 0) showToast('Removed ' + data.remove.length + ' companion file' + (data.remove.length !== 1 ? 's' : ''));
+  else showToast('No companion files found');
+}
+
+// followCompanions gives the companions of toggled files their new state when "Tests follow" is checked
+async function followCompanions(paths, selected) {
+  if (!document.getElementById('autoCompanions').checked || paths.length === 0) return;
+  const res = await fetch('/api/companions', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ paths })
+  });
+  if (!res.ok) return;
+  const found = (await res.json()).companions;
+  if (selected) {
+    addToSelection(found.filter(p => !state.selected.has(p)));
+    return;
+  }
+  found.forEach(deselect);
+  updateCount();
+  renderTree();
+}
+
+// previewDeps lists the files following the imports of the selection would add, before adding them
+async function previewDeps(importers, depth) {
+  if (depth === undefined) depth = state.depsDepth;
+  const res = await fetch('/api/deps', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ paths: selectionSpecs(), importers, depth })
+  });
+  if (!res.ok) {
+    alert('Cannot follow imports: ' + await res.text());
+    return;
+  }
+  const data = await res.json();
+  state.activeFile = null;
+  state.lines = null;
+  renderTree();
+  renderDeps(importers, data.depth, data.files);
+}
+
+// renderDeps shows the files previewDeps found, with the depth to follow and a button to add them
+function renderDeps(importers, depth, files) {
+  const n = files.length;
+  document.getElementById('previewHeader').textContent = importers ? 'Files importing the selected packages' : 'Packages imported by the selection';
+  const content = document.getElementById('previewContent');
+  content.innerHTML = '';
+
+  const summary = document.createElement('div');
+  summary.className = 'search-summary';
+  const label = document.createElement('span');
+  label.textContent = n + ' file' + (n !== 1 ? 's' : '') + ' to add, up to ';
+  const levels = document.createElement('select');
+  [1, 2, 3, 4, 5, 0].forEach(level => {
+    const opt = document.createElement('option');
+    opt.value = level;
+    opt.textContent = level === 0 ? 'all levels' : level + (level === 1 ? ' level' : ' levels');
+    opt.selected = level === depth;
+    levels.appendChild(opt);
+  });
+  if (levels.selectedIndex < 0 || Number(levels.value) !== depth) {
+    const opt = document.createElement('option');
+    opt.value = depth;
+    opt.textContent = depth + ' levels';
+    opt.selected = true;
+    levels.appendChild(opt);
+  }
+  levels.onchange = () => previewDeps(importers, Number(levels.value));
+  label.appendChild(levels);
+  summary.appendChild(label);
+
+  const add = document.createElement('button');
+  add.textContent = 'Add ' + n + ' file' + (n !== 1 ? 's' : '');
+  add.disabled = n === 0;
+  add.onclick = () => {
+    addToSelection(files.map(f => f.path));
+    content.innerHTML = '<div class="no-preview">Added ' + n + ' file' + (n !== 1 ? 's' : '') + '</div>';
+    showToast('Added ' + n + ' file' + (n !== 1 ? 's' : ''));
+  };
+  summary.appendChild(add);
+  content.appendChild(summary);
+
+  files.forEach(file => {
+    const hit = document.createElement('div');
+    hit.className = 'search-hit';
+    const path = document.createElement('div');
+    path.className = 'search-path';
+    path.textContent = file.path;
+    const level = document.createElement('span');
+    level.className = 'deps-depth';
+    level.textContent = file.package + ' · level ' + file.depth;
+    path.appendChild(level);
+    path.onclick = () => previewFile(file.path);
+    hit.appendChild(path);
+    content.appendChild(hit);
+  });
+}
+
+// renderSearchResults lists the matching files with previews in the preview panel
+function renderSearchResults() {
+  const data = state.search;
+  const n = data.files.length;
+  document.getElementById('previewHeader').textContent = 'Search results for "' + data.query + '"';
+  const content = document.getElementById('previewContent');
+  content.innerHTML = '';
+
+  const summary = document.createElement('div');
+  summary.className = 'search-summary';
+  summary.innerHTML = '<span>' + n + (data.truncated ? '+' : '') + ' matching file' + (n !== 1 ? 's' : '') + '</span>';
+  if (n > 0) {
+    const addAll = document.createElement('button');
+    addAll.className = 'secondary';
+    addAll.textContent = 'Select all matches';
+    addAll.onclick = () => {
+      addToSelection(data.files.map(f => f.path));
+      renderSearchResults();
+      showToast('Selected ' + n + ' file' + (n !== 1 ? 's' : ''));
+    };
+    summary.appendChild(addAll);
+  }
+  content.appendChild(summary);
+
+  data.files.forEach(file => {
+    const hit = document.createElement('div');
+    hit.className = 'search-hit';
+
+    const path = document.createElement('div');
+    path.className = 'search-path';
+    const check = document.createElement('input');
+    check.type = 'checkbox';
+    check.className = 'tree-check';
+    check.checked = state.selected.has(file.path);
+    check.onclick = (e) => {
+      e.stopPropagation();
+      if (state.selected.has(file.path)) {
+        deselect(file.path);
+        renderTree();
+        updateCount();
+      } else {
+        addToSelection([file.path]);
+      }
+    };
+    path.appendChild(check);
+    path.appendChild(document.createTextNode(file.path));
+    const count = document.createElement('span');
+    count.className = 'search-count';
+    count.textContent = '(' + file.count + ')';
+    path.appendChild(count);
+    path.onclick = () => previewFile(file.path);
+    hit.appendChild(path);
+
+    file.matches.forEach(match => {
+      const line = document.createElement('div');
+      line.className = 'search-line';
+      line.innerHTML = '<span class="line-num">' + match.line + '</span>' + highlightMatch(match);
+      hit.appendChild(line);
+    });
+    content.appendChild(hit);
+  });
+}
+
+// highlightMatch renders a preview line with the match marked; offsets are in UTF-8 bytes
+function highlightMatch(match) {
+  const bytes = new TextEncoder().encode(match.text);
+  const decode = (from, to) => escapeHtml(new TextDecoder().decode(bytes.slice(from, to)));
+  return decode(0, match.start) + '<mark>' + decode(match.start, match.end) + '</mark>' + decode(match.end, bytes.length);
+}
+
+// expandAncestors expands every directory on the way to path, loading them,
+// and the pages of its own directory, until path itself is in the tree
+async function expandAncestors(path) {
+  const parts = path.split('/');
+  for (let i = 1; i < parts.length; i++) {
+    const dir = await findNode(parts.slice(0, i).join('/'));
+    if (!dir) return;
+    await expandDirectory(dir);
+  }
+  await findNode(path);
+}
+
+// expandDirectory expands a directory, loading its first page of entries if needed
+async function expandDirectory(dir) {
+  dir._expanded = true;
+  if (!dir._loaded) await loadChildren(dir);
+}
+
+// findNode returns the node at path, loading the directories, and the pages of
+// them, on the way; null when there is no such entry
+async function findNode(path) {
+  let node = state.tree;
+  const parts = path === '.' ? [] : path.split('/');
+  for (let i = 0; node && i < parts.length; i++) {
+    if (!node.isDir) return null;
+    const childPath = parts.slice(0, i + 1).join('/');
+    if (!node._loaded) await loadChildren(node);
+    let child = node.children.find(c => c.path === childPath);
+    while (!child && node._next) {
+      await loadChildren(node);
+      child = node.children.find(c => c.path === childPath);
+    }
+    node = child;
+  }
+  return node || null;
+}
+
+// loadChildren fetches the next page of the entries of a directory; calls made
+// while a page is loading wait for that page instead of fetching it again
+function loadChildren(node) {
+  if (!node._loading) {
+    const params = new URLSearchParams({ path: node.path, offset: node.children.length });
+    if (document.getElementById('showIgnored').checked) params.set('ignored', '1');
+    node._loading = fetch('/api/children?' + params)
+      .then(res => res.ok ? res.json() : null)
+      .then(page => {
+        if (page) {
+          node.children = node.children.concat(page.children.map(child => Object.assign(child, { children: [] })));
+          node.childCount = page.total;
+        }
+        node._next = page ? page.next || 0 : 0;
+        node._loaded = true;
+        node._loading = null;
+      });
+  }
+  return node._loading;
+}
+
+// loadTree loads the top level of the tree, then the directories and Go files
+// that were expanded before, so a reload keeps the tree as it was
+async function loadTree() {
+  const expanded = [];
+  collectExpanded(state.tree, expanded);
+  state.tree = { name: '.', path: '.', isDir: true, children: [] };
+  await loadChildren(state.tree);
+  renderTree();
+  for (const path of expanded) {
+    const node = await findNode(path);
+    if (!node) continue;
+    if (node.isDir) {
+      await expandDirectory(node);
+    } else {
+      node._expanded = true;
+      loadSymbols(node);
+    }
+  }
+  renderTree();
+}
+
+function collectExpanded(node, expanded) {
+  if (!node) return;
+  if (node._expanded) expanded.push(node.path);
+  (node.children || []).forEach(child => collectExpanded(child, expanded));
+}
+
+// watchChanges refreshes the tree as files change on disk, when the server watches them
+function watchChanges() {
+  if (typeof EventSource === 'undefined') return;
+  const events = new EventSource('/api/events');
+  let applying = Promise.resolve();
+  events.onmessage = (e) => {
+    const change = JSON.parse(e.data);
+    applying = applying.then(() => applyChanges(change));
+  };
+}
+
+// applyChanges updates the tree after files changed on disk: the loaded
+// directories whose entries changed are read again, with their parents for the
+// entry counts, keeping expanded directories and the selection of files that
+// still exist. Removed files leave the selection, and the preview, declarations,
+// and token counts of written files are read again, with their selected
+// declarations following their new lines. When changes were lost the
+// whole tree is reloaded.
+async function applyChanges(change) {
+  let selectionChanged = false;
+  if (change.lost) {
+    await loadTree();
+  } else {
+    const dirs = new Set();
+    (change.dirs || []).forEach(dir => {
+      dirs.add(dir);
+      if (dir !== '.') dirs.add(dir.includes('/') ? dir.slice(0, dir.lastIndexOf('/')) : '.');
+    });
+    // Parents first, so a directory removed with its parent is not read again
+    const depth = path => path === '.' ? 0 : path.split('/').length;
+    for (const dir of [...dirs].sort((a, b) => depth(a) - depth(b))) {
+      const node = loadedNode(dir);
+      if (!node || !node.isDir || !node._loaded) continue;
+      for (const gone of await refreshChildren(node)) {
+        for (const path of [...state.selected]) {
+          if (path === gone || path.startsWith(gone + '/')) {
+            state.selected.delete(path);
+            state.ranges.delete(path);
+            selectionChanged = true;
+          }
+        }
+      }
+    }
+  }
+  clearFileLists(state.tree);
+
+  const written = change.written || [];
+  const gone = [];
+  for (const path of written) {
+    const node = loadedNode(path);
+    if (!node || node.isDir || !node._expanded) continue;
+    const old = node._symbols;
+    await loadSymbols(node);
+    remapSymbols(node, old).forEach(name => gone.push(path + ': ' + name));
+  }
+  if (gone.length > 0) {
+    showToast('Removed from the selection, as they are no longer declared: ' + gone.join(', '));
+    selectionChanged = true;
+  }
+  if (state.activeFile && written.includes(state.activeFile)) reloadPreview();
   renderTree();
+  if (selectionChanged) updateCount();
+  else if (written.some(path => state.selected.has(path))) updateTokens();
+}
+
+// refreshChildren reads the loaded entries of a directory again, keeping the
+// nodes of those that still exist with their children and expansion, and
+// returns the paths of the entries that are gone
+async function refreshChildren(node) {
+  if (node._loading) await node._loading;
+  const wanted = Math.max(node.children.length, 1);
+  let children = [];
+  let page = null;
+  do {
+    const params = new URLSearchParams({ path: node.path, offset: children.length, limit: Math.max(wanted - children.length, 1) });
+    if (document.getElementById('showIgnored').checked) params.set('ignored', '1');
+    const res = await fetch('/api/children?' + params);
+    if (!res.ok) return []; // The directory is gone; refreshing its parent removes it
+    page = await res.json();
+    children = children.concat(page.children);
+  } while (page.next && children.length < wanted);
+
+  const old = new Map(node.children.map(child => [child.path, child]));
+  node.children = children.map(child => {
+    const existing = old.get(child.path);
+    if (existing && existing.isDir === child.isDir) {
+      old.delete(child.path);
+      return Object.assign(existing, child);
+    }
+    return Object.assign(child, { children: [] });
+  });
+  node.childCount = page.total;
+  node._next = page.next || 0;
+  // Entries past the pages read again may still exist, so only a complete listing removes them
+  return node._next ? [] : [...old.keys()];
+}
+
+// loadedNode returns the node at path if it is in the loaded tree, without loading anything
+function loadedNode(path) {
+  let node = state.tree;
+  if (path === '.') return node;
+  const parts = path.split('/');
+  for (let i = 0; node && i < parts.length; i++) {
+    const childPath = parts.slice(0, i + 1).join('/');
+    node = (node.children || []).find(c => c.path === childPath);
+  }
+  return node || null;
+}
+
+// clearFileLists drops the file lists cached for toggling directories, as they may have changed
+function clearFileLists(node) {
+  if (!node) return;
+  delete node._files;
+  (node.children || []).forEach(clearFileLists);
+}
+
+async function loadFormats() {
+  const res = await fetch('/api/formats');
+  const data = await res.json();
+  const select = document.getElementById('formatSelect');
+  data.formats.forEach(name => {
+    const opt = document.createElement('option');
+    opt.value = name;
+    opt.textContent = name;
+    opt.selected = name === data.default;
+    select.appendChild(opt);
+  });
+  const contentSelect = document.getElementById('contentSelect');
+  data.contents.forEach(name => {
+    const opt = document.createElement('option');
+    opt.value = name;
+    opt.textContent = name;
+    opt.selected = name === data.defaultContent;
+    contentSelect.appendChild(opt);
+  });
+  contentSelect.style.display = data.contents.length > 1 ? '' : 'none';
 }
 
 function renderTree() {
   const panel = document.getElementById('treePanel');
   panel.innerHTML = '';
-  if (state.tree && state.tree.children) {
+  if (state.tree) {
     state.tree.children.forEach(child => renderNode(child, panel, 0));
+    renderMore(state.tree, panel, 0);
   }
 }
 
+// renderMore offers the next page of a directory with more entries than were loaded
+function renderMore(dir, parent, depth) {
+  if (!dir._next) return;
+  const item = document.createElement('div');
+  item.className = 'tree-item tree-more';
+  item.style.paddingLeft = (8 + depth * 18 + 18) + 'px';
+  item.textContent = 'Load more… (' + dir.children.length + ' of ' + dir.childCount + ')';
+  item.onclick = async (e) => {
+    e.stopPropagation();
+    await loadChildren(dir);
+    renderTree();
+  };
+  parent.appendChild(item);
+}
+
 function renderNode(node, parent, depth) {
   const item = document.createElement('div');
-  item.className = 'tree-item' + (state.activeFile === node.path ? ' active' : '');
+  item.className = 'tree-item' + (state.activeFile === node.path ? ' active' : '') + (node.ignored ? ' ignored' : '');
   item.style.paddingLeft = (8 + depth * 18) + 'px';
 
   if (node.isDir) {
@@ -295,6 +1671,18 @@ function renderNode(node, parent, depth) {
     toggle.textContent = node._expanded ? '▼' : '▶';
     item.appendChild(toggle);
 
+    const check = document.createElement('input');
+    check.type = 'checkbox';
+    check.className = 'tree-check';
+    const dirState = selectionState(node);
+    check.checked = dirState === 'all';
+    check.indeterminate = dirState === 'some';
+    check.onclick = (e) => {
+      e.stopPropagation();
+      toggleDirectory(node);
+    };
+    item.appendChild(check);
+
     const icon = document.createElement('span');
     icon.className = 'tree-icon';
     icon.textContent = node._expanded ? '📂' : '📁';
@@ -305,34 +1693,62 @@ function renderNode(node, parent, depth) {
     name.textContent = node.name;
     item.appendChild(name);
 
-    item.onclick = (e) => {
+    const count = document.createElement('span');
+    count.className = 'child-count';
+    count.textContent = node.childCount || 0;
+    count.title = (node.childCount || 0) + ' entries';
+    item.appendChild(count);
+
+    if (node.status) {
+      const badge = document.createElement('span');
+      badge.className = 'git-badge git-' + ({ M: 'modified', A: 'added', '?': 'untracked' }[node.status] || 'modified');
+      badge.textContent = node.status;
+      item.appendChild(badge);
+    }
+
+    item.onclick = async (e) => {
       e.stopPropagation();
-      node._expanded = !node._expanded;
+      if (node._expanded) node._expanded = false;
+      else await expandDirectory(node);
       renderTree();
     };
   } else {
+    // Go files expand into their top-level declarations
     const toggle = document.createElement('span');
     toggle.className = 'tree-toggle';
+    if (node.name.endsWith('.go')) {
+      toggle.textContent = node._expanded ? '▼' : '▶';
+      toggle.title = 'Show declarations';
+      toggle.onclick = (e) => {
+        e.stopPropagation();
+        toggleSymbols(node);
+      };
+    }
     item.appendChild(toggle);
 
     const check = document.createElement('input');
     check.type = 'checkbox';
     check.className = 'tree-check';
     check.checked = state.selected.has(node.path);
+    check.indeterminate = state.ranges.has(node.path);
+    check.title = state.ranges.has(node.path) ? 'Lines ' + formatRanges(state.ranges.get(node.path)) : '';
     check.onclick = (e) => {
       e.stopPropagation();
       if (state.selected.has(node.path)) {
-        state.selected.delete(node.path);
+        deselect(node.path);
       } else {
         state.selected.add(node.path);
       }
       updateCount();
+      renderTree();
+      if (state.activeFile === node.path) renderPreview();
+      followCompanions([node.path], state.selected.has(node.path));
     };
     item.appendChild(check);
 
     const icon = document.createElement('span');
     icon.className = 'tree-icon';
-    icon.textContent = getFileIcon(node.name);
+    icon.textContent = getFileIcon(node);
     item.appendChild(icon);
 
     const name = document.createElement('span');
@@ -340,6 +1756,16 @@ function renderNode(node, parent, depth) {
     name.textContent = node.name;
     item.appendChild(name);
 
+    if (node.status) {
+      const badge = document.createElement('span');
+      badge.className = 'git-badge git-' + ({ M: 'modified', A: 'added', '?': 'untracked' }[node.status] || 'modified');
+      badge.textContent = node.status;
+      item.appendChild(badge);
+    }
+
+    const classBadge = fileClassBadge(node);
+    if (classBadge) item.appendChild(classBadge);
+
     item.onclick = (e) => {
       if (e.target.type === 'checkbox') return;
       previewFile(node.path);
@@ -348,35 +1774,344 @@ function renderNode(node, parent, depth) {
 
   parent.appendChild(item);
 
-  if (node.isDir && node._expanded && node.children) {
+  if (node.isDir && node._expanded) {
     node.children.forEach(child => renderNode(child, parent, depth + 1));
+    renderMore(node, parent, depth + 1);
+  }
+  if (!node.isDir && node._expanded && node._symbols) {
+    node._symbols.forEach(sym => renderSymbol(node, sym, parent, depth + 1));
+  }
+}
+
+// renderSymbol renders a declaration of a Go file; checking it selects its lines
+function renderSymbol(file, sym, parent, depth) {
+  const item = document.createElement('div');
+  item.className = 'tree-item';
+  item.style.paddingLeft = (8 + depth * 18) + 'px';
+
+  const toggle = document.createElement('span');
+  toggle.className = 'tree-toggle';
+  item.appendChild(toggle);
+
+  const check = document.createElement('input');
+  check.type = 'checkbox';
+  check.className = 'tree-check';
+  const symState = symbolState(file.path, sym);
+  check.checked = symState === 'all';
+  check.indeterminate = symState === 'some';
+  check.onclick = (e) => {
+    e.stopPropagation();
+    toggleSymbol(file, sym);
+  };
+  item.appendChild(check);
+
+  const name = document.createElement('span');
+  name.className = 'tree-symbol';
+  name.textContent = sym.name;
+  name.title = 'Lines ' + sym.start + '-' + sym.end;
+  item.appendChild(name);
+
+  item.onclick = (e) => {
+    if (e.target.type === 'checkbox') return;
+    previewFile(file.path, sym.start);
+  };
+  parent.appendChild(item);
+}
+
+// toggleSymbols expands a Go file into its declarations, or collapses it
+async function toggleSymbols(node) {
+  node._expanded = !node._expanded;
+  if (node._expanded) await loadSymbols(node);
+  renderTree();
+}
+
+// loadSymbols fetches the declarations of a Go file; a file that does not parse stays collapsed
+async function loadSymbols(node) {
+  const res = await fetch('/api/symbols?path=' + encodeURIComponent(node.path));
+  if (!res.ok) {
+    node._expanded = false;
+    showToast('No declarations: ' + await res.text());
+    return;
+  }
+  const body = await res.json();
+  node._symbols = body.symbols;
+  node._lines = body.lines;
+  if (node._symbols.length === 0) node._expanded = false;
+  renderTree();
+}
+
+// symbolState returns 'none', 'some', or 'all' for the selected lines of a declaration
+function symbolState(path, sym) {
+  if (!state.selected.has(path)) return 'none';
+  const ranges = state.ranges.get(path);
+  if (!ranges) return 'all';
+  let covered = 0;
+  ranges.forEach(([start, end]) => { covered += Math.max(Math.min(end, sym.end) - Math.max(start, sym.start) + 1, 0); });
+  if (covered === 0) return 'none';
+  return covered === sym.end - sym.start + 1 ? 'all' : 'some';
+}
+
+// toggleSymbol adds the lines of a declaration to the selection, or removes them when all are selected.
+// Removing a declaration from a file selected as a whole keeps every other line of the file.
+function toggleSymbol(file, sym) {
+  const path = file.path;
+  if (symbolState(path, sym) !== 'all') {
+    const ranges = state.selected.has(path) ? state.ranges.get(path) : [];
+    state.selected.add(path);
+    state.ranges.set(path, normalizeRanges(ranges.concat([[sym.start, sym.end]])));
+  } else {
+    const last = Math.max(file._lines || 0, file._symbols[file._symbols.length - 1].end);
+    const rest = subtractRange(state.ranges.get(path) || [[1, last]], [sym.start, sym.end]);
+    if (rest.length === 0) deselect(path);
+    else state.ranges.set(path, rest);
+  }
+  renderTree();
+  updateCount();
+  if (state.activeFile === path) renderPreview();
+}
+
+// remapSymbols moves the selected lines of a file whose declarations were read
+// again from the declarations in old to those now listed, matching them by name,
+// which starts with their kind, and by order among those with the same name.
+// Lines outside wholly selected declarations are kept as they are. It returns
+// the names of the selected declarations that are gone, whose lines are dropped.
+function remapSymbols(file, old) {
+  const path = file.path;
+  if (!old || !state.selected.has(path) || !state.ranges.get(path)) return [];
+  const current = new Map();
+  (file._symbols || []).forEach(sym => {
+    if (!current.has(sym.name)) current.set(sym.name, []);
+    current.get(sym.name).push(sym);
+  });
+
+  const seen = new Map();
+  const moved = [], dropped = [];
+  let kept = state.ranges.get(path);
+  old.forEach(sym => {
+    const nth = seen.get(sym.name) || 0;
+    seen.set(sym.name, nth + 1);
+    if (symbolState(path, sym) !== 'all') return;
+    kept = subtractRange(kept, [sym.start, sym.end]);
+    const now = (current.get(sym.name) || [])[nth];
+    if (now) moved.push([now.start, now.end]);
+    else dropped.push(sym.name);
+  });
+  const ranges = normalizeRanges(kept.concat(moved));
+  if (ranges.length === 0) deselect(path);
+  else state.ranges.set(path, ranges);
+  return dropped;
+}
+
+// subtractRange returns ranges without the lines of cut, splitting a range that contains it
+function subtractRange(ranges, cut) {
+  const rest = [];
+  ranges.forEach(([start, end]) => {
+    if (end < cut[0] || start > cut[1]) {
+      rest.push([start, end]);
+      return;
+    }
+    if (start < cut[0]) rest.push([start, cut[0] - 1]);
+    if (end > cut[1]) rest.push([cut[1] + 1, end]);
+  });
+  return rest;
+}
+
+// descendantFiles lists the paths of all files under a directory node, or
+// returns null when some directory under it is not completely loaded
+function descendantFiles(node, out) {
+  if (!node._loaded || node._next) return null;
+  for (const child of node.children) {
+    if (!child.isDir) out.push(child.path);
+    else if (!descendantFiles(child, out)) return null;
   }
+  return out;
 }
 
-function getFileIcon(name) {
-  const ext = name.split('.').pop().toLowerCase();
+// selectionState returns 'none', 'some', or 'all' for the files under a
+// directory. Until the files of a directory are known, it is never 'all'.
+function selectionState(node) {
+  const files = node._files || descendantFiles(node, []);
+  if (!files) {
+    const prefix = node.path === '.' ? '' : node.path + '/';
+    return Array.from(state.selected).some(p => p.startsWith(prefix)) ? 'some' : 'none';
+  }
+  const selected = files.filter(p => state.selected.has(p)).length;
+  if (selected === 0) return 'none';
+  return selected === files.length ? 'all' : 'some';
+}
+
+// directoryFiles returns the files that toggling a directory selects, asking
+// the server for them unless the whole directory is loaded
+async function directoryFiles(node) {
+  const loaded = descendantFiles(node, []);
+  if (loaded) return loaded;
+  if (!node._files) {
+    const params = new URLSearchParams({ path: node.path });
+    if (document.getElementById('showIgnored').checked) params.set('ignored', '1');
+    const res = await fetch('/api/files?' + params);
+    if (!res.ok) return [];
+    node._files = (await res.json()).paths;
+  }
+  return node._files;
+}
+
+// toggleDirectory selects every file under a directory, or clears them all when fully selected
+async function toggleDirectory(node) {
+  const files = await directoryFiles(node);
+  if (selectionState(node) === 'all') {
+    files.forEach(deselect);
+    // Also clear selected files under the directory that are not listed (e.g. hidden ignored files)
+    const prefix = node.path === '.' ? '' : node.path + '/';
+    Array.from(state.selected).forEach(p => { if (p.startsWith(prefix)) deselect(p); });
+  } else {
+    files.forEach(p => state.selected.add(p));
+  }
+  updateCount();
+  renderTree();
+  followCompanions(files, state.selected.has(files[0]));
+}
+
+function getFileIcon(node) {
+  if (node.kind === 'binary') return '💾';
+  if (node.kind === 'oversized') return '🗄️';
+  const ext = node.name.split('.').pop().toLowerCase();
   const icons = { go: '🔵', js: '🟡', ts: '🔷', py: '🐍', md: '📝', json: '📋', yaml: '⚙️', yml: '⚙️', html: '🌐', css: '🎨', sh: '🐚', mod: '📦', sum: '🔒' };
   return icons[ext] || '📄';
 }
 
-async function previewFile(path) {
+// fileClassBadge marks files copied as a stub, or transcoded to UTF-8, and explains why on hover
+function fileClassBadge(node) {
+  if (!node.kind && !node.encoding) return null;
+  const badge = document.createElement('span');
+  badge.className = 'class-badge' + (node.kind ? ' class-' + node.kind : '');
+  if (node.kind === 'binary') {
+    badge.textContent = 'binary';
+    badge.title = 'Binary file: copied as a stub with its size and hash';
+  } else if (node.kind === 'oversized') {
+    badge.textContent = formatSize(node.size);
+    badge.title = 'Over the size limit: copied as a stub with its size and hash';
+  } else {
+    badge.textContent = node.encoding;
+    badge.title = 'Copied converted from ' + node.encoding + ' to UTF-8';
+  }
+  return badge;
+}
+
+// formatSize renders a size in bytes like the stubs do, e.g. "1.5 MiB"
+function formatSize(size) {
+  const units = [['GiB', 1 << 30], ['MiB', 1 << 20], ['KiB', 1 << 10]];
+  for (const [unit, factor] of units) {
+    if (size >= factor) return (size / factor).toFixed(1) + ' ' + unit;
+  }
+  return size + ' B';
+}
+
+// previewFile shows a file, scrolled to line when given
+async function previewFile(path, line) {
   state.activeFile = path;
+  state.lines = null;
+  state.mark = null;
   renderTree();
   document.getElementById('previewHeader').textContent = path;
   try {
     const res = await fetch('/api/file?path=' + encodeURIComponent(path));
     if (!res.ok) throw new Error(await res.text());
-    const text = await res.text();
-    const lines = text.split('\n');
-    const html = lines.map((line, i) =>
-      '<span class="line-num">' + (i + 1) + '</span>' + escapeHtml(line)
-    ).join('\n');
-    document.getElementById('previewContent').innerHTML = '<pre>' + html + '</pre>';
+    const kind = res.headers.get('X-File-Kind');
+    if (kind && kind !== 'text') {
+      // Binary and oversized files are not shown; the stub is what a copy contains
+      document.getElementById('previewContent').innerHTML = '<div class="no-preview">' + escapeHtml(await res.text()) + '</div>';
+      return;
+    }
+    const encoding = res.headers.get('X-File-Encoding');
+    state.encoding = encoding && encoding !== 'utf-8' ? encoding : null;
+    state.lines = (await res.text()).split('\n');
+    const content = document.getElementById('previewContent');
+    content.scrollTop = 0;
+    renderPreview();
+    const target = line && content.querySelector('[data-line="' + line + '"]');
+    if (target) content.scrollTop = target.offsetTop - content.offsetTop - 12;
   } catch (e) {
     document.getElementById('previewContent').innerHTML = '<div class="no-preview">Error: ' + escapeHtml(e.message) + '</div>';
   }
 }
 
+// reloadPreview reads the previewed file again after it changed on disk, keeping the scroll position
+async function reloadPreview() {
+  const path = state.activeFile;
+  const res = await fetch('/api/file?path=' + encodeURIComponent(path));
+  const kind = res.headers.get('X-File-Kind');
+  if (!res.ok || (kind && kind !== 'text') || !state.lines) return previewFile(path);
+  const text = await res.text();
+  if (state.activeFile !== path) return;
+  const encoding = res.headers.get('X-File-Encoding');
+  state.encoding = encoding && encoding !== 'utf-8' ? encoding : null;
+  state.lines = text.split('\n');
+  renderPreview();
+}
+
+// renderPreview shows the previewed file with clickable line numbers; selected lines are highlighted
+function renderPreview() {
+  if (!state.lines) return;
+  const path = state.activeFile;
+  const ranges = state.ranges.get(path);
+
+  const header = document.getElementById('previewHeader');
+  header.textContent = path + (state.encoding ? ' [' + state.encoding + ']' : '') + (ranges ? ' (lines ' + formatRanges(ranges) + ')' : '') +
+    (state.mark ? ' — click the last line of the range' : ' — click two line numbers to select a range');
+  if (ranges) {
+    const whole = document.createElement('button');
+    whole.className = 'secondary';
+    whole.textContent = 'Select whole file';
+    whole.onclick = () => {
+      state.ranges.delete(path);
+      renderTree();
+      updateCount();
+      renderPreview();
+    };
+    header.appendChild(whole);
+  }
+
+  const inRanges = n => ranges && ranges.some(([start, end]) => n >= start && n <= end);
+  const html = state.lines.map((line, i) => {
+    const n = i + 1;
+    const cls = 'line-num' + (n === state.mark ? ' marking' : inRanges(n) ? ' picked' : '');
+    return '<span class="' + cls + '" data-line="' + n + '">' + n + '</span>' + escapeHtml(line);
+  }).join('\n');
+  const content = document.getElementById('previewContent');
+  const scrollTop = content.scrollTop;
+  content.innerHTML = '<pre>' + html + '</pre>';
+  content.scrollTop = scrollTop;
+  content.firstChild.onclick = (e) => {
+    if (e.target.dataset.line) clickLine(Number(e.target.dataset.line));
+  };
+}
+
+// clickLine marks the first line of a range, or selects the range from the mark to line.
+// A file selected as a whole is narrowed to the range.
+function clickLine(line) {
+  if (state.mark === null) {
+    state.mark = line;
+    renderPreview();
+    return;
+  }
+  const range = [Math.min(state.mark, line), Math.max(state.mark, line)];
+  const path = state.activeFile;
+  state.mark = null;
+  state.selected.add(path);
+  state.ranges.set(path, normalizeRanges((state.ranges.get(path) || []).concat([range])));
+  renderTree();
+  updateCount();
+  renderPreview();
+  showToast('Selected lines ' + formatRanges([range]));
+}
+
+document.addEventListener('keydown', (e) => {
+  if (e.key === 'Escape' && state.mark !== null) {
+    state.mark = null;
+    renderPreview();
+  }
+});
+
 function escapeHtml(s) {
   return s.replace(/&/g,'&amp;').replace(/</g,'&lt;').replace(/>/g,'&gt;').replace(/"/g,'&quot;');
 }
@@ -385,25 +2120,61 @@ function updateCount() {
   const n = state.selected.size;
   document.getElementById('selectedCount').textContent = n + ' file' + (n !== 1 ? 's' : '') + ' selected';
   document.getElementById('copyBtn').disabled = n === 0;
+  updateTokens();
+}
+
+async function updateTokens() {
+  const el = document.getElementById('tokenCount');
+  const paths = selectionSpecs();
+  if (paths.length === 0) {
+    el.textContent = '';
+    el.className = 'token-count';
+    return;
+  }
+  const res = await fetch('/api/tokens', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ paths })
+  });
+  if (!res.ok) return;
+  const data = await res.json();
+  el.textContent = '~' + data.total + ' tokens' + (data.max > 0 ? ' / ' + data.max : '') + (data.exceeded ? ' (over budget)' : '');
+  el.title = data.files.map(f => f.path + ': ~' + f.tokens).join('\n');
+  el.className = 'token-count' + (data.exceeded ? ' over' : '');
 }
 
 async function copySelected() {
-  const paths = Array.from(state.selected);
+  const paths = selectionSpecs();
   try {
     const res = await fetch('/api/copy', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ paths })
+      body: JSON.stringify({
+        paths,
+        format: document.getElementById('formatSelect').value,
+        content: document.getElementById('contentSelect').value
+      })
     });
     if (!res.ok) throw new Error(await res.text());
-    showToast();
+    const data = await res.json();
+    if (data.warning) alert('Warning: ' + data.warning);
+    if (data.sensitive.length > 0) alert('Warning: copied files named like credentials: ' + data.sensitive.join(', '));
+    if (data.skipped.length > 0) {
+      alert('Skipped ' + data.skipped.length + ' of ' + (data.copied.length + data.skipped.length) + ' files:\n' +
+        data.skipped.map(f => f.path + ': ' + f.reason).join('\n'));
+    }
+    const notes = [data.copied.length + (data.copied.length === 1 ? ' file' : ' files'), formatSize(data.bytes), '~' + data.tokens + ' tokens'];
+    if (data.redacted > 0) notes.push(data.redacted + (data.redacted === 1 ? ' secret' : ' secrets') + ' redacted');
+    if (data.stubs.length > 0) notes.push(data.stubs.length + ' binary or oversized files as stubs');
+    showToast('Copied to ' + data.sink + '! (' + notes.join(', ') + ')');
   } catch (e) {
     alert('Copy failed: ' + e.message);
   }
 }
 
-function showToast() {
+function showToast(message) {
   const toast = document.getElementById('toast');
+  toast.textContent = message;
   toast.classList.add('show');
   setTimeout(() => toast.classList.remove('show'), 2000);
 }
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/navigator"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+)
+
+// workspace is the file tree seen by one request, built and selected through
+// the same navigator and selector as the terminal UI. Directories are read as
+// they are reached, so a request only reads the part of the tree it needs.
+type workspace struct {
+	navigator *navigator.FileNavigator
+	selector  *selector.FileSelector
+	root      *entities.FileNode
+}
+
+// newWorkspace reads the root of the tree; ignored entries are hidden unless showIgnored is set
+func (h *Handler) newWorkspace(showIgnored bool) (*workspace, error) {
+	nav := navigator.NewFileNavigator(h.repo)
+	nav.SetIgnoreMatcher(h.opts.Ignore)
+	nav.SetShowIgnored(showIgnored)
+	root, err := nav.BuildRootNode()
+	if err != nil {
+		return nil, err
+	}
+
+	sel := selector.NewFileSelector()
+	sel.SetTreeLoader(nav)
+	if h.opts.Changes != nil {
+		sel.SetDeletionSource(h.opts.Changes)
+	}
+	return &workspace{navigator: nav, selector: sel, root: root}, nil
+}
+
+// openWorkspace is newWorkspace, answering the request with an error when the root cannot be read
+func (h *Handler) openWorkspace(w http.ResponseWriter, showIgnored bool) (*workspace, bool) {
+	ws, err := h.newWorkspace(showIgnored)
+	if err != nil {
+		http.Error(w, "failed to read the directory tree: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return ws, true
+}
+
+// visibleChildren loads the children of a directory and returns those that are not hidden
+func (ws *workspace) visibleChildren(node *entities.FileNode) []*entities.FileNode {
+	if !node.IsDir {
+		return nil
+	}
+	ws.navigator.LoadChildren(node)
+	var visible []*entities.FileNode
+	for _, child := range node.Children {
+		if !ws.navigator.IsHidden(child) {
+			visible = append(visible, child)
+		}
+	}
+	return visible
+}
+
+// file returns the file at a cleaned root-relative path, or nil when there is none
+func (ws *workspace) file(relPath string) *entities.FileNode {
+	node := ws.selector.FindNode(ws.root, relPath)
+	if node == nil || node.IsDir {
+		return nil
+	}
+	return node
+}
+
+// isSelectable reports whether a root-relative, slash-separated path names a file that is not ignored
+func (ws *workspace) isSelectable(relPath string) bool {
+	cleaned, ok := cleanPath(relPath)
+	if !ok {
+		return false
+	}
+	node := ws.file(cleaned)
+	return node != nil && !node.Ignored
+}
+
+// resolve resolves a root-relative path that may end with line ranges
+// ("app.go:120-180") into the cleaned path, its ranges, and the file, which is
+// nil when it does not exist. ok is false for paths that escape the root. An
+// existing file whose name looks like a range suffix is matched by its full name.
+func (ws *workspace) resolve(spec string) (string, []entities.LineRange, *entities.FileNode, bool) {
+	cleaned, ok := cleanPath(spec)
+	if !ok {
+		return "", nil, nil, false
+	}
+	if node, ranges := ws.selector.FindSpec(ws.root, cleaned); node != nil && !node.IsDir {
+		return ws.relative(node), ranges, node, true
+	}
+
+	relPath, ranges, err := linerange.Split(cleaned)
+	if err != nil {
+		return "", nil, nil, false
+	}
+	return relPath, ranges, nil, true
+}
+
+// selectSpecs selects the files named by root-relative paths with optional
+// line ranges, and returns the selection with the paths that escape the root.
+// Paths that name no file are selected all the same, so the copier reports
+// why they were skipped, or copies the deletion diff of a file git reports as
+// deleted.
+func (ws *workspace) selectSpecs(specs []string) (map[string]*entities.FileNode, []copier.SkippedFile) {
+	var invalid []copier.SkippedFile
+	for _, spec := range specs {
+		relPath, ranges, node, ok := ws.resolve(spec)
+		if !ok {
+			invalid = append(invalid, copier.SkippedFile{Path: spec, Reason: "invalid path"})
+			continue
+		}
+		if node == nil && len(ranges) == 0 {
+			node = ws.selector.DeletedNode(ws.root, relPath)
+		}
+		if node == nil {
+			node = entities.NewFileNode(path.Base(relPath), filepath.Join(ws.root.Path, filepath.FromSlash(relPath)), false, nil)
+		}
+		ws.selector.SetRanges(node, ranges)
+	}
+	return ws.selector.GetSelection(), invalid
+}
+
+// relative returns the slash-separated path of node relative to the root
+func (ws *workspace) relative(node *entities.FileNode) string {
+	rel, err := filepath.Rel(ws.root.Path, node.Path)
+	if err != nil {
+		return node.Path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// joinPath appends the name of an entry to the root-relative path of its directory
+func joinPath(dirPath, name string) string {
+	if dirPath == "." {
+		return name
+	}
+	return dirPath + "/" + name
+}
+
+// cleanPath cleans a root-relative path, rejecting paths that escape the root directory
+func cleanPath(relPath string) (string, bool) {
+	if relPath == "" || filepath.IsAbs(relPath) {
+		return "", false
+	}
+	cleaned := path.Clean(filepath.ToSlash(relPath))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		return "", false
+	}
+	return cleaned, true
+}
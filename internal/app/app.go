@@ -1,45 +1,410 @@
 package app
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	domain "github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+
+	"github.com/makinzm/partial-tree-copy/internal/adapters/config"
+	"github.com/makinzm/partial-tree-copy/internal/adapters/git"
 	"github.com/makinzm/partial-tree-copy/internal/adapters/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/adapters/sinks"
 	"github.com/makinzm/partial-tree-copy/internal/adapters/ui"
 	"github.com/makinzm/partial-tree-copy/internal/adapters/ui/web"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/changes"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/companions"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/copier"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/differ"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/ignore"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/navigator"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/preview"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/profiles"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/redact"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/search"
 	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/watch"
 )
 
+// Options holds the command-line settings for the application.
+// Empty values fall back to the project configuration file.
+type Options struct {
+	WebMode      bool     // Launch the browser-based GUI instead of the TUI
+	WebPort      int      // Port for the web UI server
+	Format       string   // Output format name (see copier.FormatNames, or "template")
+	Template     string   // Path of a text/template file used for the "template" format
+	MaxTokens    int      // Token budget for the copied payload; 0 means unlimited
+	StrictBudget *bool    // Refuse to copy, instead of warning, when MaxTokens is exceeded; nil falls back to the configuration
+	ShowIgnored  bool     // Show entries matched by .gitignore and other exclude files
+	Sink         string   // Destination of the copied payload (see sinks.New)
+	Profile      string   // Saved selection to start with
+	Modified     bool     // Start with the files that have unstaged git changes selected
+	Staged       bool     // Start with the files that have staged git changes selected
+	Untracked    bool     // Start with the files git does not track selected
+	Since        string   // Start with the files changed since the branch forked from this git ref selected
+	Content      string   // What to copy for each file (see copier.ContentModeNames)
+	DiffRef      string   // Git ref diffs are computed against (default HEAD)
+	Files        []string // Root-relative files to start with selected, each optionally with line ranges ("app.go:120-180")
+	DepsDepth    *int     // Import levels followed when adding the dependencies of the selection; 0 follows them all, nil falls back to the configuration
+	Companions   *bool    // Select and deselect companion files, such as tests, along with their files; nil falls back to the configuration
+	NoRedact     bool     // Copy file contents verbatim instead of replacing secrets with placeholders
+	MaxFileSize  string   // Size above which files are copied as a stub, e.g. "2MB"; "0" means no limit
+	NoWatch      bool     // Read each directory once instead of refreshing the tree as files change
+}
+
 // Application is the main application struct that wires everything together
 type Application struct {
-	presenter *ui.UIPresenter
-	webMode   bool
-	webPort   int
+	repo       *repositories.OSFileRepository
+	presenter  *ui.UIPresenter
+	navigator  *navigator.FileNavigator
+	selector   *selector.FileSelector
+	copier     *copier.FileCopier
+	opts       Options
+	rootDir    string
+	ignore     *ignore.Matcher
+	template   *copier.TemplateFormatter
+	tokenizer  tokens.Tokenizer
+	budget     tokens.Budget
+	sink       domain.OutputSink
+	profiles   *profiles.Manager
+	store      *repositories.JSONProfileRepository
+	changes    *changes.Tracker
+	searcher   *search.Searcher
+	resolver   *deps.Resolver
+	pairs      *companions.Matcher
+	autoPairs  bool // Select and deselect companion files along with their files
+	redactor   *redact.Redactor
+	classifier *classify.Classifier
+	preselect  []string // Root-relative paths selected on start by the git options and the file arguments
+	diff       copier.DiffSource
 }
 
 // NewApplication creates and initializes a new Application
-func NewApplication(webMode bool, webPort int) (*Application, error) {
+func NewApplication(opts Options) (*Application, error) {
 	// Initialize repository
 	fileRepo := repositories.NewOSFileRepository()
 
+	rootDir, err := fileRepo.GetCurrentDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	// Command-line flags take precedence over the project configuration
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format == "" {
+		opts.Format = cfg.Format
+	}
+	if opts.Template == "" {
+		opts.Template = cfg.Template
+	}
+	if opts.MaxTokens == 0 {
+		opts.MaxTokens = cfg.MaxTokens
+	}
+	strictBudget := cfg.StrictBudget
+	if opts.StrictBudget != nil {
+		strictBudget = *opts.StrictBudget
+	}
+	if opts.Sink == "" {
+		opts.Sink = cfg.Sink
+	}
+	if opts.Content == "" {
+		opts.Content = cfg.Content
+	}
+	if opts.DiffRef == "" {
+		opts.DiffRef = cfg.DiffRef
+	}
+	depsDepth := cfg.DepsDepth
+	if opts.DepsDepth != nil {
+		depsDepth = *opts.DepsDepth
+	}
+	autoPairs := cfg.AutoCompanions
+	if opts.Companions != nil {
+		autoPairs = *opts.Companions
+	}
+	opts.NoRedact = opts.NoRedact || cfg.Redact.Disabled
+	if opts.MaxFileSize == "" {
+		opts.MaxFileSize = cfg.MaxFileSize
+	}
+
+	sink, err := sinks.New(opts.Sink)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize use cases
 	fileNavigator := navigator.NewFileNavigator(fileRepo)
+	ignoreMatcher := ignore.NewMatcher(fileRepo, rootDir, git.ExcludeFiles(rootDir)...)
+	// Started from a subdirectory, the rules from the top of the repository down apply
+	if top, prefix, ok := git.TopLevel(rootDir); ok {
+		ignoreMatcher.SetTop(top, prefix)
+	}
+	fileNavigator.SetIgnoreMatcher(ignoreMatcher)
+	fileNavigator.SetShowIgnored(opts.ShowIgnored)
 	fileSelector := selector.NewFileSelector()
+	fileSelector.SetTreeLoader(fileNavigator)
+
+	// Configured companion patterns extend the built-in test conventions
+	companionMatcher, err := companions.NewMatcher(append(companions.DefaultRules(), cfg.Companions...))
+	if err != nil {
+		return nil, fmt.Errorf("invalid companions in config: %w", err)
+	}
+	fileSelector.SetCompanions(companionMatcher, autoPairs)
 	fileCopier := copier.NewFileCopier(fileRepo)
+	tokenizer := tokens.NewApproxTokenizer()
+	tokenEstimator := tokens.NewEstimator(fileRepo, tokenizer)
+	budget := tokens.Budget{Max: opts.MaxTokens, Refuse: strictBudget}
+	fileCopier.SetBudget(tokenizer, budget)
+	fileCopier.SetSink(sink)
+
+	// Secrets are replaced before anything reaches the sink, unless redaction is turned off
+	redactRules := cfg.Redact
+	redactRules.Disabled = opts.NoRedact
+	redactor, err := redact.NewRedactor(redactRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redact settings in config: %w", err)
+	}
+	fileCopier.SetRedactor(redactor)
+
+	// Text is copied as UTF-8; binary and oversized files are copied as a stub with their size and hash
+	fileClassifier := classify.NewClassifier(fileRepo)
+	if opts.MaxFileSize != "" {
+		maxFileSize, err := classify.ParseSize(opts.MaxFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max file size: %w", err)
+		}
+		fileClassifier.SetMaxSize(maxFileSize)
+	}
+	fileCopier.SetClassifier(fileClassifier)
+	tokenEstimator.SetClassifier(fileClassifier)
+
+	// Diffs are only offered inside a git repository where the ref resolves
+	if err := copier.ValidateContentMode(opts.Content); err != nil {
+		return nil, err
+	}
+	var diffSource copier.DiffSource
+	fileDiffer := differ.NewDiffer(git.NewDiffRepository(rootDir), opts.DiffRef)
+	if err := fileDiffer.Check(); err == nil {
+		diffSource = fileDiffer
+		fileCopier.SetDiffSource(diffSource)
+	} else if copier.NeedsDiff(opts.Content) {
+		return nil, fmt.Errorf("content mode %q needs a git ref to diff against: %w", opts.Content, err)
+	}
+	if err := fileCopier.SetContentMode(opts.Content); err != nil {
+		return nil, err
+	}
+
+	// Load the user-defined template, which becomes the default format unless another is requested
+	var templateFormatter *copier.TemplateFormatter
+	if opts.Template != "" {
+		text, err := fileRepo.ReadFile(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		templateFormatter, err = copier.NewTemplateFormatter(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", opts.Template, err)
+		}
+		fileCopier.AddFormatter(templateFormatter)
+		if opts.Format == "" {
+			opts.Format = copier.FormatTemplate
+		}
+	}
+
+	if opts.Format == copier.FormatTemplate {
+		if templateFormatter == nil {
+			return nil, fmt.Errorf("format %q requires --template or a template in the config file", copier.FormatTemplate)
+		}
+		fileCopier.SetFormatter(templateFormatter)
+	} else {
+		formatter, err := copier.NewFormatter(opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		fileCopier.SetFormatter(formatter)
+	}
+
+	// Selection profiles live under the project so they can be shared or ignored like the config
+	profileStore := repositories.NewJSONProfileRepository(filepath.Join(rootDir, config.Dir, config.ProfilesDir))
+	profileManager := profiles.NewManager(profileStore, fileSelector)
+	if opts.Profile != "" {
+		if err := profiles.ValidateName(opts.Profile); err != nil {
+			return nil, err
+		}
+		if _, err := profileStore.LoadProfile(opts.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the git selection sources before any UI starts so errors surface early
+	changeTracker := changes.NewTracker(git.NewChangeRepository(rootDir), rootDir)
+	fileSelector.SetDeletionSource(changeTracker)
+	preselect, err := changedPaths(changeTracker, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Files) > 0 {
+		_ = changeTracker.Refresh() // Outside a repository no file is deleted
+	}
+	if err := checkFiles(rootDir, opts.Files, changeTracker.IsDeleted); err != nil {
+		return nil, err
+	}
+	preselect = append(preselect, opts.Files...)
+
+	// Content search skips ignored entries even when they are shown in the tree
+	contentSearcher := search.NewSearcher(fileRepo, rootDir)
+	contentSearcher.SetIgnoreMatcher(ignoreMatcher)
+	contentSearcher.SetMaxFileSize(fileClassifier.MaxSize())
+
+	// Dependencies are resolved within the Go module at the root, if there is one
+	dependencyResolver := deps.NewResolver(fileRepo, rootDir)
+	dependencyResolver.SetIgnoreMatcher(ignoreMatcher)
+	dependencyResolver.SetDepth(depsDepth)
 
 	// Initialize UI presenter
-	presenter := ui.NewUIPresenter(fileNavigator, fileSelector, fileCopier)
+	presenter := ui.NewUIPresenter(fileNavigator, fileSelector, fileCopier, tokenEstimator, profileManager, changeTracker, contentSearcher, dependencyResolver, preview.NewPreviewer(fileRepo), fileClassifier, opts.Profile)
+	presenter.SelectOnStart(preselect)
 
 	return &Application{
-		presenter: presenter,
-		webMode:   webMode,
-		webPort:   webPort,
+		repo:       fileRepo,
+		presenter:  presenter,
+		navigator:  fileNavigator,
+		selector:   fileSelector,
+		copier:     fileCopier,
+		opts:       opts,
+		rootDir:    rootDir,
+		ignore:     ignoreMatcher,
+		template:   templateFormatter,
+		tokenizer:  tokenizer,
+		budget:     budget,
+		sink:       sink,
+		profiles:   profileManager,
+		store:      profileStore,
+		changes:    changeTracker,
+		searcher:   contentSearcher,
+		resolver:   dependencyResolver,
+		pairs:      companionMatcher,
+		autoPairs:  autoPairs,
+		redactor:   redactor,
+		classifier: fileClassifier,
+		preselect:  preselect,
+		diff:       diffSource,
 	}, nil
 }
 
+// changedPaths returns the files of every git selection source requested in opts
+func changedPaths(tracker *changes.Tracker, opts Options) ([]string, error) {
+	var sources []string
+	if opts.Modified {
+		sources = append(sources, changes.SourceModified)
+	}
+	if opts.Staged {
+		sources = append(sources, changes.SourceStaged)
+	}
+	if opts.Untracked {
+		sources = append(sources, changes.SourceUntracked)
+	}
+
+	var paths []string
+	for _, source := range sources {
+		found, err := tracker.Paths(source)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+	if opts.Since != "" {
+		found, err := tracker.PathsSince(opts.Since)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+	return paths, nil
+}
+
+// checkFiles returns an error unless every file argument names an existing file
+// under rootDir, with well-formed line ranges, or a file deleted according to
+// git, whose deletion diff can be copied. A missing file wraps ErrMissingFile.
+func checkFiles(rootDir string, files []string, deleted func(path string) bool) error {
+	for _, spec := range files {
+		// A file whose name looks like a range suffix is taken by its full name
+		if info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(spec))); err == nil && !info.IsDir() {
+			continue
+		}
+		if deleted(spec) {
+			continue
+		}
+		path, _, err := linerange.Split(spec)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(path)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, ErrMissingFile)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory; line ranges and file arguments need a file", path)
+		}
+	}
+	return nil
+}
+
 // Run starts the application
 func (app *Application) Run() error {
-	if app.webMode {
-		return web.StartServer(".", app.webPort)
+	// Directories are watched as the UI reads them
+	treeWatcher := app.newWatcher()
+	var webWatcher web.TreeWatcher
+	if treeWatcher != nil {
+		defer treeWatcher.Close()
+		webWatcher = treeWatcher
+		app.navigator.SetLoadObserver(treeWatcher)
+		app.presenter.SetWatcher(treeWatcher)
+	}
+
+	if app.opts.WebMode {
+		return web.StartServer(app.repo, app.opts.WebPort, web.Options{
+			Format:         app.opts.Format,
+			Template:       app.template,
+			Tokenizer:      app.tokenizer,
+			Budget:         app.budget,
+			Ignore:         app.ignore,
+			Sink:           app.sink,
+			Profiles:       app.store,
+			Profile:        app.opts.Profile,
+			Changes:        app.changes,
+			Selected:       app.preselect,
+			Content:        app.opts.Content,
+			Diff:           app.diff,
+			Search:         app.searcher,
+			Deps:           app.resolver,
+			Companions:     app.pairs,
+			AutoCompanions: app.autoPairs,
+			Redactor:       app.redactor,
+			Classifier:     app.classifier,
+			Watcher:        webWatcher,
+		})
 	}
 	return app.presenter.StartUI()
 }
+
+// newWatcher returns a watcher that refreshes the tree as files change, or nil
+// when watching is turned off or not available on this platform
+func (app *Application) newWatcher() *watch.Watcher {
+	if app.opts.NoWatch {
+		return nil
+	}
+	source, err := repositories.NewOSFileWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: the tree will not refresh as files change: %v\n", err)
+		return nil
+	}
+	return watch.NewWatcher(source)
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/adapters/config"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/deps"
+)
+
+// Why test NewApplication?
+//
+// NewApplication merges the command-line flags with the project
+// configuration. A flag given on the command line must win over the
+// configuration in both directions, or a project that turns a setting on
+// leaves no way to turn it off for one run.
+
+// writeConfig writes the project configuration of dir
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, config.Dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.Dir, config.FileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A strict budget set in the configuration applies unless --strict-budget=false is given.
+func TestNewApplication_StrictBudgetFlagOverridesConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"maxTokens": 10, "strictBudget": true}`)
+	t.Chdir(dir)
+
+	off := false
+	for name, tc := range map[string]struct {
+		flag *bool
+		want bool
+	}{
+		"not given": {nil, true},
+		"false":     {&off, false},
+	} {
+		application, err := NewApplication(Options{StrictBudget: tc.flag})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if application.budget.Refuse != tc.want {
+			t.Errorf("%s: strict budget = %v, want %v", name, application.budget.Refuse, tc.want)
+		}
+	}
+}
+
+// Companions turned on in the configuration follow their files unless --companions=false is given.
+func TestNewApplication_CompanionsFlagOverridesConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"autoCompanions": true}`)
+	t.Chdir(dir)
+
+	off := false
+	for name, tc := range map[string]struct {
+		flag *bool
+		want bool
+	}{
+		"not given": {nil, true},
+		"false":     {&off, false},
+	} {
+		application, err := NewApplication(Options{Companions: tc.flag})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if application.autoPairs != tc.want {
+			t.Errorf("%s: companions = %v, want %v", name, application.autoPairs, tc.want)
+		}
+	}
+}
+
+// A depth of 0 follows every import level, so --deps-depth 0 must not fall back to the configured depth.
+func TestNewApplication_DepsDepthFlagOverridesConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"depsDepth": 2}`)
+	t.Chdir(dir)
+
+	zero := 0
+	for name, tc := range map[string]struct {
+		flag *int
+		want int
+	}{
+		"not given": {nil, 2},
+		"0":         {&zero, deps.Unlimited},
+	} {
+		application, err := NewApplication(Options{DepsDepth: tc.flag})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := application.resolver.Depth(); got != tc.want {
+			t.Errorf("%s: depth = %d, want %d", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/makinzm/partial-tree-copy/internal/adapters/sinks"
+	domain "github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Errors returned by RunCopy that map to dedicated exit codes
+var (
+	// ErrNothingMatched means no file matched the include/exclude patterns
+	ErrNothingMatched = errors.New("no files matched")
+
+	// ErrUnreadableFiles means the payload was written but some matched files could not be read
+	// or no longer exist
+	ErrUnreadableFiles = errors.New("some files could not be read")
+
+	// ErrMissingFile means a file argument names no file, nor a file deleted according to git
+	ErrMissingFile = errors.New("no such file")
+)
+
+// CopyOptions holds the settings of the non-interactive copy command
+type CopyOptions struct {
+	Include []string // Glob patterns of files to copy, relative to the root; empty means all files
+	Exclude []string // Glob patterns of files to leave out
+	Output  string   // A sink name (see sinks.New), "-" for stdout, or a file path; empty means the configured sink
+}
+
+// RunCopy selects files by glob patterns, the file arguments, the configured profile,
+// or git status, and writes the formatted payload without starting a UI. Unreadable files and
+// files of the profile that no longer exist are reported on stderr and yield
+// ErrUnreadableFiles after the rest of the payload has been written.
+func (app *Application) RunCopy(opts CopyOptions) error {
+	root, err := app.navigator.BuildRootNode()
+	if err != nil {
+		return err
+	}
+
+	// A profile, the git options, and the file arguments select their files; patterns add to them
+	var missing []string
+	if app.opts.Profile != "" {
+		result, err := app.profiles.Load(app.opts.Profile, root)
+		if err != nil {
+			return err
+		}
+		missing = result.Missing
+	}
+	app.selector.SelectPaths(root, app.preselect)
+
+	preselected := app.opts.Profile != "" || app.opts.Modified || app.opts.Staged || app.opts.Untracked || app.opts.Since != "" ||
+		len(app.opts.Files) > 0
+	if !preselected || len(opts.Include) > 0 {
+		if _, err := app.selector.SelectMatching(root, opts.Include, opts.Exclude); err != nil {
+			return err
+		}
+	}
+
+	if app.autoPairs {
+		app.selector.SelectCompanions(root)
+	}
+
+	matched := len(app.selector.GetSelection())
+	if matched == 0 && len(missing) == 0 {
+		return ErrNothingMatched
+	}
+
+	sensitive, err := app.copier.CheckSensitive(app.selector.GetSelection())
+	if err != nil {
+		return err
+	}
+	result, err := app.copier.RenderSelection(app.selector.GetSelection())
+	if err != nil {
+		return err
+	}
+	if err := app.copier.CheckBudget(result.Payload); err != nil {
+		return err
+	}
+
+	sink := app.outputSink(opts.Output)
+	if err := sink.Write(result.Payload); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", sink.Name(), err)
+	}
+
+	if budget := app.copier.Budget(); budget.Exceeded(result.Tokens) {
+		fmt.Fprintf(os.Stderr, "warning: copied ~%d tokens, over the budget of %d\n", result.Tokens, budget.Max)
+	}
+	for _, path := range sensitive {
+		fmt.Fprintf(os.Stderr, "warning: copied %s, which is named like a file holding credentials\n", path)
+	}
+	for _, path := range result.Stubs() {
+		fmt.Fprintf(os.Stderr, "copied %s as a stub with its size and hash\n", path)
+	}
+	if result.Redacted > 0 {
+		fmt.Fprintf(os.Stderr, "redacted %s\n", result.RedactedSecrets())
+	}
+	for _, path := range missing {
+		fmt.Fprintf(os.Stderr, "skipped %s: no longer exists\n", path)
+	}
+	for _, file := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s: %s\n", file.Path, file.Reason)
+	}
+	if n := len(missing) + len(result.Skipped); n > 0 {
+		return fmt.Errorf("%w (%d of %d)", ErrUnreadableFiles, n, matched+len(missing))
+	}
+	return nil
+}
+
+// outputSink returns the sink named by output, treating anything that is
+// not a sink name as a file path
+func (app *Application) outputSink(output string) domain.OutputSink {
+	if output == "" {
+		return app.sink
+	}
+	if sink, err := sinks.New(output); err == nil {
+		return sink
+	}
+	return sinks.NewFileSink(output)
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Why test the copy command?
+//
+// The copy command is the scriptable entry point, so what it reports on
+// stderr is all a script or a person running it sees. It must report the
+// same things as the TUI and the web UI, such as a budget overrun.
+
+// captureStderr returns what fn writes to stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// A non-strict budget copies the payload all the same, but warns about the overrun.
+func TestRunCopy_WarnsOverBudget(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	application, err := NewApplication(Options{MaxTokens: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "payload.txt")
+	var runErr error
+	stderr := captureStderr(t, func() { runErr = application.RunCopy(CopyOptions{Output: output}) })
+	if runErr != nil {
+		t.Fatalf("RunCopy returned error: %v", runErr)
+	}
+	if payload, err := os.ReadFile(output); err != nil || !strings.Contains(string(payload), "func main") {
+		t.Fatalf("expected the full payload to be written, got %q (%v)", payload, err)
+	}
+	if !strings.Contains(stderr, "over the budget of 3") {
+		t.Errorf("expected a budget warning on stderr, got %q", stderr)
+	}
+}
+
+// runGit runs git in dir with a fixed identity and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// A deleted file has no content left, but its deletion diff is part of the
+// change; both a file argument and a git option must reach it.
+func TestRunCopy_DiffOfDeletedFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main_test.go"), []byte("package main\n\nfunc TestGone(t *testing.T) {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "init")
+	runGit(t, dir, "rm", "-q", "src/main_test.go")
+	t.Chdir(dir)
+
+	for name, opts := range map[string]Options{
+		"file argument": {Content: "diff", Files: []string{"src/main_test.go"}},
+		"staged":        {Content: "diff", Staged: true},
+	} {
+		application, err := NewApplication(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		output := filepath.Join(t.TempDir(), "payload.txt")
+		if err := application.RunCopy(CopyOptions{Output: output}); err != nil {
+			t.Fatalf("%s: RunCopy returned error: %v", name, err)
+		}
+		payload, err := os.ReadFile(output)
+		if err != nil || !strings.Contains(string(payload), "-func TestGone") {
+			t.Errorf("%s: expected the deletion diff, got %q (%v)", name, payload, err)
+		}
+	}
+}
+
+// A file argument naming no file is reported as unreadable, so scripts can tell it from other errors.
+func TestNewApplication_MissingFileArgument(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, err := NewApplication(Options{Files: []string{"missing.go:1-3"}})
+	if !errors.Is(err, ErrMissingFile) || !strings.Contains(err.Error(), "missing.go") {
+		t.Fatalf("expected ErrMissingFile for missing.go, got %v", err)
+	}
+}
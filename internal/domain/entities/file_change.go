@@ -0,0 +1,47 @@
+package entities
+
+// ChangeStatus is the version control status shown next to a file
+type ChangeStatus int
+
+const (
+	Unchanged ChangeStatus = iota // The file matches HEAD (or is not in a repository)
+	Modified                      // The file differs from HEAD
+	Added                         // The file is new in the index
+	Untracked                     // The file is not tracked
+)
+
+// Badge returns the one-letter marker of the status, or "" when unchanged
+func (s ChangeStatus) Badge() string {
+	switch s {
+	case Modified:
+		return "M"
+	case Added:
+		return "A"
+	case Untracked:
+		return "?"
+	}
+	return ""
+}
+
+// FileChange describes how a file differs from HEAD in the index and the working tree
+type FileChange struct {
+	Path      string // Path relative to the root directory, with forward slashes
+	Staged    bool   // The index differs from HEAD
+	Modified  bool   // The working tree differs from the index
+	Untracked bool   // The file is not tracked
+	Added     bool   // The file is new in the index
+	Deleted   bool   // The file was deleted from the index or the working tree
+}
+
+// Status returns the status shown for the change
+func (c FileChange) Status() ChangeStatus {
+	switch {
+	case c.Untracked:
+		return Untracked
+	case c.Added:
+		return Added
+	case c.Staged || c.Modified:
+		return Modified
+	}
+	return Unchanged
+}
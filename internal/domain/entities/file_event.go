@@ -0,0 +1,17 @@
+package entities
+
+// FileOp is the kind of change reported by a file watcher
+type FileOp int
+
+const (
+	FileCreated FileOp = iota // An entry was created in, or moved into, a watched directory
+	FileRemoved               // An entry was deleted from, or moved out of, a watched directory
+	FileWritten               // A file in a watched directory was written and closed
+	EventsLost                // The watcher dropped events; any watched directory may have changed
+)
+
+// FileEvent is a change to an entry of a watched directory
+type FileEvent struct {
+	Op   FileOp
+	Path string // Full path of the entry; empty when events were lost
+}
@@ -2,7 +2,8 @@ package entities
 
 // FileNode represents a node in the file tree structure.
 // It contains information about the file or directory, including its name, path, whether it is a directory,
-// its expanded state, its child nodes, selection state, and a reference to its parent node.
+// its expanded state, its child nodes, selection state, selected line ranges, ignore state, and a reference to its parent node.
+// An expanded source file lists its top-level declarations as child nodes, which carry a Symbol and the path of the file.
 type FileNode struct {
 	Name     string      // Name of the file or directory
 	Path     string      // Full path of the file or directory
@@ -10,7 +11,11 @@ type FileNode struct {
 	Expanded bool        // Indicates if the directory is expanded in the tree view
 	Children []*FileNode // List of child nodes (files/directories within this directory)
 	Selected bool        // Indicates if the node is selected
+	Ranges   []LineRange // Line ranges to copy from a selected file; empty means the whole file
+	Ignored  bool        // Indicates if the node (or one of its ancestors) matches an ignore rule
 	Parent   *FileNode   // Reference to the parent node
+	Symbol   *Symbol     // Declaration listed under an expanded source file; nil for files and directories
+	Deleted  bool        // A file deleted from disk, selected outside the tree so its deletion diff can be copied
 }
 
 // NewFileNode creates a new FileNode with the given properties
@@ -0,0 +1,22 @@
+package entities
+
+import "strconv"
+
+// LineRange is an inclusive range of 1-based line numbers in a file
+type LineRange struct {
+	Start int // First line of the range
+	End   int // Last line of the range, at least Start
+}
+
+// String returns the range as "120-180", or "120" when it covers a single line
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+}
+
+// Contains reports whether line lies within the range
+func (r LineRange) Contains(line int) bool {
+	return line >= r.Start && line <= r.End
+}
@@ -0,0 +1,8 @@
+package entities
+
+// Symbol is a top-level declaration of a source file, such as a function or a type
+type Symbol struct {
+	Name      string    // Declaration as shown in the tree, e.g. "func (fn *FileNavigator) BuildTree" or "type FileNode"
+	Lines     LineRange // Lines of the declaration, including its doc comment
+	FileLines int       // Lines of the whole file, so the content after the last declaration can be kept
+}
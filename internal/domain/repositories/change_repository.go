@@ -0,0 +1,14 @@
+package repositories
+
+import "github.com/makinzm/partial-tree-copy/internal/domain/entities"
+
+// ChangeRepository defines the interface for querying version control changes
+type ChangeRepository interface {
+	// Changes returns the files that differ from HEAD in the index or the
+	// working tree, and untracked files, under the root directory
+	Changes() ([]entities.FileChange, error)
+
+	// ChangedSince returns the root-relative paths of existing files that
+	// differ between the working tree and the point where ref forked from HEAD
+	ChangedSince(ref string) ([]string, error)
+}
@@ -0,0 +1,14 @@
+package repositories
+
+// DiffRepository defines the interface for computing unified diffs against a version control ref
+type DiffRepository interface {
+	// ExistsAt reports whether the root-relative path exists in ref
+	ExistsAt(ref, path string) (bool, error)
+
+	// Diff returns the unified diff of the root-relative path between ref and
+	// the working tree, which is empty when the file is unchanged
+	Diff(ref, path string) (string, error)
+
+	// DiffNew returns the unified diff that adds the working-tree file at the root-relative path
+	DiffNew(path string) (string, error)
+}
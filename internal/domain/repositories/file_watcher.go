@@ -0,0 +1,16 @@
+package repositories
+
+import "github.com/makinzm/partial-tree-copy/internal/domain/entities"
+
+// FileWatcher defines the interface for watching directories for changes to their entries
+type FileWatcher interface {
+	// Add starts watching the entries of the directory at path, but not of
+	// its subdirectories; adding a directory again has no effect
+	Add(path string) error
+
+	// Events returns the channel changes are delivered on, closed after Close
+	Events() <-chan entities.FileEvent
+
+	// Close stops watching every directory
+	Close() error
+}
@@ -0,0 +1,10 @@
+package repositories
+
+// OutputSink defines a destination for the copied payload
+type OutputSink interface {
+	// Name returns a short description of the destination (e.g. "clipboard")
+	Name() string
+
+	// Write delivers the payload to the destination
+	Write(content string) error
+}
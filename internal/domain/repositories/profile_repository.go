@@ -0,0 +1,19 @@
+package repositories
+
+import "errors"
+
+// ErrProfileNotFound is returned when a selection profile does not exist
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileRepository defines the interface for persisting named selection profiles
+type ProfileRepository interface {
+	// SaveProfile stores the root-relative paths under name, replacing any existing profile
+	SaveProfile(name string, paths []string) error
+
+	// LoadProfile returns the root-relative paths stored under name.
+	// It returns an error wrapping ErrProfileNotFound when there is no such profile.
+	LoadProfile(name string) ([]string, error)
+
+	// ListProfiles returns the names of all stored profiles in alphabetical order
+	ListProfiles() ([]string, error)
+}
@@ -0,0 +1,119 @@
+package changes
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Sources of changed files accepted by Tracker.Paths
+const (
+	SourceModified  = "modified"  // Files with unstaged changes
+	SourceStaged    = "staged"    // Files with staged changes
+	SourceUntracked = "untracked" // Files git does not track
+	SourceChanged   = "changed"   // Files from any of the sources above
+)
+
+// SourceNames returns the sources accepted by Tracker.Paths in display order
+func SourceNames() []string {
+	return []string{SourceModified, SourceStaged, SourceUntracked, SourceChanged}
+}
+
+// Tracker caches the version control status of the files under a root directory
+type Tracker struct {
+	repo repositories.ChangeRepository
+	root string
+
+	mu      sync.RWMutex
+	changes map[string]entities.FileChange // Keyed by root-relative path
+}
+
+// NewTracker creates a new Tracker for the files under root
+func NewTracker(repo repositories.ChangeRepository, root string) *Tracker {
+	return &Tracker{repo: repo, root: root}
+}
+
+// Refresh reloads the status of every file.
+// Outside a repository the error is returned and every file reports Unchanged.
+func (t *Tracker) Refresh() error {
+	list, err := t.repo.Changes()
+	changes := make(map[string]entities.FileChange, len(list))
+	for _, change := range list {
+		changes[change.Path] = change
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes = changes
+	return err
+}
+
+// Status returns the status of the file at the full path
+func (t *Tracker) Status(path string) entities.ChangeStatus {
+	rel, err := filepath.Rel(t.root, path)
+	if err != nil {
+		return entities.Unchanged
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.changes[filepath.ToSlash(rel)].Status()
+}
+
+// Paths refreshes the status and returns the sorted root-relative paths of the
+// files from source (see SourceNames), including files deleted from disk,
+// whose deletion diff can still be copied
+func (t *Tracker) Paths(source string) ([]string, error) {
+	var include func(entities.FileChange) bool
+	switch source {
+	case SourceModified:
+		include = func(c entities.FileChange) bool { return c.Modified }
+	case SourceStaged:
+		include = func(c entities.FileChange) bool { return c.Staged }
+	case SourceUntracked:
+		include = func(c entities.FileChange) bool { return c.Untracked }
+	case SourceChanged:
+		include = func(c entities.FileChange) bool { return true }
+	default:
+		return nil, fmt.Errorf("unknown source %q (available: %s)", source, strings.Join(SourceNames(), ", "))
+	}
+
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var paths []string
+	for path, change := range t.changes {
+		if include(change) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// IsDeleted reports whether git reported the file at the root-relative path as
+// deleted when the status was last refreshed
+func (t *Tracker) IsDeleted(path string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.changes[path].Deleted
+}
+
+// PathsSince returns the sorted root-relative paths of the existing files
+// changed on the current branch since it forked from ref
+func (t *Tracker) PathsSince(ref string) ([]string, error) {
+	paths, err := t.repo.ChangedSince(ref)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
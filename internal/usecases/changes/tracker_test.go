@@ -0,0 +1,88 @@
+package changes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test the tracker?
+//
+// "Everything I touched on this branch" is only useful if each source picks
+// exactly the right files: deleted files are only copied as a diff, untracked
+// files are not "modified", and badges must match the path the tree shows.
+
+// mockChangeRepo returns fixed changes
+type mockChangeRepo struct {
+	changes []entities.FileChange
+	since   []string
+}
+
+func (m *mockChangeRepo) Changes() ([]entities.FileChange, error) { return m.changes, nil }
+func (m *mockChangeRepo) ChangedSince(string) ([]string, error)   { return m.since, nil }
+
+func newTestTracker() *Tracker {
+	return NewTracker(&mockChangeRepo{
+		changes: []entities.FileChange{
+			{Path: "b.go", Modified: true},
+			{Path: "a.go", Staged: true, Modified: true},
+			{Path: "new.go", Staged: true, Added: true},
+			{Path: "notes.txt", Untracked: true},
+			{Path: "gone.go", Modified: true, Deleted: true},
+		},
+		since: []string{"z.go", "a.go"},
+	}, "/p")
+}
+
+// Each source must select its own files, sorted, deleted ones included so
+// their deletion diff can be copied.
+func TestTracker_Paths(t *testing.T) {
+	tracker := newTestTracker()
+	for source, want := range map[string][]string{
+		SourceModified:  {"a.go", "b.go", "gone.go"},
+		SourceStaged:    {"a.go", "new.go"},
+		SourceUntracked: {"notes.txt"},
+		SourceChanged:   {"a.go", "b.go", "gone.go", "new.go", "notes.txt"},
+	} {
+		got, err := tracker.Paths(source)
+		if err != nil {
+			t.Fatalf("Paths(%q) returned error: %v", source, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Paths(%q) = %v, want %v", source, got, want)
+		}
+	}
+
+	if !tracker.IsDeleted("gone.go") || tracker.IsDeleted("a.go") {
+		t.Error("only gone.go should be reported as deleted")
+	}
+
+	if _, err := tracker.Paths("stashed"); err == nil {
+		t.Error("unknown sources should be rejected")
+	}
+
+	since, _ := tracker.PathsSince("main")
+	if !reflect.DeepEqual(since, []string{"a.go", "z.go"}) {
+		t.Errorf("PathsSince = %v", since)
+	}
+}
+
+// Badges are looked up by the full path of the tree node.
+func TestTracker_Status(t *testing.T) {
+	tracker := newTestTracker()
+	if err := tracker.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"/p/b.go":      "M",
+		"/p/new.go":    "A",
+		"/p/notes.txt": "?",
+		"/p/clean.go":  "",
+	} {
+		if got := tracker.Status(path).Badge(); got != want {
+			t.Errorf("badge of %s = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package classify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// DefaultMaxSize is the size above which files are copied as a stub unless configured otherwise
+const DefaultMaxSize = 1 << 20
+
+// probeSize is the number of bytes inspected to tell text from binary content
+const probeSize = 8000
+
+// Kind is what a file holds, as far as copying it is concerned
+type Kind int
+
+const (
+	Text      Kind = iota // Text, copied as UTF-8
+	Binary                // Binary content, copied as a stub
+	Oversized             // Larger than the size limit, copied as a stub
+)
+
+// String returns the name of the kind, e.g. "binary"
+func (k Kind) String() string {
+	switch k {
+	case Binary:
+		return "binary"
+	case Oversized:
+		return "oversized"
+	default:
+		return "text"
+	}
+}
+
+// Text encodings recognized by Detect
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	ShiftJIS    = "shift_jis"
+	EUCJP       = "euc-jp"
+	Windows1252 = "windows-1252"
+)
+
+// decoders convert the recognized encodings other than UTF-8
+var decoders = map[string]encoding.Encoding{
+	UTF16LE:     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	UTF16BE:     unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	ShiftJIS:    japanese.ShiftJIS,
+	EUCJP:       japanese.EUCJP,
+	Windows1252: charmap.Windows1252,
+}
+
+// legacyEncodings are tried in order on text that is not valid UTF-8. EUC-JP
+// comes before Shift_JIS, which would read EUC-JP as half-width katakana.
+var legacyEncodings = []string{EUCJP, ShiftJIS}
+
+// Class describes the content of a file
+type Class struct {
+	Kind     Kind
+	Encoding string // Encoding of a text file; empty for binary and oversized files
+	Size     int64  // Size of the file in bytes
+}
+
+// Transcoded reports whether the file is text in an encoding other than UTF-8
+func (c Class) Transcoded() bool {
+	return c.Kind == Text && c.Encoding != UTF8
+}
+
+// HeadReader reads the beginning of a file without loading all of it
+type HeadReader interface {
+	// ReadHead returns up to n bytes from the start of the file at path, and the size of the file
+	ReadHead(path string, n int) ([]byte, int64, error)
+}
+
+// FileReader reads whole text files and streams the others, which are never loaded at once
+type FileReader interface {
+	HeadReader
+	ReadFile(path string) ([]byte, error)
+
+	// Open opens the file at path for reading it in pieces
+	Open(path string) (io.ReadCloser, error)
+}
+
+// Classifier tells text files from binary and oversized ones, and decodes text to UTF-8
+type Classifier struct {
+	repo    FileReader
+	maxSize int64
+}
+
+// NewClassifier creates a Classifier with DefaultMaxSize; repo is only needed by Inspect and ReadText
+func NewClassifier(repo FileReader) *Classifier {
+	return &Classifier{repo: repo, maxSize: DefaultMaxSize}
+}
+
+// SetMaxSize sets the size above which files are oversized; 0 means no limit
+func (c *Classifier) SetMaxSize(size int64) {
+	c.maxSize = max(size, 0)
+}
+
+// MaxSize returns the size above which files are oversized; 0 means no limit
+func (c *Classifier) MaxSize() int64 {
+	return c.maxSize
+}
+
+// Classify returns the class of a file's complete content
+func (c *Classifier) Classify(content []byte) Class {
+	return c.classify(content, int64(len(content)))
+}
+
+// Inspect returns the class of the file at path from its beginning, without reading all of it
+func (c *Classifier) Inspect(path string) (Class, error) {
+	head, size, err := c.repo.ReadHead(path, probeSize)
+	if err != nil {
+		return Class{}, err
+	}
+	return c.classify(head, size), nil
+}
+
+// ReadText returns what is copied for the file at path, as Text does for its
+// content. Binary and oversized files are told from their size and first
+// bytes, and their hash is computed as they are read, so a large file is never
+// held in memory.
+func (c *Classifier) ReadText(path string) (string, Class, error) {
+	class, err := c.Inspect(path)
+	if err != nil {
+		return "", Class{}, err
+	}
+	if class.Kind != Text {
+		sum, err := c.hashFile(path)
+		if err != nil {
+			return "", Class{}, err
+		}
+		return stub(sum, class), class, nil
+	}
+
+	content, err := c.repo.ReadFile(path)
+	if err != nil {
+		return "", Class{}, err
+	}
+	text, class := c.Text(content)
+	return text, class, nil
+}
+
+// hashFile returns the SHA-256 of the file at path, reading it in pieces
+func (c *Classifier) hashFile(path string) ([]byte, error) {
+	f, err := c.repo.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// classify returns the class of a file of size bytes that starts with head
+func (c *Classifier) classify(head []byte, size int64) Class {
+	class := Class{Size: size}
+	if c.maxSize > 0 && size > c.maxSize {
+		class.Kind = Oversized
+		return class
+	}
+	binary, enc := Detect(head, int64(len(head)) < size)
+	if binary {
+		class.Kind = Binary
+		return class
+	}
+	class.Encoding = enc
+	return class
+}
+
+// Text returns what is copied for a file's content: the text decoded to UTF-8,
+// or a stub with the size and hash of a binary or oversized file. A nil
+// Classifier returns the content as it is.
+func (c *Classifier) Text(content []byte) (string, Class) {
+	if c == nil {
+		return string(content), Class{Kind: Text, Encoding: UTF8, Size: int64(len(content))}
+	}
+	class := c.Classify(content)
+	if class.Kind != Text {
+		return Stub(content, class), class
+	}
+	text, err := Decode(content, class.Encoding)
+	if err != nil {
+		return Stub(content, Class{Kind: Binary, Size: class.Size}), Class{Kind: Binary, Size: class.Size}
+	}
+	return text, class
+}
+
+// Detect reports whether content looks binary and, if not, its encoding.
+// partial tells that content is only the beginning of a file, so a character
+// cut at its end is not taken for invalid text.
+func Detect(content []byte, partial bool) (binary bool, enc string) {
+	probe := content
+	if len(probe) > probeSize {
+		probe, partial = probe[:probeSize], true
+	}
+
+	// Byte order marks, and UTF-16 without one, come before the NUL check they would fail
+	switch {
+	case bytes.HasPrefix(probe, []byte{0xEF, 0xBB, 0xBF}):
+		return false, UTF8
+	case bytes.HasPrefix(probe, []byte{0xFF, 0xFE}):
+		return false, UTF16LE
+	case bytes.HasPrefix(probe, []byte{0xFE, 0xFF}):
+		return false, UTF16BE
+	}
+	if enc := detectUTF16(probe); enc != "" {
+		return false, enc
+	}
+	if bytes.IndexByte(probe, 0) >= 0 || controlRatio(probe) > 0.1 {
+		return true, ""
+	}
+
+	if partial {
+		probe = trimIncomplete(probe)
+	}
+	if utf8.Valid(probe) {
+		return false, UTF8
+	}
+	for _, candidate := range legacyEncodings {
+		if decoded, err := decoders[candidate].NewDecoder().Bytes(probe); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+			return false, candidate
+		}
+	}
+	return false, Windows1252
+}
+
+// Decode converts content in enc to UTF-8, dropping a byte order mark
+func Decode(content []byte, enc string) (string, error) {
+	if enc == UTF8 || enc == "" {
+		return string(bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})), nil
+	}
+	decoder, ok := decoders[enc]
+	if !ok {
+		return "", fmt.Errorf("unknown encoding %q", enc)
+	}
+	text, err := decoder.NewDecoder().Bytes(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", enc, err)
+	}
+	return string(text), nil
+}
+
+// detectUTF16 recognizes UTF-16 without a byte order mark by the NUL bytes
+// that mostly ASCII text has in every other position
+func detectUTF16(probe []byte) string {
+	if len(probe) < 4 {
+		return ""
+	}
+	var evenZeros, oddZeros int
+	for i, b := range probe {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	half := len(probe) / 2
+	switch {
+	case oddZeros > half*3/4 && evenZeros == 0:
+		return UTF16LE
+	case evenZeros > half*3/4 && oddZeros == 0:
+		return UTF16BE
+	}
+	return ""
+}
+
+// controlRatio returns the share of control bytes other than whitespace and escapes
+func controlRatio(probe []byte) float64 {
+	if len(probe) == 0 {
+		return 0
+	}
+	control := 0
+	for _, b := range probe {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != 0x1B {
+			control++
+		}
+	}
+	return float64(control) / float64(len(probe))
+}
+
+// trimIncomplete drops a multi-byte character cut off at the end of probe
+func trimIncomplete(probe []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(probe); i++ {
+		if utf8.RuneStart(probe[len(probe)-i]) {
+			if !utf8.FullRune(probe[len(probe)-i:]) {
+				return probe[:len(probe)-i]
+			}
+			break
+		}
+	}
+	// Legacy encodings use two-byte characters; an odd last byte above ASCII may be cut in half
+	if n := len(probe); n > 0 && probe[n-1] >= 0x80 && !utf8.Valid(probe) {
+		return probe[:n-1]
+	}
+	return probe
+}
+
+// Stub returns the placeholder copied instead of a binary or oversized file
+func Stub(content []byte, class Class) string {
+	sum := sha256.Sum256(content)
+	return stub(sum[:], class)
+}
+
+// stub returns the placeholder of a file of the class whose SHA-256 is sum
+func stub(sum []byte, class Class) string {
+	return fmt.Sprintf("[%s file omitted: %s, sha256 %s]\n", class.Kind, FormatSize(class.Size), hex.EncodeToString(sum))
+}
+
+// sizeUnits are the suffixes ParseSize accepts, in powers of 1024
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// ParseSize parses a size such as "512KB", "2MB", or "1048576" (bytes)
+func ParseSize(text string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(text))
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if rest, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, factor = strings.TrimSpace(rest), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 512KB or 2MB)", text)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// FormatSize renders a size in bytes for people, e.g. "1.5 MiB"
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
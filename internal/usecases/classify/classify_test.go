@@ -0,0 +1,223 @@
+package classify
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Why test Classifier?
+//
+// A misclassified file either floods the payload with bytes no model can read
+// or hides real source behind a stub. Legacy encodings are only guessed, and a
+// wrong guess turns Japanese comments into mojibake, so the order in which
+// encodings are tried matters. Inspect sees only the head of a file, which
+// may end in the middle of a character.
+
+const japaneseText = "こんにちは、世界。日本語のテキストです。\n"
+
+// Detect tells binary content from text and names the encoding of the text.
+func TestDetect(t *testing.T) {
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(japaneseText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eucJP, err := japanese.EUCJP.NewEncoder().Bytes([]byte(japaneseText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16LE, err := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("package main\n\nfunc main() {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16BEBOM, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewEncoder().Bytes([]byte("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		partial bool
+		binary  bool
+		enc     string
+	}{
+		{"utf-8", []byte("package main\n// 日本語\n"), false, false, UTF8},
+		{"utf-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, "x := 1\n"...), false, false, UTF8},
+		{"empty", nil, false, false, UTF8},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00"), false, true, ""},
+		{"control bytes", bytes.Repeat([]byte{0x01, 0x02, 'a', 0x03}, 50), false, true, ""},
+		{"utf-16le without BOM", utf16LE, false, false, UTF16LE},
+		{"utf-16be with BOM", utf16BEBOM, false, false, UTF16BE},
+		{"shift_jis", shiftJIS, false, false, ShiftJIS},
+		{"euc-jp", eucJP, false, false, EUCJP},
+		{"windows-1252", []byte("caf\xe9 cr\xe8me\n"), false, false, Windows1252},
+		{"utf-8 head cut in a character", []byte("日本語")[:7], true, false, UTF8},
+		{"shift_jis head cut in a character", shiftJIS[:len(shiftJIS)-6], true, false, ShiftJIS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, enc := Detect(tt.content, tt.partial)
+			if binary != tt.binary || enc != tt.enc {
+				t.Errorf("Detect = (%v, %q), want (%v, %q)", binary, enc, tt.binary, tt.enc)
+			}
+		})
+	}
+}
+
+// Text decodes legacy encodings to UTF-8 and stands in a stub for binary and oversized files.
+func TestClassifier_Text(t *testing.T) {
+	c := NewClassifier(nil)
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(japaneseText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text, class := c.Text(shiftJIS); text != japaneseText || class.Kind != Text || !class.Transcoded() {
+		t.Errorf("Text(shift_jis) = %q, %+v", text, class)
+	}
+	if text, class := c.Text([]byte("plain\n")); text != "plain\n" || class.Transcoded() {
+		t.Errorf("Text(utf-8) = %q, %+v", text, class)
+	}
+
+	binary := []byte("\x00\x01\x02binary")
+	text, class := c.Text(binary)
+	want := "[binary file omitted: 9 B, sha256 "
+	if class.Kind != Binary || !strings.HasPrefix(text, want) || strings.Contains(text, "\x00") {
+		t.Errorf("Text(binary) = %q, %+v", text, class)
+	}
+
+	c.SetMaxSize(4)
+	if text, class := c.Text([]byte("too long\n")); class.Kind != Oversized || !strings.HasPrefix(text, "[oversized file omitted: 9 B, sha256 ") {
+		t.Errorf("Text(oversized) = %q, %+v", text, class)
+	}
+	c.SetMaxSize(0)
+	if _, class := c.Text(bytes.Repeat([]byte("a"), 2*DefaultMaxSize)); class.Kind != Text {
+		t.Errorf("a zero limit should disable the size check, got %+v", class)
+	}
+
+	var none *Classifier
+	if text, class := none.Text(shiftJIS); text != string(shiftJIS) || class.Kind != Text {
+		t.Errorf("a nil classifier should return the content as it is, got %q, %+v", text, class)
+	}
+}
+
+// mockFileReader serves files from memory, recording the files read whole
+type mockFileReader struct {
+	content map[string][]byte
+	read    []string
+}
+
+func (m *mockFileReader) ReadHead(path string, n int) ([]byte, int64, error) {
+	content, ok := m.content[path]
+	if !ok {
+		return nil, 0, errors.New("not found")
+	}
+	return content[:min(n, len(content))], int64(len(content)), nil
+}
+
+func (m *mockFileReader) ReadFile(path string) ([]byte, error) {
+	content, ok := m.content[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	m.read = append(m.read, path)
+	return content, nil
+}
+
+func (m *mockFileReader) Open(path string) (io.ReadCloser, error) {
+	content, ok := m.content[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Inspect classifies a file from its head, using the size of the whole file.
+func TestClassifier_Inspect(t *testing.T) {
+	repo := &mockFileReader{content: map[string][]byte{
+		"/big.txt":   bytes.Repeat([]byte("日本語\n"), 1000),
+		"/image.png": append([]byte("\x89PNG\x00"), bytes.Repeat([]byte("x"), 10000)...),
+	}}
+	c := NewClassifier(repo)
+
+	class, err := c.Inspect("/big.txt")
+	if err != nil || class.Kind != Text || class.Encoding != UTF8 || class.Size != 10000 {
+		t.Errorf("Inspect(big.txt) = %+v, %v", class, err)
+	}
+	if class, err := c.Inspect("/image.png"); err != nil || class.Kind != Binary {
+		t.Errorf("Inspect(image.png) = %+v, %v", class, err)
+	}
+	c.SetMaxSize(5000)
+	if class, err := c.Inspect("/big.txt"); err != nil || class.Kind != Oversized {
+		t.Errorf("Inspect(big.txt) over the limit = %+v, %v", class, err)
+	}
+	if _, err := c.Inspect("/missing"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// A 200 MB build artifact selected by mistake must become its stub without
+// being loaded into memory; only text files are read whole.
+func TestClassifier_ReadText(t *testing.T) {
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(japaneseText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := append([]byte("\x89PNG\x00"), bytes.Repeat([]byte("x"), 1000)...)
+	big := bytes.Repeat([]byte("log line\n"), 1000)
+	repo := &mockFileReader{content: map[string][]byte{
+		"/image.png": image,
+		"/big.log":   big,
+		"/sjis.txt":  shiftJIS,
+	}}
+	c := NewClassifier(repo)
+	c.SetMaxSize(5000)
+
+	text, class, err := c.ReadText("/image.png")
+	if err != nil || class.Kind != Binary || text != Stub(image, class) {
+		t.Errorf("ReadText(image.png) = %q, %+v, %v", text, class, err)
+	}
+	text, class, err = c.ReadText("/big.log")
+	if err != nil || class.Kind != Oversized || text != Stub(big, class) {
+		t.Errorf("ReadText(big.log) = %q, %+v, %v", text, class, err)
+	}
+	if text, class, err := c.ReadText("/sjis.txt"); err != nil || class.Encoding != ShiftJIS || text != japaneseText {
+		t.Errorf("ReadText(sjis.txt) = %q, %+v, %v", text, class, err)
+	}
+	if len(repo.read) != 1 || repo.read[0] != "/sjis.txt" {
+		t.Errorf("only the text file should be read whole, got %v", repo.read)
+	}
+	if _, _, err := c.ReadText("/missing"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// Sizes are read the way people write them in flags and config files.
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":       0,
+		"1048576": 1 << 20,
+		"512KB":   512 << 10,
+		"2mb":     2 << 20,
+		"1.5 MiB": 3 << 19,
+		"1G":      1 << 30,
+		"100B":    100,
+	}
+	for text, want := range tests {
+		got, err := ParseSize(text)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"", "MB", "-1KB", "lots"} {
+		if _, err := ParseSize(text); err == nil {
+			t.Errorf("ParseSize(%q) should fail", text)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package companions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholders of rule patterns
+const (
+	namePlaceholder = "{name}" // A file name, or part of one, without '/'
+	dirPlaceholder  = "{dir}/" // Any directory path, possibly none
+)
+
+// Rule pairs source files with companion files, such as their tests. Patterns
+// are slash-separated paths relative to the root in which "{name}" stands for
+// a part of a file name and "{dir}/" for any directory path; the companion
+// uses the values the source matched. A pattern without '/' applies within a
+// directory, so "{name}.go" pairs with "{name}_test.go" next to it.
+type Rule struct {
+	Source    string `json:"source"`    // e.g. "src/{dir}/{name}.ts"
+	Companion string `json:"companion"` // e.g. "test/{dir}/{name}.test.ts"
+}
+
+// scriptExtensions are the TS/JS extensions paired with .test and .spec files
+var scriptExtensions = []string{"ts", "tsx", "js", "jsx", "mjs", "cjs"}
+
+// DefaultRules returns the built-in conventions: Go tests, TS/JS .test and
+// .spec files, and Python test_*.py and *_test.py files
+func DefaultRules() []Rule {
+	rules := []Rule{{Source: "{name}.go", Companion: "{name}_test.go"}}
+	for _, ext := range scriptExtensions {
+		rules = append(rules,
+			Rule{Source: "{name}." + ext, Companion: "{name}.test." + ext},
+			Rule{Source: "{name}." + ext, Companion: "{name}.spec." + ext})
+	}
+	return append(rules,
+		Rule{Source: "{name}.py", Companion: "test_{name}.py"},
+		Rule{Source: "{name}.py", Companion: "{name}_test.py"})
+}
+
+// pattern is a compiled rule pattern
+type pattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+// compiledRule is a rule whose patterns can match and expand paths
+type compiledRule struct {
+	source, companion pattern
+}
+
+// Matcher finds the companions of files, and the files companions belong to
+type Matcher struct {
+	rules []compiledRule
+}
+
+// NewMatcher compiles rules, returning an error for a pattern without "{name}",
+// with "{dir}" not followed by '/', or with placeholders the other side lacks
+func NewMatcher(rules []Rule) (*Matcher, error) {
+	m := &Matcher{}
+	for _, rule := range rules {
+		source, err := compile(rule.Source)
+		if err != nil {
+			return nil, err
+		}
+		companion, err := compile(rule.Companion)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(source.text, dirPlaceholder) != strings.Contains(companion.text, dirPlaceholder) {
+			return nil, fmt.Errorf("companion rule %q -> %q: both patterns must use {dir}/, or neither", rule.Source, rule.Companion)
+		}
+		m.rules = append(m.rules, compiledRule{source: source, companion: companion})
+	}
+	return m, nil
+}
+
+// compile turns a pattern into a regular expression with "name" and "dir" groups
+func compile(text string) (pattern, error) {
+	if !strings.Contains(text, "/") {
+		text = dirPlaceholder + text
+	}
+	if strings.Count(text, namePlaceholder) != 1 || strings.Count(text, "{dir}") != strings.Count(text, dirPlaceholder) ||
+		strings.Count(text, dirPlaceholder) > 1 {
+		return pattern{}, fmt.Errorf("invalid companion pattern %q: it needs one {name} and at most one {dir}/", text)
+	}
+
+	expr := regexp.QuoteMeta(text)
+	expr = strings.Replace(expr, regexp.QuoteMeta(dirPlaceholder), `(?:(?P<dir>.+)/)?`, 1)
+	expr = strings.Replace(expr, regexp.QuoteMeta(namePlaceholder), `(?P<name>[^/]+)`, 1)
+	return pattern{text: text, re: regexp.MustCompile("^" + expr + "$")}, nil
+}
+
+// expand fills in the placeholders of the pattern with the groups of match
+func (p pattern) expand(re *regexp.Regexp, match []string) string {
+	dir := match[re.SubexpIndex("dir")]
+	if dir != "" {
+		dir += "/"
+	}
+	return strings.Replace(strings.Replace(p.text, dirPlaceholder, dir, 1), namePlaceholder, match[re.SubexpIndex("name")], 1)
+}
+
+// Companions returns the paths the companions of the file at path would have, whether or not they exist
+func (m *Matcher) Companions(path string) []string {
+	var paths []string
+	for _, rule := range m.rules {
+		if match := rule.source.re.FindStringSubmatch(path); match != nil {
+			paths = append(paths, rule.companion.expand(rule.source.re, match))
+		}
+	}
+	return unique(paths, path)
+}
+
+// Sources returns the paths of the files the file at path would be a companion of, whether or not they exist
+func (m *Matcher) Sources(path string) []string {
+	var paths []string
+	for _, rule := range m.rules {
+		if match := rule.companion.re.FindStringSubmatch(path); match != nil {
+			paths = append(paths, rule.source.expand(rule.companion.re, match))
+		}
+	}
+	return unique(paths, path)
+}
+
+// Follow returns the existing companions of the files at paths that are not among paths
+func (m *Matcher) Follow(paths []string, exists func(path string) bool) []string {
+	given := toSet(paths)
+	var found []string
+	for _, path := range paths {
+		for _, companion := range m.Companions(path) {
+			if !given[companion] && exists(companion) {
+				found = append(found, companion)
+			}
+		}
+	}
+	return unique(found, "")
+}
+
+// Pair returns what pairing the selected files with their companions changes:
+// the existing companions and sources of the selected files that are not
+// selected yet. When every pair is complete already, it returns the selected
+// companions of selected files to remove instead, so pairing twice undoes it.
+func (m *Matcher) Pair(selected []string, exists func(path string) bool) (add, remove []string) {
+	chosen := toSet(selected)
+	for _, path := range selected {
+		for _, other := range append(m.Companions(path), m.Sources(path)...) {
+			if !chosen[other] && exists(other) {
+				add = append(add, other)
+			}
+		}
+	}
+	if len(add) > 0 {
+		return unique(add, ""), nil
+	}
+
+	for _, path := range selected {
+		for _, source := range m.Sources(path) {
+			if chosen[source] {
+				remove = append(remove, path)
+				break
+			}
+		}
+	}
+	return nil, unique(remove, "")
+}
+
+// unique returns paths sorted, without duplicates and without skip
+func unique(paths []string, skip string) []string {
+	seen := map[string]bool{skip: true}
+	var out []string
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func toSet(paths []string) map[string]bool {
+	set := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		set[path] = true
+	}
+	return set
+}
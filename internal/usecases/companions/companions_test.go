@@ -0,0 +1,117 @@
+package companions
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Why test Matcher?
+//
+// Reviews need an implementation file and its tests side by side. A rule that
+// maps foo.test.ts to foo.test.test.ts, or a test back to the wrong source,
+// silently pads or shrinks the payload, and pairing twice must undo itself so
+// the one-key toggle can be trusted.
+
+func newDefaultMatcher(t *testing.T) *Matcher {
+	t.Helper()
+	m, err := NewMatcher(DefaultRules())
+	if err != nil {
+		t.Fatalf("NewMatcher(DefaultRules()): %v", err)
+	}
+	return m
+}
+
+// existsIn reports whether a path is one of files
+func existsIn(files ...string) func(string) bool {
+	set := toSet(files)
+	return func(path string) bool { return set[path] }
+}
+
+// The built-in conventions map each language's sources to its test files and back.
+func TestDefaultRules(t *testing.T) {
+	m := newDefaultMatcher(t)
+
+	companions := map[string][]string{
+		"internal/selector/file_selector.go": {"internal/selector/file_selector_test.go"},
+		"web/app.ts":                         {"web/app.spec.ts", "web/app.test.ts"},
+		"pkg/util.py":                        {"pkg/test_util.py", "pkg/util_test.py"},
+		"README.md":                          nil,
+	}
+	for path, want := range companions {
+		if got := m.Companions(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("Companions(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	sources := map[string][]string{
+		"internal/selector/file_selector_test.go": {"internal/selector/file_selector.go"},
+		"web/app.spec.ts":                         {"web/app.ts"},
+		"pkg/test_util.py":                        {"pkg/util.py"},
+		"main.go":                                 nil,
+	}
+	for path, want := range sources {
+		if got := m.Sources(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("Sources(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// Configured patterns can pair files across directories, with or without a {dir}/ part.
+func TestNewMatcher_DirectoryPatterns(t *testing.T) {
+	m, err := NewMatcher([]Rule{{Source: "src/{dir}/{name}.ts", Companion: "test/{dir}/{name}.test.ts"}})
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	if got, want := m.Companions("src/ui/button.ts"), []string{"test/ui/button.test.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Companions(nested) = %v, want %v", got, want)
+	}
+	if got, want := m.Companions("src/main.ts"), []string{"test/main.test.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Companions(top level) = %v, want %v", got, want)
+	}
+	if got, want := m.Sources("test/ui/button.test.ts"), []string{"src/ui/button.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources = %v, want %v", got, want)
+	}
+	if got := m.Companions("lib/main.ts"); got != nil {
+		t.Errorf("Companions outside src/ = %v, want none", got)
+	}
+}
+
+// Patterns that cannot be expanded back and forth are rejected up front.
+func TestNewMatcher_InvalidPatterns(t *testing.T) {
+	for _, rule := range []Rule{
+		{Source: "*.go", Companion: "*_test.go"},
+		{Source: "{name}/{name}.go", Companion: "{name}_test.go"},
+		{Source: "src/{dir}{name}.ts", Companion: "test/{dir}/{name}.ts"},
+		{Source: "src/{dir}/{name}.ts", Companion: "test/{name}.ts"},
+	} {
+		if _, err := NewMatcher([]Rule{rule}); err == nil {
+			t.Errorf("NewMatcher(%+v) succeeded, want an error", rule)
+		}
+	}
+}
+
+// Pairing adds missing companions and sources; once every pair is complete, it removes the companions.
+func TestPair(t *testing.T) {
+	m := newDefaultMatcher(t)
+	exists := existsIn("a.go", "a_test.go", "b.go", "b_test.go", "c.go")
+
+	add, remove := m.Pair([]string{"a.go", "b_test.go", "c.go"}, exists)
+	if want := []string{"a_test.go", "b.go"}; !reflect.DeepEqual(add, want) || remove != nil {
+		t.Fatalf("Pair = %v, %v; want %v, nil", add, remove, want)
+	}
+
+	add, remove = m.Pair([]string{"a.go", "a_test.go", "b.go", "b_test.go", "c.go"}, exists)
+	if want := []string{"a_test.go", "b_test.go"}; add != nil || !reflect.DeepEqual(remove, want) {
+		t.Fatalf("Pair of complete pairs = %v, %v; want nil, %v", add, remove, want)
+	}
+}
+
+// Follow only returns companions that exist and were not given.
+func TestFollow(t *testing.T) {
+	m := newDefaultMatcher(t)
+	got := m.Follow([]string{"a.go", "b.go", "b_test.go"}, existsIn("a_test.go", "b_test.go"))
+	if want := []string{"a_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Follow = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package copier
+
+import "github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+
+// Classifier turns files into the text that is copied
+type Classifier interface {
+	// ReadText returns the file at path decoded to UTF-8, or a stub for a binary or oversized file,
+	// with its class, without loading binary and oversized files into memory
+	ReadText(path string) (string, classify.Class, error)
+}
+
+// SetClassifier sets the classifier applied to every copied file; nil copies contents as they are
+func (fc *FileCopier) SetClassifier(classifier Classifier) {
+	fc.classifier = classifier
+}
+
+// readText returns the text copied for the file at path and whether it is a
+// stub standing in for a binary or oversized file. Without a classifier the
+// content is copied as it is.
+func (fc *FileCopier) readText(path string) (string, bool, error) {
+	if fc.classifier == nil {
+		content, err := fc.repo.ReadFile(path)
+		return string(content), false, err
+	}
+	text, class, err := fc.classifier.ReadText(path)
+	return text, class.Kind != classify.Text, err
+}
@@ -0,0 +1,58 @@
+package copier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Content modes accepted by NewContentMode
+const (
+	ContentFull     = "full"      // The full content of each file
+	ContentDiff     = "diff"      // Only the diff of each changed file
+	ContentFullDiff = "full+diff" // The full content followed by the diff
+	ContentOutline  = "outline"   // The declarations and signatures of Go files, with function bodies left out; other files in full
+)
+
+// DefaultContentMode is the content mode used when none is configured
+const DefaultContentMode = ContentFull
+
+// ContentModeNames returns the names of all content modes in display order
+func ContentModeNames() []string {
+	return []string{ContentFull, ContentDiff, ContentFullDiff, ContentOutline}
+}
+
+// AvailableContentModes returns the content modes in display order, leaving
+// out the ones that need diffs unless withDiff is set
+func AvailableContentModes(withDiff bool) []string {
+	var modes []string
+	for _, mode := range ContentModeNames() {
+		if withDiff || !NeedsDiff(mode) {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// NeedsDiff reports whether mode copies diffs and therefore needs a DiffSource
+func NeedsDiff(mode string) bool {
+	return mode == ContentDiff || mode == ContentFullDiff
+}
+
+// ValidateContentMode returns an error when mode is not a content mode.
+// An empty mode is accepted and means DefaultContentMode.
+func ValidateContentMode(mode string) error {
+	switch mode {
+	case "", ContentFull, ContentDiff, ContentFullDiff, ContentOutline:
+		return nil
+	}
+	return fmt.Errorf("unknown content mode %q (available: %s)", mode, strings.Join(ContentModeNames(), ", "))
+}
+
+// DiffSource produces the unified diff of a file against a version control ref
+type DiffSource interface {
+	// Diff returns the diff of the root-relative path, empty when the file is unchanged
+	Diff(path string) (string, error)
+
+	// Ref returns the ref diffs are computed against
+	Ref() string
+}
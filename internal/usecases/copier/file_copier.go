@@ -1,51 +1,313 @@
 package copier
 
 import (
-	"strings"
+	"fmt"
+	"path/filepath"
+	"sort"
 
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
 	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
 )
 
-// FileCopier handles copying selected files to clipboard
+// FileCopier handles copying selected files to an output sink
 type FileCopier struct {
-	repo repositories.FileRepository
+	repo       repositories.FileRepository
+	sink       repositories.OutputSink // Destination of the payload; nil means the repository clipboard
+	formatter  Formatter
+	formatters []Formatter // Formatters available for cycling, in display order
+	tokenizer  tokens.Tokenizer
+	budget     tokens.Budget
+	content    string     // Content mode (see ContentModeNames)
+	differ     DiffSource // Source of diffs for the diff content modes
+	redactor   Redactor   // Hides secrets before anything is formatted; nil copies verbatim
+	classifier Classifier // Decodes text and stubs out binary and oversized files; nil copies bytes as they are
 }
 
-// NewFileCopier creates a new FileCopier
+// NewFileCopier creates a new FileCopier using the default output format
 func NewFileCopier(repo repositories.FileRepository) *FileCopier {
 	return &FileCopier{
-		repo: repo,
+		repo:       repo,
+		formatter:  PlainFormatter{},
+		formatters: builtinFormatters(),
+		tokenizer:  tokens.NewApproxTokenizer(),
+		content:    DefaultContentMode,
 	}
 }
 
-// CopySelectionToClipboard copies all selected files to clipboard
-func (fc *FileCopier) CopySelectionToClipboard(selection map[string]*entities.FileNode) error {
+// SetDiffSource sets where diffs come from; without one the diff content modes are unavailable
+func (fc *FileCopier) SetDiffSource(differ DiffSource) {
+	fc.differ = differ
+}
+
+// DiffRef returns the ref diffs are computed against, or "" without a diff source
+func (fc *FileCopier) DiffRef() string {
+	if fc.differ == nil {
+		return ""
+	}
+	return fc.differ.Ref()
+}
+
+// SetContentMode changes what is emitted for each file (see ContentModeNames).
+// An empty mode selects DefaultContentMode.
+func (fc *FileCopier) SetContentMode(mode string) error {
+	if err := ValidateContentMode(mode); err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = DefaultContentMode
+	}
+	if NeedsDiff(mode) && fc.differ == nil {
+		return fmt.Errorf("content mode %q requires a git repository", mode)
+	}
+	fc.content = mode
+	return nil
+}
+
+// ContentMode returns what is emitted for each file
+func (fc *FileCopier) ContentMode() string {
+	return fc.content
+}
+
+// CycleContentMode switches to the next content mode and returns it.
+// Without a diff source the diff content modes are skipped.
+func (fc *FileCopier) CycleContentMode() string {
+	names := AvailableContentModes(fc.differ != nil)
+	for i, name := range names {
+		if name == fc.content {
+			fc.content = names[(i+1)%len(names)]
+			break
+		}
+	}
+	return fc.content
+}
+
+// SetBudget limits the number of tokens in the copied payload, counted with tokenizer
+func (fc *FileCopier) SetBudget(tokenizer tokens.Tokenizer, budget tokens.Budget) {
+	fc.tokenizer = tokenizer
+	fc.budget = budget
+}
+
+// SetSink changes the destination of the copied payload
+func (fc *FileCopier) SetSink(sink repositories.OutputSink) {
+	fc.sink = sink
+}
+
+// SinkName returns a short description of the destination of the copied payload
+func (fc *FileCopier) SinkName() string {
+	if fc.sink == nil {
+		return "clipboard"
+	}
+	return fc.sink.Name()
+}
+
+// Budget returns the token budget of the copied payload
+func (fc *FileCopier) Budget() tokens.Budget {
+	return fc.budget
+}
+
+// SetFormatter changes the formatter used to render the selection.
+// Formatters that are not yet available for cycling are added to the list.
+func (fc *FileCopier) SetFormatter(formatter Formatter) {
+	fc.AddFormatter(formatter)
+	fc.formatter = formatter
+}
+
+// AddFormatter makes a formatter available for cycling, replacing any formatter with the same name
+func (fc *FileCopier) AddFormatter(formatter Formatter) {
+	for i, f := range fc.formatters {
+		if f.Name() == formatter.Name() {
+			fc.formatters[i] = formatter
+			return
+		}
+	}
+	fc.formatters = append(fc.formatters, formatter)
+}
+
+// Formatters returns the formatters available for cycling
+func (fc *FileCopier) Formatters() []Formatter {
+	return fc.formatters
+}
+
+// CycleFormatter switches to the next available formatter and returns it
+func (fc *FileCopier) CycleFormatter() Formatter {
+	next := fc.formatters[0]
+	for i, f := range fc.formatters {
+		if f.Name() == fc.formatter.Name() {
+			next = fc.formatters[(i+1)%len(fc.formatters)]
+			break
+		}
+	}
+	fc.formatter = next
+	return next
+}
+
+// Formatter returns the formatter used to render the selection
+func (fc *FileCopier) Formatter() Formatter {
+	return fc.formatter
+}
+
+// FormatSelection renders all selected files with the current formatter.
+// Files are ordered by path so the output is deterministic.
+func (fc *FileCopier) FormatSelection(selection map[string]*entities.FileNode) (string, error) {
+	result, err := fc.RenderSelection(selection)
+	return result.Payload, err
+}
+
+// RenderSelection renders all selected files with the current formatter and
+// reports the files copied and the files that had to be left out, with the
+// reason. Files selected by line ranges are rendered as one document per
+// range. In ContentOutline, other Go files are rendered as outlines (see
+// NewContentDocuments). In the diff content modes each file also carries its
+// diff; unchanged files are left out of ContentDiff without being reported,
+// and files deleted from disk are rendered from their deletion diff. Deleted
+// files selected outside the tree are left out of the other modes without
+// being reported, as they have no content to copy. When a
+// classifier is set, text is decoded to UTF-8 and binary and oversized files
+// are rendered as a stub, whatever their line ranges. Secrets are replaced by
+// placeholders when a redactor is set, in the whole file before its line
+// ranges are sliced, so the reported count covers whole files.
+func (fc *FileCopier) RenderSelection(selection map[string]*entities.FileNode) (CopyResult, error) {
 	currentDir, err := fc.repo.GetCurrentDirectory()
 	if err != nil {
-		return err
+		return CopyResult{}, err
 	}
 
-	var builder strings.Builder
+	nodes := make([]*entities.FileNode, 0, len(selection))
 	for _, node := range selection {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Path < nodes[j].Path })
+
+	var docs []Document
+	var skipped []SkippedFile
+	redacted := 0
+	for _, node := range nodes {
+		if node.Deleted && !NeedsDiff(fc.content) {
+			continue
+		}
+
 		// Get path relative to current directory
 		relativePath, err := fc.repo.GetRelativePath(node.Path, currentDir)
 		if err != nil {
+			skipped = append(skipped, SkippedFile{Path: node.Path, Reason: err.Error()})
 			continue
 		}
+		skip := func(err error) {
+			skipped = append(skipped, SkippedFile{Path: filepath.ToSlash(relativePath), Reason: err.Error()})
+		}
 
 		// Read file content
-		content, err := fc.repo.ReadFile(node.Path)
+		content, stub, readErr := fc.readText(node.Path)
+		ranges := node.Ranges
+		if stub {
+			ranges = nil
+		} else if readErr == nil && fc.content != ContentDiff {
+			var n int
+			content, n = fc.redact(filepath.ToSlash(relativePath), content)
+			redacted += n
+		}
+		if !NeedsDiff(fc.content) {
+			if readErr != nil {
+				skip(readErr)
+				continue
+			}
+			if stub {
+				doc := NewDocument(relativePath, content)
+				doc.Stub = true
+				docs = append(docs, doc)
+				continue
+			}
+			fileDocs, err := NewContentDocuments(fc.content, relativePath, content, ranges)
+			if err != nil {
+				skip(err)
+				continue
+			}
+			docs = append(docs, fileDocs...)
+			continue
+		}
+
+		diff, err := fc.differ.Diff(filepath.ToSlash(relativePath))
+		if err != nil {
+			skip(err)
+			continue
+		}
+		if readErr != nil && diff == "" {
+			skip(readErr)
+			continue
+		}
+		if fc.content == ContentDiff && diff == "" {
+			continue
+		}
+		var n int
+		diff, n = fc.redact(filepath.ToSlash(relativePath), diff)
+		redacted += n
+
+		// Deleted files, and files in ContentDiff, only have a diff
+		if fc.content == ContentDiff || readErr != nil {
+			doc := NewDocument(relativePath, content)
+			doc.Diff, doc.DiffOnly, doc.Stub = diff, true, stub
+			docs = append(docs, doc)
+			continue
+		}
+
+		// The diff follows the last line range of the file
+		fileDocs, err := NewDocuments(relativePath, content, ranges)
 		if err != nil {
+			skip(err)
 			continue
 		}
+		fileDocs[len(fileDocs)-1].Diff = diff
+		for i := range fileDocs {
+			fileDocs[i].Stub = stub
+		}
+		docs = append(docs, fileDocs...)
+	}
+
+	payload, err := fc.formatter.Format(docs)
+	if err != nil {
+		return CopyResult{Skipped: skipped}, err
+	}
+	result := NewCopyResult(payload, docs, skipped, fc.tokenizer)
+	result.Redacted = redacted
+	return result, nil
+}
+
+// CheckBudget returns an error wrapping tokens.ErrBudgetExceeded when payload
+// is over a refusing token budget
+func (fc *FileCopier) CheckBudget(payload string) error {
+	if fc.budget.Max == 0 {
+		return nil
+	}
+	return fc.budget.Check(fc.tokenizer.Count(payload))
+}
+
+// WritePayload delivers an already rendered payload to the configured sink
+func (fc *FileCopier) WritePayload(payload string) error {
+	if fc.sink == nil {
+		return fc.repo.WriteToClipboard(payload)
+	}
+	return fc.sink.Write(payload)
+}
+
+// CopySelectionToClipboard copies all selected files to the configured sink,
+// the clipboard by default, and returns what was copied and skipped. It
+// returns an error wrapping tokens.ErrBudgetExceeded, without copying, when
+// the payload is over a refusing token budget, or the error of CheckSensitive
+// when sensitive files are refused.
+func (fc *FileCopier) CopySelectionToClipboard(selection map[string]*entities.FileNode) (CopyResult, error) {
+	if _, err := fc.CheckSensitive(selection); err != nil {
+		return CopyResult{}, err
+	}
+
+	result, err := fc.RenderSelection(selection)
+	if err != nil {
+		return result, err
+	}
 
-		// Add to clipboard content
-		builder.WriteString("★★ The contents of " + relativePath + " is below.\n")
-		builder.Write(content)
-		builder.WriteString("\n\n")
+	if err := fc.CheckBudget(result.Payload); err != nil {
+		return result, err
 	}
 
-	// Write to clipboard
-	return fc.repo.WriteToClipboard(builder.String())
+	return result, fc.WritePayload(result.Payload)
 }
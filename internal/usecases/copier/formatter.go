@@ -0,0 +1,322 @@
+package copier
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/outline"
+)
+
+// Built-in format names accepted by NewFormatter
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatXML      = "xml"
+	FormatJSON     = "json"
+	FormatTemplate = "template"
+)
+
+// DefaultFormat is the format used when none is configured
+const DefaultFormat = FormatPlain
+
+// Document is a single selected file prepared for output
+type Document struct {
+	Path     string // Path relative to the current directory, with forward slashes
+	Name     string // Base name of the file
+	Dir      string // Directory part of Path ("." for top-level files)
+	Language string // Fence language tag derived from the file name (may be empty)
+	Size     int    // Size of Content in bytes
+	Lines    int    // Number of lines in Content
+	Content  string // Content of the file
+	Range    string // Lines of the file in Content, e.g. "120-180"; empty when Content is the whole file
+	Outline  bool   // Content is the outline of a Go file, with function bodies left out
+	Diff     string // Unified diff against the diff ref; empty when not requested or unchanged
+	DiffOnly bool   // Only the diff was requested; Content must not be rendered
+	Stub     bool   // Content is the stub of a binary or oversized file
+}
+
+// NewDocument creates a Document for the file at path, deriving its metadata from the content
+func NewDocument(path, content string) Document {
+	path = filepath.ToSlash(path)
+	return Document{
+		Path:     path,
+		Name:     filepath.Base(path),
+		Dir:      filepath.ToSlash(filepath.Dir(path)),
+		Language: Language(path),
+		Size:     len(content),
+		Lines:    countLines(content),
+		Content:  content,
+	}
+}
+
+// NewDocuments creates the Documents for the file at path: one for the whole
+// content, or one per line range. It returns an error when every range lies
+// past the end of the file.
+func NewDocuments(path, content string, ranges []entities.LineRange) ([]Document, error) {
+	if len(ranges) == 0 {
+		return []Document{NewDocument(path, content)}, nil
+	}
+
+	sections := linerange.Extract(content, ranges)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("%s: the file has only %d lines", linesLabel(linerange.Format(ranges)), countLines(content))
+	}
+	docs := make([]Document, len(sections))
+	for i, section := range sections {
+		docs[i] = NewDocument(path, section.Content)
+		docs[i].Range = section.Range.String()
+	}
+	return docs, nil
+}
+
+// NewContentDocuments creates the Documents for the file at path in a content
+// mode without diffs. In ContentOutline a Go file selected as a whole becomes
+// the outline of its declarations; files with line ranges, other files, and Go
+// files that do not parse are rendered as by NewDocuments.
+func NewContentDocuments(mode, path, content string, ranges []entities.LineRange) ([]Document, error) {
+	if mode == ContentOutline && len(ranges) == 0 && outline.Supported(path) {
+		if text, err := outline.Go(path, []byte(content)); err == nil {
+			doc := NewDocument(path, text)
+			doc.Outline = true
+			return []Document{doc}, nil
+		}
+	}
+	return NewDocuments(path, content, ranges)
+}
+
+// title returns the path of the document, followed by its line range or "outline"
+func (d Document) title() string {
+	switch {
+	case d.Outline:
+		return d.Path + " (outline)"
+	case d.Range != "":
+		return d.Path + " (" + linesLabel(d.Range) + ")"
+	}
+	return d.Path
+}
+
+// linesLabel returns "line 7" for a single line and "lines 120-180" otherwise
+func linesLabel(ranges string) string {
+	if strings.ContainsAny(ranges, "-,") {
+		return "lines " + ranges
+	}
+	return "line " + ranges
+}
+
+// Formatter renders selected documents into the payload that is copied
+type Formatter interface {
+	// Name returns the identifier used to select the formatter
+	Name() string
+
+	// Format renders the documents into a single string
+	Format(docs []Document) (string, error)
+}
+
+// FormatNames returns the names of all built-in formats in display order
+func FormatNames() []string {
+	return []string{FormatPlain, FormatMarkdown, FormatXML, FormatJSON}
+}
+
+// NewFormatter returns the built-in formatter registered under name.
+// An empty name selects DefaultFormat.
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "", FormatPlain:
+		return PlainFormatter{}, nil
+	case FormatMarkdown:
+		return MarkdownFormatter{}, nil
+	case FormatXML:
+		return XMLFormatter{}, nil
+	case FormatJSON:
+		return JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(FormatNames(), ", "))
+}
+
+// builtinFormatters returns one instance of every built-in formatter in display order
+func builtinFormatters() []Formatter {
+	var formatters []Formatter
+	for _, name := range FormatNames() {
+		formatter, _ := NewFormatter(name)
+		formatters = append(formatters, formatter)
+	}
+	return formatters
+}
+
+// PlainFormatter renders each file under a "★★ The contents of X is below." header
+type PlainFormatter struct{}
+
+// Name returns the identifier of the formatter
+func (PlainFormatter) Name() string { return FormatPlain }
+
+// Format renders the documents with star headers
+func (PlainFormatter) Format(docs []Document) (string, error) {
+	var builder strings.Builder
+	for _, doc := range docs {
+		if !doc.DiffOnly {
+			builder.WriteString("★★ The contents of " + doc.title() + " is below.\n")
+			builder.WriteString(doc.Content)
+			builder.WriteString("\n\n")
+		}
+		if doc.Diff != "" {
+			builder.WriteString("★★ The diff of " + doc.Path + " is below.\n")
+			builder.WriteString(doc.Diff)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String(), nil
+}
+
+// MarkdownFormatter renders each file as a fenced code block tagged with its language
+type MarkdownFormatter struct{}
+
+// Name returns the identifier of the formatter
+func (MarkdownFormatter) Name() string { return FormatMarkdown }
+
+// Format renders the documents as Markdown sections
+func (MarkdownFormatter) Format(docs []Document) (string, error) {
+	var builder strings.Builder
+	for _, doc := range docs {
+		builder.WriteString("### " + doc.title() + "\n\n")
+		if !doc.DiffOnly {
+			writeFenced(&builder, Language(doc.Path), doc.Content)
+		}
+		if doc.Diff != "" {
+			writeFenced(&builder, "diff", doc.Diff)
+		}
+	}
+	return builder.String(), nil
+}
+
+// writeFenced writes content as a fenced code block tagged with language
+func writeFenced(builder *strings.Builder, language, content string) {
+	// The fence must be longer than any backtick run inside the content
+	fence := strings.Repeat("`", max(3, longestRun(content, '`')+1))
+
+	builder.WriteString(fence + language + "\n")
+	builder.WriteString(content)
+	if !strings.HasSuffix(content, "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString(fence + "\n\n")
+}
+
+// XMLFormatter renders each file as a <document path="..."> element
+type XMLFormatter struct{}
+
+// Name returns the identifier of the formatter
+func (XMLFormatter) Name() string { return FormatXML }
+
+// Format renders the documents inside a <documents> root element, followed by
+// a <diff path="..."> element for each document with a diff. Documents holding
+// a line range carry it in a lines="120-180" attribute, and outlines are
+// marked with outline="true".
+// Contents are wrapped in CDATA so source code stays readable and the output stays well-formed.
+func (XMLFormatter) Format(docs []Document) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("<documents>\n")
+	for _, doc := range docs {
+		if !doc.DiffOnly {
+			if err := writeCDATAElement(&builder, "document", doc.Path, documentAttributes(doc), doc.Content); err != nil {
+				return "", err
+			}
+		}
+		if doc.Diff != "" {
+			if err := writeCDATAElement(&builder, "diff", doc.Path, "", doc.Diff); err != nil {
+				return "", err
+			}
+		}
+	}
+	builder.WriteString("</documents>\n")
+	return builder.String(), nil
+}
+
+// documentAttributes returns the attributes describing a partial document, e.g. ` lines="120-180"`
+func documentAttributes(doc Document) string {
+	switch {
+	case doc.Outline:
+		return ` outline="true"`
+	case doc.Range != "":
+		return ` lines="` + doc.Range + `"`
+	}
+	return ""
+}
+
+// writeCDATAElement writes <tag path="path"> with the extra attributes and
+// content wrapped in CDATA
+func writeCDATAElement(builder *strings.Builder, tag, path, attributes, content string) error {
+	builder.WriteString("<" + tag + ` path="`)
+	if err := xml.EscapeText(builder, []byte(path)); err != nil {
+		return err
+	}
+	builder.WriteString(`"` + attributes + "><![CDATA[\n")
+	builder.WriteString(strings.ReplaceAll(content, "]]>", "]]]]><![CDATA[>"))
+	if !strings.HasSuffix(content, "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString("]]></" + tag + ">\n")
+	return nil
+}
+
+// JSONFormatter renders the files as a JSON array of {path, lines, outline, content, diff} objects
+type JSONFormatter struct{}
+
+// Name returns the identifier of the formatter
+func (JSONFormatter) Name() string { return FormatJSON }
+
+// Format renders the documents as an indented JSON array
+func (JSONFormatter) Format(docs []Document) (string, error) {
+	type jsonDocument struct {
+		Path    string  `json:"path"`
+		Lines   string  `json:"lines,omitempty"`   // Line range of a partial file
+		Outline bool    `json:"outline,omitempty"` // Content is the outline of a Go file
+		Content *string `json:"content,omitempty"` // Left out when only the diff was requested
+		Diff    string  `json:"diff,omitempty"`
+	}
+
+	out := make([]jsonDocument, 0, len(docs))
+	for _, doc := range docs {
+		entry := jsonDocument{Path: doc.Path, Lines: doc.Range, Outline: doc.Outline, Diff: doc.Diff}
+		if !doc.DiffOnly {
+			entry.Content = &doc.Content
+		}
+		out = append(out, entry)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// countLines returns the number of lines in s, counting a final unterminated line
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	lines := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines++
+	}
+	return lines
+}
+
+// longestRun returns the length of the longest run of r in s
+func longestRun(s string, r rune) int {
+	longest, current := 0, 0
+	for _, c := range s {
+		if c == r {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
@@ -0,0 +1,202 @@
+package copier
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test the formatters?
+//
+// Each formatter is a contract with a different consumer: Markdown renderers,
+// XML-aware prompt builders, and JSON parsers. A fence that is too short, an
+// unescaped attribute, or invalid JSON silently corrupts the pasted payload.
+// These tests pin down the exact shape of each built-in format.
+
+// Markdown output must tag the fence with the file's language so that
+// renderers and LLMs highlight it correctly.
+func TestMarkdownFormatter_LanguageTag(t *testing.T) {
+	out, err := MarkdownFormatter{}.Format([]Document{{Path: "src/lib.go", Content: "package lib\n"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "### src/lib.go\n\n```go\npackage lib\n```\n\n"
+	if out != expected {
+		t.Fatalf("markdown mismatch.\nwant: %q\ngot:  %q", expected, out)
+	}
+}
+
+// A file that itself contains a ``` fence (e.g. a README) would close the
+// block early. The fence must grow to stay longer than any backtick run.
+func TestMarkdownFormatter_FenceLongerThanContent(t *testing.T) {
+	out, err := MarkdownFormatter{}.Format([]Document{{Path: "README.md", Content: "```sh\nls\n```"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out, "````markdown\n") || !strings.HasSuffix(out, "\n````\n\n") {
+		t.Fatalf("fence should be four backticks, got %q", out)
+	}
+}
+
+// XML output must stay well-formed even when the path or content contains
+// markup characters, otherwise XML-aware tools reject the whole payload.
+func TestXMLFormatter_WellFormed(t *testing.T) {
+	out, err := XMLFormatter{}.Format([]Document{
+		{Path: `a&"b".go`, Content: "if a < b && c > d {}\n// ]]> inside"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Documents []struct {
+			Path    string `xml:"path,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"document"`
+	}
+	if err := xml.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, out)
+	}
+	if len(parsed.Documents) != 1 || parsed.Documents[0].Path != `a&"b".go` {
+		t.Fatalf("unexpected documents: %+v", parsed.Documents)
+	}
+	if !strings.Contains(parsed.Documents[0].Content, "// ]]> inside") {
+		t.Fatalf("content should survive CDATA splitting, got %q", parsed.Documents[0].Content)
+	}
+}
+
+// JSON output must round-trip to the same paths and contents.
+func TestJSONFormatter_RoundTrip(t *testing.T) {
+	docs := []Document{
+		{Path: "a.go", Content: "package a"},
+		{Path: "b.txt", Content: "line \"quoted\"\n"},
+	}
+	out, err := JSONFormatter{}.Format(docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed []Document
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(parsed) != 2 || parsed[0] != docs[0] || parsed[1] != docs[1] {
+		t.Fatalf("round trip mismatch: %+v", parsed)
+	}
+}
+
+// Unknown names must be rejected so a typo in --format fails loudly instead
+// of silently falling back to another format.
+func TestNewFormatter_UnknownName(t *testing.T) {
+	if _, err := NewFormatter("yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	for _, name := range FormatNames() {
+		f, err := NewFormatter(name)
+		if err != nil || f.Name() != name {
+			t.Fatalf("built-in format %q should be available, got %v", name, err)
+		}
+	}
+}
+
+// The copier must hand files to the configured formatter in path order, so
+// the same selection always produces the same payload.
+func TestCopySelectionToClipboard_UsesFormatterInPathOrder(t *testing.T) {
+	repo := &mockFileRepo{
+		currentDir: "/project",
+		files: map[string][]byte{
+			"/project/b.go": []byte("package b"),
+			"/project/a.go": []byte("package a"),
+		},
+	}
+	cp := NewFileCopier(repo)
+	cp.SetFormatter(JSONFormatter{})
+
+	nodeB := entities.NewFileNode("b.go", "/project/b.go", false, nil)
+	nodeA := entities.NewFileNode("a.go", "/project/a.go", false, nil)
+	selection := map[string]*entities.FileNode{nodeB.Path: nodeB, nodeA.Path: nodeA}
+
+	if _, err := cp.CopySelectionToClipboard(selection); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed []Document
+	if err := json.Unmarshal([]byte(repo.clipboardText), &parsed); err != nil {
+		t.Fatalf("clipboard is not JSON: %v", err)
+	}
+	if len(parsed) != 2 || parsed[0].Path != "a.go" || parsed[1].Path != "b.go" {
+		t.Fatalf("expected a.go then b.go, got %+v", parsed)
+	}
+}
+
+// Diffs get their own "diff" fence in Markdown, and diff-only documents must
+// not render an empty content block.
+func TestMarkdownFormatter_Diff(t *testing.T) {
+	out, err := MarkdownFormatter{}.Format([]Document{
+		{Path: "a.go", Content: "package a\n", Diff: "+package a\n"},
+		{Path: "b.go", Diff: "-package b\n", DiffOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "### a.go\n\n```go\npackage a\n```\n\n```diff\n+package a\n```\n\n" +
+		"### b.go\n\n```diff\n-package b\n```\n\n"
+	if out != expected {
+		t.Fatalf("markdown mismatch.\nwant: %q\ngot:  %q", expected, out)
+	}
+}
+
+// JSON consumers tell a diff-only entry from an empty file by the missing
+// "content" key.
+func TestJSONFormatter_DiffOnlyOmitsContent(t *testing.T) {
+	out, err := JSONFormatter{}.Format([]Document{
+		{Path: "empty.go", Content: ""},
+		{Path: "b.go", Diff: "-package b\n", DiffOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed []map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if _, ok := parsed[0]["content"]; !ok {
+		t.Error("an empty file must keep its content key")
+	}
+	if _, ok := parsed[1]["content"]; ok || parsed[1]["diff"] != "-package b\n" {
+		t.Errorf("diff-only entry should only carry the diff, got %v", parsed[1])
+	}
+}
+
+// Partial files must say which lines they hold in a form each consumer can
+// read: an attribute in XML and a field in JSON.
+func TestFormatters_LineRange(t *testing.T) {
+	docs := []Document{{Path: "a.go", Content: "x\n", Range: "120-180"}}
+
+	out, err := XMLFormatter{}.Format(docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, `<document path="a.go" lines="120-180">`) {
+		t.Fatalf("xml should carry the range, got %q", out)
+	}
+
+	out, err = JSONFormatter{}.Format(docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed []map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if parsed[0]["lines"] != "120-180" {
+		t.Fatalf("json should carry the range, got %v", parsed[0])
+	}
+}
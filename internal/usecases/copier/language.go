@@ -0,0 +1,62 @@
+package copier
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// languagesByExtension maps file extensions to Markdown fence language tags
+var languagesByExtension = map[string]string{
+	".go":    "go",
+	".mod":   "go",
+	".js":    "javascript",
+	".jsx":   "jsx",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".java":  "java",
+	".kt":    "kotlin",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".swift": "swift",
+	".php":   "php",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "zsh",
+	".sql":   "sql",
+	".html":  "html",
+	".css":   "css",
+	".scss":  "scss",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".md":    "markdown",
+	".proto": "protobuf",
+	".tf":    "hcl",
+	".lua":   "lua",
+}
+
+// languagesByName maps well-known file names without a useful extension to language tags
+var languagesByName = map[string]string{
+	"Dockerfile": "dockerfile",
+	"Makefile":   "makefile",
+	"go.sum":     "text",
+}
+
+// Language returns the Markdown fence language tag for a file path,
+// or an empty string when the language is unknown
+func Language(path string) string {
+	base := filepath.Base(path)
+	if lang, ok := languagesByName[base]; ok {
+		return lang
+	}
+	return languagesByExtension[strings.ToLower(filepath.Ext(base))]
+}
@@ -0,0 +1,54 @@
+package copier
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Redactor hides secrets in copied contents and flags files named like credentials
+type Redactor interface {
+	// Redact returns content with its secrets replaced by placeholders, and how many were replaced
+	Redact(path, content string) (string, int)
+
+	// Check returns the sensitive files among the root-relative paths, with an error when they must not be copied
+	Check(paths []string) ([]string, error)
+}
+
+// SetRedactor sets the redactor applied to every copied file; nil copies contents verbatim
+func (fc *FileCopier) SetRedactor(redactor Redactor) {
+	fc.redactor = redactor
+}
+
+// CheckSensitive returns the sorted, slash-separated paths of the selected files
+// named like credentials, and an error when the redactor refuses to copy them
+func (fc *FileCopier) CheckSensitive(selection map[string]*entities.FileNode) ([]string, error) {
+	if fc.redactor == nil {
+		return nil, nil
+	}
+	currentDir, err := fc.repo.GetCurrentDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(selection))
+	for _, node := range selection {
+		if rel, err := fc.repo.GetRelativePath(node.Path, currentDir); err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	sort.Strings(paths)
+	return fc.redactor.Check(paths)
+}
+
+// redact replaces the secrets in text, the whole content or diff of the file at
+// the slash-separated relativePath, and returns it with the number replaced.
+// Whole files are redacted before line ranges slice them, so a range starting
+// inside a secret still hides it. A nil redactor leaves text unchanged.
+func (fc *FileCopier) redact(relativePath, text string) (string, int) {
+	if fc.redactor == nil || text == "" {
+		return text, 0
+	}
+	return fc.redactor.Redact(relativePath, text)
+}
@@ -0,0 +1,86 @@
+package copier
+
+import (
+	"fmt"
+
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/tokens"
+)
+
+// SkippedFile is a selected file that could not be included in the payload
+type SkippedFile struct {
+	Path   string `json:"path"`   // Path relative to the current directory with forward slashes, or the full path when it has none
+	Reason string `json:"reason"` // Why the file was skipped
+}
+
+// CopiedFile is a selected file whose content, or diff, is in the payload
+type CopiedFile struct {
+	Path   string `json:"path"`           // Path relative to the current directory with forward slashes
+	Bytes  int    `json:"bytes"`          // Bytes of its content and diff, before formatting
+	Tokens int    `json:"tokens"`         // Estimated tokens of its content and diff
+	Stub   bool   `json:"stub,omitempty"` // A binary or oversized file copied as a stub
+}
+
+// CopyResult is a rendered selection with what went into it and what was left out
+type CopyResult struct {
+	Payload  string        // Formatted text written to the output sink
+	Copied   []CopiedFile  // Files in the payload, in payload order
+	Skipped  []SkippedFile // Files left out of the payload, with the reason
+	Bytes    int           // Size of the payload in bytes
+	Tokens   int           // Estimated tokens of the payload
+	Redacted int           // Secrets replaced with placeholders
+}
+
+// NewCopyResult describes payload, formatted from docs, counting tokens with tokenizer.
+// Consecutive documents of the same file, such as its line ranges, count as one file.
+func NewCopyResult(payload string, docs []Document, skipped []SkippedFile, tokenizer tokens.Tokenizer) CopyResult {
+	result := CopyResult{
+		Payload: payload,
+		Copied:  []CopiedFile{},
+		Skipped: skipped,
+		Bytes:   len(payload),
+		Tokens:  tokenizer.Count(payload),
+	}
+	if result.Skipped == nil {
+		result.Skipped = []SkippedFile{}
+	}
+	for _, doc := range docs {
+		n := len(result.Copied)
+		if n == 0 || result.Copied[n-1].Path != doc.Path {
+			result.Copied = append(result.Copied, CopiedFile{Path: doc.Path, Stub: doc.Stub})
+			n++
+		}
+		file := &result.Copied[n-1]
+		file.Bytes += len(doc.Content) + len(doc.Diff)
+		file.Tokens += tokenizer.Count(doc.Content) + tokenizer.Count(doc.Diff)
+	}
+	return result
+}
+
+// Summary returns the totals of the result, e.g. "3 files, 12.3 KiB, ~3100 tokens"
+func (r CopyResult) Summary() string {
+	files := "files"
+	if len(r.Copied) == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s, %s, ~%d tokens", len(r.Copied), files, classify.FormatSize(int64(r.Bytes)), r.Tokens)
+}
+
+// RedactedSecrets returns the number of secrets redacted, e.g. "1 secret" or "3 secrets"
+func (r CopyResult) RedactedSecrets() string {
+	if r.Redacted == 1 {
+		return "1 secret"
+	}
+	return fmt.Sprintf("%d secrets", r.Redacted)
+}
+
+// Stubs returns the paths of the files copied as a stub
+func (r CopyResult) Stubs() []string {
+	stubs := []string{}
+	for _, file := range r.Copied {
+		if file.Stub {
+			stubs = append(stubs, file.Path)
+		}
+	}
+	return stubs
+}
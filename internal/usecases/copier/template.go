@@ -0,0 +1,77 @@
+package copier
+
+import (
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the value a user-defined output template is executed with.
+//
+// A template typically renders a preamble, ranges over .Files to render one
+// block per file, and finishes with a footer:
+//
+//	Files: {{.Count}} ({{.TotalLines}} lines)
+//	{{range .Files}}
+//	--- {{.Path}} ({{.Language}}, {{.Size}} bytes, {{.Lines}} lines)
+//	{{.Content}}
+//	{{end}}
+type TemplateData struct {
+	Files      []Document // Selected files in path order, one per line range of a file selected by ranges
+	Count      int        // Number of distinct files, however many line ranges they have
+	TotalSize  int        // Sum of all file sizes in bytes
+	TotalLines int        // Sum of all line counts
+}
+
+// templateFuncs are the helper functions available inside output templates
+var templateFuncs = template.FuncMap{
+	// fence returns a backtick fence longer than any backtick run in the content
+	"fence": func(content string) string {
+		return strings.Repeat("`", max(3, longestRun(content, '`')+1))
+	},
+	// indent prefixes every line of s with n spaces
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+pad)
+	},
+	// trimNewline removes trailing newlines
+	"trimNewline": func(s string) string {
+		return strings.TrimRight(s, "\n")
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// TemplateFormatter renders the selection through a user-defined text/template
+type TemplateFormatter struct {
+	tmpl *template.Template
+}
+
+// NewTemplateFormatter parses text as an output template executed with TemplateData
+func NewTemplateFormatter(text string) (*TemplateFormatter, error) {
+	tmpl, err := template.New(FormatTemplate).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateFormatter{tmpl: tmpl}, nil
+}
+
+// Name returns the identifier of the formatter
+func (f *TemplateFormatter) Name() string { return FormatTemplate }
+
+// Format executes the template with the documents
+func (f *TemplateFormatter) Format(docs []Document) (string, error) {
+	data := TemplateData{Files: docs}
+	paths := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		paths[doc.Path] = true
+		data.TotalSize += doc.Size
+		data.TotalLines += doc.Lines
+	}
+	data.Count = len(paths)
+
+	var builder strings.Builder
+	if err := f.tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,130 @@
+package copier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test TemplateFormatter?
+//
+// Templates are the escape hatch for teams whose tools expect a payload shape
+// we don't ship. The data model (.Files, .Count, per-file .Language, .Lines,
+// ...) is documented and users write templates against it, so renaming or
+// miscomputing a field breaks their files without any compile error.
+
+// A template with a preamble, per-file block, and footer must see every
+// documented field with correct values.
+func TestTemplateFormatter_DataModel(t *testing.T) {
+	f, err := NewTemplateFormatter(
+		"{{.Count}} files, {{.TotalLines}} lines, {{.TotalSize}} bytes\n" +
+			"{{range .Files}}[{{.Path}}|{{.Name}}|{{.Dir}}|{{.Language}}|{{.Size}}|{{.Lines}}]\n{{.Content}}\n{{end}}" +
+			"END",
+	)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	out, err := f.Format([]Document{
+		NewDocument("src/lib.go", "package lib\n\nfunc A() {}\n"),
+		NewDocument("README.md", "# hi"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected execute error: %v", err)
+	}
+
+	expected := "2 files, 4 lines, 29 bytes\n" +
+		"[src/lib.go|lib.go|src|go|25|3]\npackage lib\n\nfunc A() {}\n\n" +
+		"[README.md|README.md|.|markdown|4|1]\n# hi\n" +
+		"END"
+	if out != expected {
+		t.Fatalf("template output mismatch.\nwant: %q\ngot:  %q", expected, out)
+	}
+}
+
+// A file selected by several line ranges is one document per range, but it
+// is still one file in .Count.
+func TestTemplateFormatter_CountsDistinctFiles(t *testing.T) {
+	f, err := NewTemplateFormatter("{{.Count}} files in {{len .Files}} sections")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	docs, err := NewDocuments("app.go", "a\nb\nc\nd\n", []entities.LineRange{{Start: 1, End: 1}, {Start: 3, End: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := f.Format(append(docs, NewDocument("README.md", "# hi")))
+	if err != nil {
+		t.Fatalf("unexpected execute error: %v", err)
+	}
+	if out != "2 files in 3 sections" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+// Helper functions let templates build safe fences and indented blocks
+// without reimplementing them in template syntax.
+func TestTemplateFormatter_Funcs(t *testing.T) {
+	f, err := NewTemplateFormatter("{{range .Files}}{{fence .Content}}\n{{indent 2 .Content}}{{end}}")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	out, err := f.Format([]Document{NewDocument("a.md", "````\nx\n")})
+	if err != nil {
+		t.Fatalf("unexpected execute error: %v", err)
+	}
+	if out != "`````\n  ````\n  x" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+// Parse and execution errors must surface to the caller instead of producing
+// a half-rendered payload on the clipboard.
+func TestTemplateFormatter_Errors(t *testing.T) {
+	if _, err := NewTemplateFormatter("{{range .Files}"); err == nil {
+		t.Fatal("expected parse error for malformed template")
+	}
+
+	f, err := NewTemplateFormatter("{{.Missing}}")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if _, err := f.Format(nil); err == nil {
+		t.Fatal("expected execution error for unknown field")
+	}
+}
+
+// A template registered with the copier must be reachable when cycling with
+// the TUI key, alongside the built-in formats.
+func TestCycleFormatter_IncludesTemplate(t *testing.T) {
+	repo := &mockFileRepo{
+		currentDir: "/project",
+		files:      map[string][]byte{"/project/a.go": []byte("package a")},
+	}
+	cp := NewFileCopier(repo)
+	f, err := NewTemplateFormatter("{{range .Files}}<{{.Path}}>{{end}}")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	cp.AddFormatter(f)
+
+	var seen []string
+	for range cp.Formatters() {
+		seen = append(seen, cp.CycleFormatter().Name())
+	}
+	if strings.Join(seen, ",") != "markdown,xml,json,template,plain" {
+		t.Fatalf("unexpected cycle order: %v", seen)
+	}
+
+	cp.SetFormatter(f)
+	node := entities.NewFileNode("a.go", "/project/a.go", false, nil)
+	if _, err := cp.CopySelectionToClipboard(map[string]*entities.FileNode{node.Path: node}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.clipboardText != "<a.go>" {
+		t.Fatalf("expected template output, got %q", repo.clipboardText)
+	}
+}
@@ -0,0 +1,389 @@
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Unlimited follows imports until no new package is found
+const Unlimited = 0
+
+// ErrNoModule is returned when neither the root directory nor a directory above it has a go.mod naming its module
+var ErrNoModule = errors.New("no go.mod with a module path in the root directory or above it")
+
+// IgnoreMatcher decides whether a path is excluded by ignore rules
+type IgnoreMatcher interface {
+	IsIgnored(path string, isDir bool) bool
+}
+
+// Direction selects which way imports are followed
+type Direction int
+
+const (
+	Imports   Direction = iota // Add the packages the selected files import
+	Importers                  // Add the files importing the packages of the selected files
+)
+
+// File is a file added to the selection by following imports
+type File struct {
+	Path    string // Slash-separated path relative to the root
+	Package string // Import path of the package the file belongs to
+	Depth   int    // Imports followed from the selection to reach the file: 1 for a direct import
+}
+
+// Resolver follows the imports between the packages of the Go module enclosing
+// a root directory, which may be a directory inside the module. Build
+// constraints are not evaluated, so every file of a package counts. The module
+// is read on the first expansion and reused until Reset.
+type Resolver struct {
+	repo   repositories.FileRepository
+	ignore IgnoreMatcher
+	root   string
+	depth  int
+
+	mu  sync.Mutex
+	mod *module // Index of the module; nil until the next expansion reads it
+}
+
+// NewResolver creates a Resolver for the module enclosing root
+func NewResolver(repo repositories.FileRepository, root string) *Resolver {
+	return &Resolver{repo: repo, root: root}
+}
+
+// SetIgnoreMatcher sets the matcher of entries to leave out of the module
+func (r *Resolver) SetIgnoreMatcher(matcher IgnoreMatcher) {
+	r.ignore = matcher
+}
+
+// SetDepth sets the number of import levels the UIs follow by default; Unlimited follows them all
+func (r *Resolver) SetDepth(depth int) {
+	r.depth = max(depth, Unlimited)
+}
+
+// Depth returns the number of import levels the UIs follow by default
+func (r *Resolver) Depth() int {
+	return r.depth
+}
+
+// Reset drops the index of the module so the next expansion reads it again,
+// after files of the module changed on disk
+func (r *Resolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mod = nil
+}
+
+// index returns the index of the module, reading it unless it is cached
+func (r *Resolver) index() (*module, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mod == nil {
+		mod, err := r.load()
+		if err != nil {
+			return nil, err
+		}
+		r.mod = mod
+	}
+	return r.mod, nil
+}
+
+// goFile is a parsed Go source file of the module
+type goFile struct {
+	path    string   // Slash-separated path relative to the module root
+	test    bool     // A _test.go file, which other packages cannot import
+	imports []string // Import paths, including those outside the module
+}
+
+// pkg is a directory of Go files in the module
+type pkg struct {
+	files []goFile
+}
+
+// module indexes the packages of a module by import path and their files by path
+type module struct {
+	packages map[string]*pkg
+	fileOf   map[string]string // File path to the import path of its package
+	prefix   string            // Slash-separated path of the root relative to the module root; "" when they are the same
+}
+
+// inModule returns the module-relative path of a root-relative path
+func (m *module) inModule(p string) string {
+	return path.Join(m.prefix, p)
+}
+
+// inRoot returns the root-relative path of a module-relative path, or false when it is outside the root
+func (m *module) inRoot(p string) (string, bool) {
+	if m.prefix == "" {
+		return p, true
+	}
+	return strings.CutPrefix(p, m.prefix+"/")
+}
+
+// Expand returns the files to add to a selection by following the imports of
+// its Go files in direction, up to depth imports away (Unlimited follows them
+// all). Imports adds the non-test files of imported packages in the module;
+// Importers adds every file, tests included, importing one of the selected
+// packages. Files already selected are left out, and the rest are sorted by
+// depth and path. selected holds root-relative, slash-separated paths. When the
+// root is inside the module, imports are followed through the whole module but
+// only the files under the root, which can be selected, are returned.
+func (r *Resolver) Expand(selected []string, direction Direction, depth int) ([]File, error) {
+	mod, err := r.index()
+	if err != nil {
+		return nil, err
+	}
+
+	selected = slices.Clone(selected)
+	chosen := make(map[string]bool, len(selected))
+	for i, p := range selected {
+		selected[i] = mod.inModule(p)
+		chosen[selected[i]] = true
+	}
+
+	var added []File
+	visited := make(map[string]bool)
+	add := func(file goFile, level int) {
+		if chosen[file.path] {
+			return
+		}
+		chosen[file.path] = true
+		if p, ok := mod.inRoot(file.path); ok {
+			added = append(added, File{Path: p, Package: mod.fileOf[file.path], Depth: level})
+		}
+	}
+
+	// The frontier holds the files (Imports) or packages (Importers) the next level starts from
+	var frontier []string
+	for _, p := range selected {
+		importPath, ok := mod.fileOf[p]
+		if !ok {
+			continue
+		}
+		if direction == Imports {
+			frontier = append(frontier, p)
+		} else if !visited[importPath] {
+			visited[importPath] = true
+			frontier = append(frontier, importPath)
+		}
+	}
+
+	for level := 1; len(frontier) > 0 && (depth == Unlimited || level <= depth); level++ {
+		var next []string
+		if direction == Imports {
+			for _, importPath := range mod.importsOf(frontier) {
+				if visited[importPath] {
+					continue
+				}
+				visited[importPath] = true
+				for _, file := range mod.packages[importPath].files {
+					if !file.test {
+						add(file, level)
+						next = append(next, file.path)
+					}
+				}
+			}
+		} else {
+			targets := make(map[string]bool, len(frontier))
+			for _, importPath := range frontier {
+				targets[importPath] = true
+			}
+			for _, importPath := range mod.sortedPackages() {
+				for _, file := range mod.packages[importPath].files {
+					if !file.importsAny(targets) {
+						continue
+					}
+					add(file, level)
+					// Nothing imports a test, so only regular files lead further
+					if !file.test && !visited[importPath] {
+						visited[importPath] = true
+						next = append(next, importPath)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	sort.SliceStable(added, func(i, j int) bool {
+		if added[i].Depth != added[j].Depth {
+			return added[i].Depth < added[j].Depth
+		}
+		return added[i].Path < added[j].Path
+	})
+	return added, nil
+}
+
+// importsOf returns the in-module packages imported by the given files, sorted
+func (m *module) importsOf(files []string) []string {
+	seen := make(map[string]bool)
+	var found []string
+	for _, p := range files {
+		for _, file := range m.packages[m.fileOf[p]].files {
+			if file.path != p {
+				continue
+			}
+			for _, importPath := range file.imports {
+				if _, ok := m.packages[importPath]; ok && !seen[importPath] {
+					seen[importPath] = true
+					found = append(found, importPath)
+				}
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// sortedPackages returns the import paths of the module's packages in order
+func (m *module) sortedPackages() []string {
+	paths := make([]string, 0, len(m.packages))
+	for importPath := range m.packages {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// importsAny reports whether the file imports one of the target packages
+func (f goFile) importsAny(targets map[string]bool) bool {
+	for _, importPath := range f.imports {
+		if targets[importPath] {
+			return true
+		}
+	}
+	return false
+}
+
+// findModule returns the directory of the go.mod enclosing the root, looking
+// in the root and then in each directory above it as the go command does, with
+// the module path it declares
+func (r *Resolver) findModule() (dir, modulePath string, err error) {
+	dir = r.root
+	for {
+		if data, err := r.repo.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			if modulePath = ModulePath(data); modulePath == "" {
+				return "", "", ErrNoModule
+			}
+			return dir, modulePath, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", ErrNoModule
+		}
+		dir = parent
+	}
+}
+
+// load finds the enclosing go.mod and indexes the imports of every Go file of
+// the module. Ignored entries, vendor and testdata directories, directories
+// starting with '.' or '_', and nested modules are skipped, as the go command
+// does. Files that do not parse are indexed without imports.
+func (r *Resolver) load() (*module, error) {
+	moduleRoot, modulePath, err := r.findModule()
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := r.repo.GetRelativePath(r.root, moduleRoot)
+	if err != nil {
+		return nil, ErrNoModule
+	}
+	if prefix = filepath.ToSlash(prefix); prefix == "." {
+		prefix = ""
+	}
+
+	mod := &module{packages: make(map[string]*pkg), fileOf: make(map[string]string), prefix: prefix}
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		entries, err := r.repo.ReadDirectory(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			name, fullPath := entry.Name(), filepath.Join(dir, entry.Name())
+			if r.ignore != nil && r.ignore.IsIgnored(fullPath, entry.IsDir()) {
+				continue
+			}
+			childRel := path.Join(rel, name)
+
+			if entry.IsDir() {
+				if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					continue
+				}
+				if _, err := r.repo.ReadFile(filepath.Join(fullPath, "go.mod")); err == nil {
+					continue
+				}
+				walk(fullPath, childRel)
+				continue
+			}
+			if !strings.HasSuffix(name, ".go") {
+				continue
+			}
+
+			importPath := modulePath
+			if rel != "" {
+				importPath += "/" + rel
+			}
+			p := mod.packages[importPath]
+			if p == nil {
+				p = &pkg{}
+				mod.packages[importPath] = p
+			}
+			p.files = append(p.files, goFile{
+				path:    childRel,
+				test:    strings.HasSuffix(name, "_test.go"),
+				imports: r.imports(fullPath),
+			})
+			mod.fileOf[childRel] = importPath
+		}
+	}
+	walk(moduleRoot, "")
+	return mod, nil
+}
+
+// imports returns the import paths of the Go file at fullPath, or nil when it cannot be parsed
+func (r *Resolver) imports(fullPath string) []string {
+	content, err := r.repo.ReadFile(fullPath)
+	if err != nil {
+		return nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), fullPath, content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	imports := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, importPath)
+		}
+	}
+	return imports
+}
+
+// ModulePath returns the module path declared by the contents of a go.mod file, or "" when there is none
+func ModulePath(gomod []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			rest, _, _ = strings.Cut(rest, "//")
+			if modulePath, err := strconv.Unquote(strings.TrimSpace(rest)); err == nil {
+				return modulePath
+			}
+			return strings.TrimSpace(rest)
+		}
+	}
+	return ""
+}
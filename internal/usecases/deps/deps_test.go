@@ -0,0 +1,207 @@
+package deps
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Why test Resolver?
+//
+// Expanding a selection by its imports decides which packages a model gets to
+// see next to the code it is asked about. A missed import leaves out the code
+// the selection depends on; a package from outside the module, a vendored copy,
+// or a nested module pads the payload. The depth limit and the reverse
+// direction must agree with what the UIs preview before confirming.
+
+// --- mock repository ---
+
+type mockDirEntry struct {
+	name  string
+	isDir bool
+}
+
+func (m mockDirEntry) Name() string { return m.name }
+func (m mockDirEntry) IsDir() bool  { return m.isDir }
+
+// mockFileRepo serves an in-memory tree built from full file paths
+type mockFileRepo struct {
+	files map[string]string
+}
+
+func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return "/repo", nil }
+func (m *mockFileRepo) ReadDirectory(path string) ([]repositories.DirEntry, error) {
+	seen := map[string]bool{}
+	var entries []repositories.DirEntry
+	for file := range m.files {
+		rel, err := filepath.Rel(path, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		name, _, nested := strings.Cut(rel, "/")
+		if !seen[name] {
+			seen[name] = true
+			entries = append(entries, mockDirEntry{name, nested})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
+func (m *mockFileRepo) GetRelativePath(target, base string) (string, error) {
+	return filepath.Rel(base, target)
+}
+func (m *mockFileRepo) WriteToClipboard(string) error { return nil }
+
+// newTestResolver builds a module where main imports app, app imports store
+// and util, and store imports util
+func newTestResolver() *Resolver {
+	repo := &mockFileRepo{files: map[string]string{
+		"/repo/go.mod":                           "// The example module\nmodule example.com/app\n\ngo 1.22\n",
+		"/repo/main.go":                          "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/internal/app\"\n)\n",
+		"/repo/internal/app/app.go":              "package app\n\nimport (\n\t\"example.com/app/internal/store\"\n\tu \"example.com/app/internal/util\"\n)\n",
+		"/repo/internal/app/app_test.go":         "package app\n\nimport \"example.com/app/internal/testutil\"\n",
+		"/repo/internal/store/store.go":          "package store\n\nimport \"example.com/app/internal/util\"\n",
+		"/repo/internal/store/store_test.go":     "package store_test\n\nimport \"example.com/app/internal/store\"\n",
+		"/repo/internal/util/util.go":            "package util\n",
+		"/repo/internal/util/README.md":          "not Go\n",
+		"/repo/internal/testutil/testutil.go":    "package testutil\n",
+		"/repo/vendor/example.com/x/x.go":        "package x\n\nimport \"example.com/app/internal/util\"\n",
+		"/repo/tools/go.mod":                     "module example.com/tools\n",
+		"/repo/tools/gen.go":                     "package tools\n\nimport \"example.com/app/internal/util\"\n",
+		"/repo/internal/broken/broken.go":        "package broken\nimport (",
+		"/repo/internal/app/testdata/fixture.go": "package fixture\n\nimport \"example.com/app/internal/util\"\n",
+	}}
+	return NewResolver(repo, "/repo")
+}
+
+// paths lists the added files as "path@depth"
+func paths(files []File) []string {
+	var out []string
+	for _, file := range files {
+		out = append(out, fmt.Sprintf("%s@%d", file.Path, file.Depth))
+	}
+	return out
+}
+
+// Imports are followed transitively through the module, level by level; the
+// standard library and the tests of imported packages are not added.
+func TestExpand_Imports(t *testing.T) {
+	r := newTestResolver()
+
+	got, err := r.Expand([]string{"main.go", "README.md"}, Imports, Unlimited)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"internal/app/app.go@1", "internal/store/store.go@2", "internal/util/util.go@2"}
+	if !reflect.DeepEqual(paths(got), want) {
+		t.Errorf("got %v, want %v", paths(got), want)
+	}
+	if got[0].Package != "example.com/app/internal/app" {
+		t.Errorf("unexpected package %q", got[0].Package)
+	}
+
+	// The depth limits how many imports away files are added
+	got, _ = r.Expand([]string{"main.go"}, Imports, 1)
+	if !reflect.DeepEqual(paths(got), []string{"internal/app/app.go@1"}) {
+		t.Errorf("depth 1: got %v", paths(got))
+	}
+
+	// Files already selected are not added again
+	got, _ = r.Expand([]string{"internal/app/app.go", "internal/util/util.go"}, Imports, Unlimited)
+	if !reflect.DeepEqual(paths(got), []string{"internal/store/store.go@1"}) {
+		t.Errorf("already selected: got %v", paths(got))
+	}
+}
+
+// The module is read once and reused, so a changed import is only followed after Reset.
+func TestExpand_ReusesModuleUntilReset(t *testing.T) {
+	r := newTestResolver()
+	if got, _ := r.Expand([]string{"internal/util/util.go"}, Imports, Unlimited); len(got) != 0 {
+		t.Fatalf("util imports nothing, got %v", paths(got))
+	}
+
+	r.repo.(*mockFileRepo).files["/repo/internal/util/util.go"] = "package util\n\nimport \"example.com/app/internal/testutil\"\n"
+	if got, _ := r.Expand([]string{"internal/util/util.go"}, Imports, Unlimited); len(got) != 0 {
+		t.Errorf("expected the cached module before Reset, got %v", paths(got))
+	}
+
+	r.Reset()
+	got, _ := r.Expand([]string{"internal/util/util.go"}, Imports, Unlimited)
+	if !reflect.DeepEqual(paths(got), []string{"internal/testutil/testutil.go@1"}) {
+		t.Errorf("after Reset: got %v", paths(got))
+	}
+}
+
+// The reverse direction finds who imports the selected packages, tests
+// included, but never looks into vendored code or nested modules.
+func TestExpand_Importers(t *testing.T) {
+	r := newTestResolver()
+
+	got, err := r.Expand([]string{"internal/util/util.go"}, Importers, Unlimited)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"internal/app/app.go@1", "internal/store/store.go@1",
+		"internal/store/store_test.go@2", "main.go@2",
+	}
+	if !reflect.DeepEqual(paths(got), want) {
+		t.Errorf("got %v, want %v", paths(got), want)
+	}
+
+	got, _ = r.Expand([]string{"internal/util/util.go"}, Importers, 1)
+	if len(got) != 2 {
+		t.Errorf("depth 1: got %v", paths(got))
+	}
+}
+
+// Started inside the module, as from a package directory, the go.mod above
+// is used; only files under the root are returned, with root-relative paths,
+// though imports are followed through the whole module.
+func TestExpand_FromSubdirectory(t *testing.T) {
+	r := newTestResolver()
+	r.root = "/repo/internal"
+
+	got, err := r.Expand([]string{"app/app.go"}, Imports, Unlimited)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"store/store.go@1", "util/util.go@1"}
+	if !reflect.DeepEqual(paths(got), want) {
+		t.Errorf("got %v, want %v", paths(got), want)
+	}
+
+	got, _ = r.Expand([]string{"store/store.go"}, Importers, Unlimited)
+	want = []string{"app/app.go@1", "store/store_test.go@1"}
+	if !reflect.DeepEqual(paths(got), want) {
+		t.Errorf("importers: got %v, want %v", paths(got), want)
+	}
+}
+
+// Outside a module there is nothing to resolve imports against.
+func TestExpand_NoModule(t *testing.T) {
+	r := NewResolver(&mockFileRepo{files: map[string]string{"/repo/main.go": "package main\n"}}, "/repo")
+	if _, err := r.Expand([]string{"main.go"}, Imports, Unlimited); !errors.Is(err, ErrNoModule) {
+		t.Fatalf("expected ErrNoModule, got %v", err)
+	}
+
+	if got := ModulePath([]byte("module \"example.com/quoted\" // comment\n")); got != "example.com/quoted" {
+		t.Errorf("ModulePath = %q", got)
+	}
+	if got := ModulePath([]byte("modulex foo\n")); got != "" {
+		t.Errorf("ModulePath = %q, want none", got)
+	}
+}
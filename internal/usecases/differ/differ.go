@@ -0,0 +1,49 @@
+package differ
+
+import (
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// DefaultRef is the ref diffs are computed against when none is configured
+const DefaultRef = "HEAD"
+
+// Differ produces the unified diff of a file against a fixed ref, covering
+// files that are new since the ref, modified, or deleted from the working tree
+type Differ struct {
+	repo repositories.DiffRepository
+	ref  string
+}
+
+// NewDiffer creates a Differ comparing against ref; an empty ref selects DefaultRef
+func NewDiffer(repo repositories.DiffRepository, ref string) *Differ {
+	if ref == "" {
+		ref = DefaultRef
+	}
+	return &Differ{repo: repo, ref: ref}
+}
+
+// Ref returns the ref diffs are computed against
+func (d *Differ) Ref() string {
+	return d.ref
+}
+
+// Check returns an error when the ref cannot be resolved, e.g. outside a repository
+func (d *Differ) Check() error {
+	_, err := d.repo.ExistsAt(d.ref, ".")
+	return err
+}
+
+// Diff returns the unified diff of the root-relative path against the ref.
+// Files that do not exist in the ref are diffed against an empty file, and
+// files deleted from the working tree yield a deletion diff. The diff is
+// empty when the file is unchanged.
+func (d *Differ) Diff(path string) (string, error) {
+	exists, err := d.repo.ExistsAt(d.ref, path)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return d.repo.DiffNew(path)
+	}
+	return d.repo.Diff(d.ref, path)
+}
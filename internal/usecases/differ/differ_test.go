@@ -0,0 +1,45 @@
+package differ
+
+import (
+	"testing"
+)
+
+// Why test the differ?
+//
+// A plain "git diff REF -- path" prints nothing for a file that is not in
+// REF yet, so new files would silently vanish from a diff-only copy. The
+// differ must route them to an addition diff and keep the ref-based diff for
+// modified and deleted files.
+
+// mockDiffRepo knows which paths exist at the ref and records the calls
+type mockDiffRepo struct {
+	atRef map[string]bool
+	calls []string
+}
+
+func (m *mockDiffRepo) ExistsAt(ref, path string) (bool, error) { return m.atRef[path], nil }
+
+func (m *mockDiffRepo) Diff(ref, path string) (string, error) {
+	m.calls = append(m.calls, "diff "+ref+" "+path)
+	return "diff of " + path, nil
+}
+
+func (m *mockDiffRepo) DiffNew(path string) (string, error) {
+	m.calls = append(m.calls, "new "+path)
+	return "addition of " + path, nil
+}
+
+func TestDiffer_RoutesNewFiles(t *testing.T) {
+	repo := &mockDiffRepo{atRef: map[string]bool{"old.go": true}}
+	d := NewDiffer(repo, "")
+
+	if d.Ref() != DefaultRef {
+		t.Fatalf("empty ref should default to %s, got %s", DefaultRef, d.Ref())
+	}
+	if diff, _ := d.Diff("old.go"); diff != "diff of old.go" {
+		t.Errorf("existing file should be diffed against the ref, got %q", diff)
+	}
+	if diff, _ := d.Diff("new.go"); diff != "addition of new.go" {
+		t.Errorf("new file should be diffed against an empty file, got %q", diff)
+	}
+}
@@ -0,0 +1,152 @@
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring weights. Matches that start segments and run consecutively score
+// higher; gaps and long paths score lower.
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 24 // Match at the start of a path segment or word
+	bonusConsecutive = 16 // Match right after the previous match
+	bonusBaseName    = 12 // Match inside the last path segment
+	penaltyGap       = 1  // Per skipped character between matches
+	penaltyLength    = 1  // Per 8 characters of the candidate
+)
+
+// Result is a candidate that matches the pattern
+type Result struct {
+	Index     int   // Index of the candidate in the input slice
+	Score     int   // Higher is better
+	Positions []int // Byte offsets of the matched characters in the candidate
+}
+
+// Match reports whether every rune of pattern appears in text in order,
+// ignoring case, and scores the match. An empty pattern matches everything.
+func Match(pattern, text string) (Result, bool) {
+	if pattern == "" {
+		return Result{}, true
+	}
+
+	pattern = strings.ToLower(pattern)
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Case folding changed byte offsets; fall back to exact-case positions
+		lower = text
+	}
+
+	// Prefer the occurrence of the pattern that starts latest, so matches
+	// land in the base name rather than in a parent directory
+	start := lastStart(pattern, lower)
+	if start < 0 {
+		return Result{}, false
+	}
+
+	baseStart := strings.LastIndexByte(strings.TrimSuffix(text, "/"), '/') + 1
+	result := Result{Score: -len(text) / 8 * penaltyLength}
+	prev := -1
+	pos := start
+	for _, r := range pattern {
+		idx := strings.IndexRune(lower[pos:], r)
+		if idx < 0 {
+			return Result{}, false
+		}
+		idx += pos
+
+		result.Score += scoreMatch
+		if isBoundary(text, idx) {
+			result.Score += bonusBoundary
+		}
+		if prev >= 0 && idx == prev+len(string(r)) {
+			result.Score += bonusConsecutive
+		} else if prev >= 0 {
+			result.Score -= (idx - prev) * penaltyGap
+		}
+		if idx >= baseStart {
+			result.Score += bonusBaseName
+		}
+
+		result.Positions = append(result.Positions, idx)
+		prev = idx
+		pos = idx + len(string(r))
+	}
+	return result, true
+}
+
+// Rank returns the candidates matching pattern, best first.
+// Ties keep the input order. A limit of 0 returns every match.
+func Rank(pattern string, candidates []string, limit int) []Result {
+	var results []Result
+	for i, candidate := range candidates {
+		if result, ok := Match(pattern, candidate); ok {
+			result.Index = i
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// lastStart returns the latest offset in text from which the whole pattern
+// still matches as a subsequence, or -1 when it does not match at all
+func lastStart(pattern, text string) int {
+	first, _ := firstRune(pattern)
+	start := -1
+	for offset := 0; offset < len(text); {
+		idx := strings.IndexRune(text[offset:], first)
+		if idx < 0 {
+			break
+		}
+		idx += offset
+		if !isSubsequence(pattern, text[idx:]) {
+			break
+		}
+		start = idx
+		offset = idx + len(string(first))
+	}
+	return start
+}
+
+// isSubsequence reports whether the runes of pattern appear in text in order
+func isSubsequence(pattern, text string) bool {
+	pos := 0
+	for _, r := range pattern {
+		idx := strings.IndexRune(text[pos:], r)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(string(r))
+	}
+	return true
+}
+
+// firstRune returns the first rune of s
+func firstRune(s string) (rune, bool) {
+	for _, r := range s {
+		return r, true
+	}
+	return 0, false
+}
+
+// isBoundary reports whether the byte at idx starts a path segment, a word, or a camelCase hump
+func isBoundary(text string, idx int) bool {
+	if idx == 0 {
+		return true
+	}
+	prev := rune(text[idx-1])
+	switch prev {
+	case '/', '_', '-', '.', ' ':
+		return true
+	}
+	cur := rune(text[idx])
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
@@ -0,0 +1,85 @@
+package fuzzy
+
+import (
+	"testing"
+)
+
+// Why test the fuzzy matcher?
+//
+// The finder is only faster than j/k if the file you mean shows up first.
+// These tests pin down the ranking rules users rely on: base-name matches
+// beat directory matches, segment starts beat mid-word hits, and shorter
+// paths win ties.
+
+func rankPaths(pattern string, paths []string) []string {
+	var ranked []string
+	for _, result := range Rank(pattern, paths, 0) {
+		ranked = append(ranked, paths[result.Index])
+	}
+	return ranked
+}
+
+// Matching is an in-order, case-insensitive subsequence test.
+func TestMatch_Subsequence(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, text string
+		ok            bool
+	}{
+		{"fnav", "internal/usecases/navigator/file_navigator.go", true},
+		{"FNAV", "internal/usecases/navigator/file_navigator.go", true},
+		{"vanf", "internal/usecases/navigator/file_navigator.go", false},
+		{"", "anything", true},
+		{"xyz", "main.go", false},
+	} {
+		if _, ok := Match(tc.pattern, tc.text); ok != tc.ok {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.pattern, tc.text, ok, tc.ok)
+		}
+	}
+}
+
+// The positions must point at the characters to highlight, preferring the base name.
+func TestMatch_PositionsInBaseName(t *testing.T) {
+	result, ok := Match("main", "cmd/main/main.go")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	want := []int{9, 10, 11, 12}
+	for i, pos := range want {
+		if result.Positions[i] != pos {
+			t.Fatalf("positions = %v, want %v", result.Positions, want)
+		}
+	}
+}
+
+// A query typed as the file name must rank that file above paths that only
+// match across directory names.
+func TestRank_PrefersBaseNameAndBoundaries(t *testing.T) {
+	paths := []string{
+		"internal/adapters/ui/tui/model.go",
+		"internal/usecases/tokens/estimator.go",
+		"docs/models/overview.md",
+		"internal/domain/entities/file_node.go",
+	}
+
+	ranked := rankPaths("model", paths)
+	if len(ranked) != 2 || ranked[0] != "internal/adapters/ui/tui/model.go" {
+		t.Fatalf("model.go should rank first, got %v", ranked)
+	}
+
+	ranked = rankPaths("fn", paths)
+	if len(ranked) == 0 || ranked[0] != "internal/domain/entities/file_node.go" {
+		t.Fatalf("file_node.go should rank first for word starts, got %v", ranked)
+	}
+}
+
+// With equal matches the shorter path wins, and the limit caps the results.
+func TestRank_ShorterPathAndLimit(t *testing.T) {
+	paths := []string{"a/b/c/d/e/f/g/h/main.go", "main.go", "x/main.go"}
+	ranked := rankPaths("main", paths)
+	if ranked[0] != "main.go" {
+		t.Fatalf("shortest path should rank first, got %v", ranked)
+	}
+	if results := Rank("main", paths, 2); len(results) != 2 {
+		t.Fatalf("limit should cap the results, got %d", len(results))
+	}
+}
@@ -0,0 +1,151 @@
+package ignore
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// GitDirName is the repository metadata directory, which is always ignored
+const GitDirName = ".git"
+
+// Matcher decides whether paths under a root are ignored by git's exclude rules.
+//
+// Rules are read, in increasing order of precedence, from the given exclude
+// files (the global excludes file and .git/info/exclude), then from the
+// .gitignore of every directory between the top of the repository and the
+// path. As in git, the last matching rule wins, and a path inside an ignored
+// directory is ignored no matter what rules follow.
+type Matcher struct {
+	repo   repositories.FileRepository
+	root   string
+	top    string   // Top of the repository, where exclude file rules are anchored; root unless set
+	prefix []string // Directories from the top down to the root
+	base   []rule
+	mu     sync.Mutex
+	cache  map[string][]rule // Rules of each directory's .gitignore, keyed by slash path from the top
+}
+
+// NewMatcher creates a Matcher for the tree rooted at root, taken as the top
+// of the repository until SetTop says otherwise. excludeFiles are read as rule
+// files anchored at the top; missing files are skipped.
+func NewMatcher(repo repositories.FileRepository, root string, excludeFiles ...string) *Matcher {
+	m := &Matcher{
+		repo:  repo,
+		root:  root,
+		top:   root,
+		cache: make(map[string][]rule),
+	}
+	for _, file := range excludeFiles {
+		if content, err := repo.ReadFile(file); err == nil {
+			m.base = append(m.base, parseRules(string(content), "")...)
+		}
+	}
+	return m
+}
+
+// SetTop sets the top of the repository containing the root, and the
+// slash-separated path of the root below it ("a/b/", or "" at the top), so
+// that the .gitignore files above the root apply and exclude file rules are
+// anchored at the top, as when git runs from a subdirectory
+func (m *Matcher) SetTop(top, prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.top = top
+	m.prefix = nil
+	if prefix = strings.Trim(filepath.ToSlash(prefix), "/"); prefix != "" {
+		m.prefix = strings.Split(prefix, "/")
+	}
+	m.cache = make(map[string][]rule)
+}
+
+// Forget drops the cached rules of the .gitignore in the directory at dir, so
+// they are read again after the file was created, removed, or written
+func (m *Matcher) Forget(dir string) {
+	rel, err := m.repo.GetRelativePath(dir, m.root)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(rel, "../") || rel == ".." {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	segments := slices.Clone(m.prefix)
+	if rel != "." {
+		segments = append(segments, strings.Split(rel, "/")...)
+	}
+	delete(m.cache, strings.Join(segments, "/"))
+}
+
+// IsIgnored reports whether the file or directory at path is ignored
+func (m *Matcher) IsIgnored(path string, isDir bool) bool {
+	rel, err := m.repo.GetRelativePath(path, m.root)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || strings.HasPrefix(rel, "../") || rel == ".." {
+		return false
+	}
+
+	// A path inside an ignored directory cannot be re-included. The root and
+	// the directories above it are not checked, so the tree is never hidden.
+	m.mu.Lock()
+	prefix := m.prefix
+	m.mu.Unlock()
+	segments := append(slices.Clone(prefix), strings.Split(rel, "/")...)
+	for i := len(prefix) + 1; i <= len(segments); i++ {
+		dirPart := i < len(segments) || isDir
+		if m.matches(segments[:i], dirPart) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies all rules to the path made of segments, without checking its parents
+func (m *Matcher) matches(segments []string, isDir bool) bool {
+	if segments[len(segments)-1] == GitDirName {
+		return true
+	}
+
+	rel := strings.Join(segments, "/")
+	ignored := false
+	apply := func(rules []rule) {
+		for _, r := range rules {
+			if r.match(rel, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+
+	apply(m.base)
+	for i := 0; i < len(segments); i++ {
+		apply(m.dirRules(strings.Join(segments[:i], "/")))
+	}
+	return ignored
+}
+
+// dirRules returns the rules of the .gitignore in the directory at dir, a slash path from the top
+func (m *Matcher) dirRules(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rules, ok := m.cache[dir]; ok {
+		return rules
+	}
+
+	var rules []rule
+	file := filepath.Join(m.top, filepath.FromSlash(dir), ".gitignore")
+	if content, err := m.repo.ReadFile(file); err == nil {
+		rules = parseRules(string(content), dir)
+	}
+	m.cache[dir] = rules
+	return rules
+}
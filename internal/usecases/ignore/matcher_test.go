@@ -0,0 +1,169 @@
+package ignore
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Why test Matcher?
+//
+// Ignore rules decide what the user sees in both UIs. A rule that matches
+// too much hides source files the user wants to copy; one that matches too
+// little floods the tree with node_modules and build output. Git's rules
+// have several subtle parts (anchoring, directory-only patterns, negation,
+// precedence of nested files) that are easy to get wrong.
+
+// --- mock repository ---
+
+type mockFileRepo struct {
+	files map[string]string
+}
+
+func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return "/repo", nil }
+func (m *mockFileRepo) ReadDirectory(string) ([]repositories.DirEntry, error) {
+	return nil, nil
+}
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
+func (m *mockFileRepo) GetRelativePath(target, base string) (string, error) {
+	return filepath.Rel(base, target)
+}
+func (m *mockFileRepo) WriteToClipboard(string) error { return nil }
+
+func newTestMatcher() *Matcher {
+	repo := &mockFileRepo{files: map[string]string{
+		"/home/.config/git/ignore": "*.swp\n",
+		"/repo/.git/info/exclude":  "local/\n",
+		"/repo/.gitignore": "# build output\n" +
+			"node_modules/\n" +
+			"/dist\n" +
+			"*.log\n" +
+			"!keep.log\n" +
+			"docs/*.tmp\n",
+		"/repo/src/.gitignore": "generated/\n!important.log\n",
+	}}
+	return NewMatcher(repo, "/repo", "/home/.config/git/ignore", "/repo/.git/info/exclude")
+}
+
+func TestIsIgnored(t *testing.T) {
+	m := newTestMatcher()
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		// .git is always hidden
+		{"/repo/.git", true, true},
+		{"/repo/.git/COMMIT_EDITMSG", false, true},
+		// Unanchored directory pattern matches at any depth, and only directories
+		{"/repo/node_modules", true, true},
+		{"/repo/web/node_modules", true, true},
+		{"/repo/node_modules", false, false},
+		// Everything inside an ignored directory is ignored
+		{"/repo/node_modules/pkg/index.js", false, true},
+		// Leading slash anchors to the .gitignore's directory
+		{"/repo/dist", true, true},
+		{"/repo/src/dist", true, false},
+		// Middle slash anchors too, and '*' does not cross directories
+		{"/repo/docs/a.tmp", false, true},
+		{"/repo/docs/sub/a.tmp", false, false},
+		// Negation re-includes a file
+		{"/repo/app.log", false, true},
+		{"/repo/keep.log", false, false},
+		// Nested .gitignore adds rules relative to its own directory and can override parents
+		{"/repo/src/generated", true, true},
+		{"/repo/generated", true, false},
+		{"/repo/src/important.log", false, false},
+		{"/repo/src/other.log", false, true},
+		// Global excludes file and .git/info/exclude
+		{"/repo/main.go.swp", false, true},
+		{"/repo/local", true, true},
+		// Ordinary files are visible
+		{"/repo/main.go", false, false},
+		{"/repo/src/lib.go", false, false},
+		// The root itself is never ignored
+		{"/repo", true, false},
+	}
+	for _, c := range cases {
+		if got := m.IsIgnored(c.path, c.isDir); got != c.want {
+			t.Errorf("IsIgnored(%q, dir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+// Comments, blank lines, escapes, and trailing spaces follow git's syntax.
+func TestParseRules_Syntax(t *testing.T) {
+	rules := parseRules("# comment\n\n\\#hash\n\\!bang\ntrailing   \nescaped\\ \n", "")
+	if len(rules) != 4 {
+		t.Fatalf("expected 4 rules, got %d: %+v", len(rules), rules)
+	}
+	want := []string{"**/#hash", "**/!bang", "**/trailing", "**/escaped "}
+	for i, r := range rules {
+		if r.pattern != want[i] || r.negate {
+			t.Errorf("rule %d = %+v, want pattern %q", i, r, want[i])
+		}
+	}
+}
+
+// Started from a subdirectory, git still applies the .gitignore files above
+// it, and anchors exclude file rules at the top of the repository. Without
+// this, node_modules ignored by the top-level .gitignore comes back.
+func TestIsIgnored_FromSubdirectory(t *testing.T) {
+	repo := &mockFileRepo{files: map[string]string{
+		"/repo/.git/info/exclude": "/build\n",
+		"/repo/.gitignore":        "node_modules/\n/a/secret.txt\n",
+		"/repo/a/.gitignore":      "*.tmp\n",
+	}}
+	m := NewMatcher(repo, "/repo/a", "/repo/.git/info/exclude")
+	m.SetTop("/repo", "a/")
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"/repo/a/node_modules", true, true},
+		{"/repo/a/node_modules/y.js", false, true},
+		{"/repo/a/secret.txt", false, true},
+		{"/repo/a/x.tmp", false, true},
+		// /build is anchored at the top, not at the directory the tool runs in
+		{"/repo/a/build", true, false},
+		{"/repo/a/main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := m.IsIgnored(c.path, c.isDir); got != c.want {
+			t.Errorf("IsIgnored(%q, dir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+// A .gitignore edited while the tool runs must take effect; without Forget
+// the rules read first are used until the tool restarts.
+func TestForget(t *testing.T) {
+	repo := &mockFileRepo{files: map[string]string{"/repo/src/.gitignore": "*.tmp\n"}}
+	m := NewMatcher(repo, "/repo")
+	if !m.IsIgnored("/repo/src/x.tmp", false) {
+		t.Fatal("x.tmp should be ignored by src/.gitignore")
+	}
+
+	repo.files["/repo/src/.gitignore"] = "*.out\n"
+	m.Forget("/repo/src")
+	if m.IsIgnored("/repo/src/x.tmp", false) || !m.IsIgnored("/repo/src/y.out", false) {
+		t.Fatal("the rewritten src/.gitignore should replace the cached rules")
+	}
+
+	delete(repo.files, "/repo/src/.gitignore")
+	m.Forget("/repo/src")
+	if m.IsIgnored("/repo/src/y.out", false) {
+		t.Fatal("the rules of a removed .gitignore should no longer apply")
+	}
+}
@@ -0,0 +1,82 @@
+package ignore
+
+import (
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/usecases/pathmatch"
+)
+
+// rule is a single parsed line of a gitignore-style file
+type rule struct {
+	pattern string // Slash-separated glob, anchored at base
+	base    string // Directory the rule is relative to, as a slash path from the root ("" for the root)
+	negate  bool   // Pattern started with '!' and re-includes matching paths
+	dirOnly bool   // Pattern ended with '/' and only matches directories
+}
+
+// parseRules parses the content of a gitignore-style file whose rules are relative to base
+func parseRules(content, base string) []rule {
+	var rules []rule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		line = trimTrailingSpaces(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := rule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// Patterns with a slash at the start or in the middle are relative to base;
+		// patterns without one match at any depth below base
+		if strings.Contains(line, "/") {
+			r.pattern = strings.TrimPrefix(line, "/")
+		} else {
+			r.pattern = "**/" + line
+		}
+
+		if !pathmatch.Valid(r.pattern) {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// match reports whether the rule applies to relPath, a slash path from the root
+func (r rule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, r.base+"/")
+	}
+	return pathmatch.Match(r.pattern, relPath)
+}
+
+// trimTrailingSpaces removes unescaped trailing spaces
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
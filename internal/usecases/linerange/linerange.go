@@ -0,0 +1,144 @@
+package linerange
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// suffix matches what follows the last ':' of a path with line ranges ("120-180,200")
+var suffix = regexp.MustCompile(`^[0-9][0-9,-]*$`)
+
+// Parse parses a comma-separated list of line ranges such as "120-180,200".
+// The ranges are returned sorted, with overlapping and adjacent ranges merged.
+func Parse(spec string) ([]entities.LineRange, error) {
+	var ranges []entities.LineRange
+	for _, part := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid line range %q", part)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid line range %q", part)
+			}
+		}
+		ranges = append(ranges, entities.LineRange{Start: start, End: end})
+	}
+	return Normalize(ranges), nil
+}
+
+// Format returns ranges in the syntax accepted by Parse
+func Format(ranges []entities.LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Split separates a slash-separated path with optional line ranges, such as
+// "internal/app/app.go:120-180", into the path and its ranges. A path without a
+// range suffix is returned with nil ranges; a suffix that only consists of
+// digits, '-' and ',' but does not parse is an error.
+func Split(spec string) (string, []entities.LineRange, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 0 || !suffix.MatchString(spec[i+1:]) {
+		return spec, nil, nil
+	}
+	ranges, err := Parse(spec[i+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	return spec[:i], ranges, nil
+}
+
+// Join appends ranges to path in the syntax accepted by Split
+func Join(path string, ranges []entities.LineRange) string {
+	if len(ranges) == 0 {
+		return path
+	}
+	return path + ":" + Format(ranges)
+}
+
+// Normalize returns ranges sorted by their first line, with overlapping and adjacent ranges merged
+func Normalize(ranges []entities.LineRange) []entities.LineRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([]entities.LineRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Subtract returns ranges without the lines of cut, splitting a range that contains it
+func Subtract(ranges []entities.LineRange, cut entities.LineRange) []entities.LineRange {
+	var rest []entities.LineRange
+	for _, r := range ranges {
+		if r.End < cut.Start || r.Start > cut.End {
+			rest = append(rest, r)
+			continue
+		}
+		if r.Start < cut.Start {
+			rest = append(rest, entities.LineRange{Start: r.Start, End: cut.Start - 1})
+		}
+		if r.End > cut.End {
+			rest = append(rest, entities.LineRange{Start: cut.End + 1, End: r.End})
+		}
+	}
+	return rest
+}
+
+// Covered returns how many lines of r lie within ranges, which must be normalized
+func Covered(ranges []entities.LineRange, r entities.LineRange) int {
+	covered := 0
+	for _, other := range ranges {
+		covered += max(min(other.End, r.End)-max(other.Start, r.Start)+1, 0)
+	}
+	return covered
+}
+
+// Section is the text of one line range of a file
+type Section struct {
+	Range   entities.LineRange // Lines of the file in Content, clipped to the end of the file
+	Content string             // Text of the lines, including their line breaks
+}
+
+// Extract returns the text of each range of content. Ranges reaching past the
+// last line are clipped to it; ranges starting after it are left out.
+func Extract(content string, ranges []entities.LineRange) []Section {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var sections []Section
+	for _, r := range ranges {
+		if r.Start > len(lines) {
+			continue
+		}
+		r.End = min(r.End, len(lines))
+		sections = append(sections, Section{
+			Range:   r,
+			Content: strings.Join(lines[r.Start-1:r.End], ""),
+		})
+	}
+	return sections
+}
@@ -0,0 +1,113 @@
+package linerange
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test line ranges?
+//
+// A range decides exactly which lines end up in the payload. An off-by-one
+// here silently drops the line someone asked for, and a file name containing
+// ':' must not be mistaken for a range. Profiles store ranges as text, so
+// Format must round-trip through Parse.
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		spec string
+		want []entities.LineRange
+	}{
+		{"120-180", []entities.LineRange{{Start: 120, End: 180}}},
+		{"7", []entities.LineRange{{Start: 7, End: 7}}},
+		// Ranges are sorted, and overlapping or adjacent ranges are merged
+		{"50-60,1-10,11-12,55-70", []entities.LineRange{{Start: 1, End: 12}, {Start: 50, End: 70}}},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.spec, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q) = %v, want %v", c.spec, got, c.want)
+		}
+		if again, _ := Parse(Format(got)); !reflect.DeepEqual(again, got) {
+			t.Errorf("Format(%v) = %q does not round-trip", got, Format(got))
+		}
+	}
+
+	for _, spec := range []string{"", "0-3", "180-120", "1-", "a-b", "1,,2"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// Only a suffix made of line numbers is a range; other colons belong to the file name.
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		spec, path string
+		ranges     []entities.LineRange
+	}{
+		{"internal/app/app.go:120-180", "internal/app/app.go", []entities.LineRange{{Start: 120, End: 180}}},
+		{"main.go", "main.go", nil},
+		{"notes:todo.txt", "notes:todo.txt", nil},
+		{"a:b.go:3", "a:b.go", []entities.LineRange{{Start: 3, End: 3}}},
+	}
+	for _, c := range cases {
+		path, ranges, err := Split(c.spec)
+		if err != nil {
+			t.Fatalf("Split(%q): %v", c.spec, err)
+		}
+		if path != c.path || !reflect.DeepEqual(ranges, c.ranges) {
+			t.Errorf("Split(%q) = %q, %v, want %q, %v", c.spec, path, ranges, c.path, c.ranges)
+		}
+	}
+
+	if _, _, err := Split("main.go:180-120"); err == nil {
+		t.Error("Split accepted a reversed range")
+	}
+}
+
+// Ranges past the end of the file are clipped or dropped instead of failing the copy.
+func TestExtract(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\n"
+	got := Extract(content, []entities.LineRange{{Start: 2, End: 3}, {Start: 4, End: 9}, {Start: 10, End: 12}})
+	want := []Section{
+		{Range: entities.LineRange{Start: 2, End: 3}, Content: "two\nthree\n"},
+		{Range: entities.LineRange{Start: 4, End: 4}, Content: "four\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract = %#v, want %#v", got, want)
+	}
+
+	// A last line without a line break is still a line
+	if got := Extract("a\nb", []entities.LineRange{{Start: 2, End: 2}}); len(got) != 1 || got[0].Content != "b" {
+		t.Errorf("Extract of an unterminated last line = %#v", got)
+	}
+}
+
+// Deselecting part of a selection must keep every other line, even when the cut
+// lies in the middle of a range or spans several of them.
+func TestSubtract(t *testing.T) {
+	ranges := []entities.LineRange{{Start: 1, End: 10}, {Start: 20, End: 30}}
+	cases := []struct {
+		cut  entities.LineRange
+		want []entities.LineRange
+	}{
+		{entities.LineRange{Start: 4, End: 6}, []entities.LineRange{{Start: 1, End: 3}, {Start: 7, End: 10}, {Start: 20, End: 30}}},
+		{entities.LineRange{Start: 8, End: 25}, []entities.LineRange{{Start: 1, End: 7}, {Start: 26, End: 30}}},
+		{entities.LineRange{Start: 11, End: 19}, ranges},
+		{entities.LineRange{Start: 1, End: 30}, nil},
+	}
+	for _, c := range cases {
+		if got := Subtract(ranges, c.cut); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Subtract(%v) = %v, want %v", c.cut, got, c.want)
+		}
+	}
+
+	if got := Covered(ranges, entities.LineRange{Start: 8, End: 25}); got != 9 {
+		t.Errorf("Covered = %d, want 9", got)
+	}
+}
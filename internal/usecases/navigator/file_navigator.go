@@ -5,11 +5,28 @@ import (
 
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
 	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/outline"
 )
 
+// IgnoreMatcher decides whether a path is excluded by ignore rules
+type IgnoreMatcher interface {
+	IsIgnored(path string, isDir bool) bool
+
+	// Forget drops the rules read from the ignore file of a directory, so they are read again
+	Forget(dir string)
+}
+
+// LoadObserver is told about every directory whose entries the navigator reads, e.g. to watch it for changes
+type LoadObserver interface {
+	DirectoryLoaded(node *entities.FileNode)
+}
+
 // FileNavigator handles the navigation through the file tree
 type FileNavigator struct {
-	repo repositories.FileRepository
+	repo        repositories.FileRepository
+	ignore      IgnoreMatcher
+	showIgnored bool
+	observer    LoadObserver
 }
 
 // NewFileNavigator creates a new FileNavigator
@@ -19,6 +36,31 @@ func NewFileNavigator(repo repositories.FileRepository) *FileNavigator {
 	}
 }
 
+// SetIgnoreMatcher sets the matcher used to flag ignored nodes when building the tree
+func (fn *FileNavigator) SetIgnoreMatcher(matcher IgnoreMatcher) {
+	fn.ignore = matcher
+}
+
+// SetShowIgnored sets whether ignored nodes are visible
+func (fn *FileNavigator) SetShowIgnored(show bool) {
+	fn.showIgnored = show
+}
+
+// SetLoadObserver sets the observer told about every directory read from now on; nil tells no one
+func (fn *FileNavigator) SetLoadObserver(observer LoadObserver) {
+	fn.observer = observer
+}
+
+// ShowIgnored reports whether ignored nodes are visible
+func (fn *FileNavigator) ShowIgnored() bool {
+	return fn.showIgnored
+}
+
+// IsHidden reports whether a node is hidden because it is ignored
+func (fn *FileNavigator) IsHidden(node *entities.FileNode) bool {
+	return node.Ignored && !fn.showIgnored
+}
+
 // BuildRootNode creates the root node for the file tree
 func (fn *FileNavigator) BuildRootNode() (*entities.FileNode, error) {
 	rootPath, err := fn.repo.GetCurrentDirectory()
@@ -40,25 +82,99 @@ func (fn *FileNavigator) BuildTree(node *entities.FileNode) {
 	}
 
 	for _, entry := range entries {
-		childNode := entities.NewFileNode(
-			entry.Name(),
-			filepath.Join(node.Path, entry.Name()),
-			entry.IsDir(),
-			node,
-		)
-		node.Children = append(node.Children, childNode)
+		node.Children = append(node.Children, fn.newChild(node, entry))
 	}
+	fn.loaded(node)
 }
 
-// GetVisibleNodes returns a list of nodes that are currently visible based on the expanded state
+// Refresh reads the entries of a directory again so that its children match
+// the directory on disk. Entries that still exist keep
+// their nodes, with their expansion, selection, and children; new entries get
+// new nodes. It returns the nodes of the entries that no longer exist. A
+// directory that cannot be read is left as it is.
+func (fn *FileNavigator) Refresh(node *entities.FileNode) []*entities.FileNode {
+	entries, err := fn.repo.ReadDirectory(node.Path)
+	if err != nil {
+		return nil
+	}
+
+	existing := make(map[string]*entities.FileNode, len(node.Children))
+	for _, child := range node.Children {
+		existing[child.Name] = child
+	}
+	children := make([]*entities.FileNode, 0, len(entries))
+	for _, entry := range entries {
+		if child, ok := existing[entry.Name()]; ok && child.IsDir == entry.IsDir() {
+			delete(existing, entry.Name())
+			children = append(children, child)
+			continue
+		}
+		children = append(children, fn.newChild(node, entry))
+	}
+
+	var removed []*entities.FileNode
+	for _, child := range node.Children {
+		if existing[child.Name] == child {
+			removed = append(removed, child)
+		}
+	}
+	node.Children = children
+	fn.loaded(node)
+	return removed
+}
+
+// ReloadIgnoreRules rereads the ignore rules of a directory after its
+// .gitignore changed, and flags every loaded node under it again
+func (fn *FileNavigator) ReloadIgnoreRules(node *entities.FileNode) {
+	if fn.ignore == nil {
+		return
+	}
+	fn.ignore.Forget(node.Path)
+	fn.flagIgnored(node)
+}
+
+// flagIgnored flags the loaded nodes under node that the ignore rules exclude
+func (fn *FileNavigator) flagIgnored(node *entities.FileNode) {
+	for _, child := range node.Children {
+		child.Ignored = node.Ignored ||
+			(child.Symbol == nil && fn.ignore.IsIgnored(child.Path, child.IsDir))
+		fn.flagIgnored(child)
+	}
+}
+
+// newChild creates the node of a directory entry, flagged when it is ignored
+func (fn *FileNavigator) newChild(node *entities.FileNode, entry repositories.DirEntry) *entities.FileNode {
+	child := entities.NewFileNode(
+		entry.Name(),
+		filepath.Join(node.Path, entry.Name()),
+		entry.IsDir(),
+		node,
+	)
+	child.Ignored = node.Ignored ||
+		(fn.ignore != nil && fn.ignore.IsIgnored(child.Path, child.IsDir))
+	return child
+}
+
+// loaded tells the observer, if any, that the entries of a directory were read
+func (fn *FileNavigator) loaded(node *entities.FileNode) {
+	if fn.observer != nil {
+		fn.observer.DirectoryLoaded(node)
+	}
+}
+
+// GetVisibleNodes returns a list of nodes that are currently visible based on the expanded state,
+// including the declarations of expanded source files. Ignored nodes are left out unless ignored entries are shown.
 func (fn *FileNavigator) GetVisibleNodes(root *entities.FileNode) []*entities.FileNode {
 	var nodes []*entities.FileNode
 	var traverse func(node *entities.FileNode)
 
 	traverse = func(node *entities.FileNode) {
 		nodes = append(nodes, node)
-		if node.IsDir && node.Expanded {
+		if node.Expanded {
 			for _, child := range node.Children {
+				if fn.IsHidden(child) {
+					continue
+				}
 				traverse(child)
 			}
 		}
@@ -68,16 +184,66 @@ func (fn *FileNavigator) GetVisibleNodes(root *entities.FileNode) []*entities.Fi
 	return nodes
 }
 
-// ToggleExpand toggles the expanded state of a directory node
+// ToggleExpand toggles the expanded state of a directory node, or of a source
+// file listing its declarations. A file without declarations stays collapsed.
 func (fn *FileNavigator) ToggleExpand(node *entities.FileNode) {
-	if node.IsDir {
+	switch {
+	case node.IsDir:
+		node.Expanded = !node.Expanded
+		if node.Expanded {
+			fn.LoadChildren(node)
+		}
+	case fn.HasSymbols(node):
 		node.Expanded = !node.Expanded
-		if node.Expanded && len(node.Children) == 0 {
-			fn.BuildTree(node)
+		if node.Expanded {
+			fn.LoadSymbols(node)
+			node.Expanded = len(node.Children) > 0
 		}
 	}
 }
 
+// HasSymbols reports whether node is a file that can be expanded into its top-level declarations
+func (fn *FileNavigator) HasSymbols(node *entities.FileNode) bool {
+	return !node.IsDir && node.Symbol == nil && outline.Supported(node.Path)
+}
+
+// LoadSymbols replaces the children of a source file with its top-level
+// declarations, read afresh so they match the file on disk. A file that
+// cannot be read or parsed gets no children.
+func (fn *FileNavigator) LoadSymbols(node *entities.FileNode) {
+	node.Children = []*entities.FileNode{}
+	content, err := fn.repo.ReadFile(node.Path)
+	if err != nil {
+		return
+	}
+	symbols, err := outline.Declarations(node.Path, content)
+	if err != nil {
+		return
+	}
+
+	for i := range symbols {
+		child := entities.NewFileNode(symbols[i].Name, node.Path, false, node)
+		child.Symbol = &symbols[i]
+		child.Ignored = node.Ignored
+		node.Children = append(node.Children, child)
+	}
+}
+
+// LoadChildren builds the children of a directory node if they have not been built yet
+func (fn *FileNavigator) LoadChildren(node *entities.FileNode) {
+	if node.IsDir && len(node.Children) == 0 {
+		fn.BuildTree(node)
+	}
+}
+
+// NearestVisible returns node, or its closest ancestor when node is hidden because it is ignored
+func (fn *FileNavigator) NearestVisible(node *entities.FileNode) *entities.FileNode {
+	for node.Parent != nil && fn.IsHidden(node) {
+		node = node.Parent
+	}
+	return node
+}
+
 // GetNodeLevel returns the depth level of a node in the tree
 func (fn *FileNavigator) GetNodeLevel(node *entities.FileNode) int {
 	level := 0
@@ -150,3 +316,35 @@ func (fn *FileNavigator) MoveToPreviousDirectory(visibleNodes []*entities.FileNo
 
 	return currentNode
 }
+
+// AllNodes returns every node below root in depth-first order, loading the
+// children of directories that have not been expanded yet. Hidden nodes and
+// the contents of hidden directories are left out.
+func (fn *FileNavigator) AllNodes(root *entities.FileNode) []*entities.FileNode {
+	var nodes []*entities.FileNode
+	var traverse func(node *entities.FileNode)
+
+	traverse = func(node *entities.FileNode) {
+		fn.LoadChildren(node)
+		for _, child := range node.Children {
+			if fn.IsHidden(child) {
+				continue
+			}
+			nodes = append(nodes, child)
+			if child.IsDir {
+				traverse(child)
+			}
+		}
+	}
+
+	traverse(root)
+	return nodes
+}
+
+// Reveal expands every ancestor of node so that it becomes visible
+func (fn *FileNavigator) Reveal(node *entities.FileNode) {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		fn.LoadChildren(parent)
+		parent.Expanded = true
+	}
+}
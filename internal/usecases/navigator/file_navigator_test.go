@@ -29,6 +29,7 @@ func (m mockDirEntry) IsDir() bool  { return m.isDir }
 type mockFileRepo struct {
 	currentDir string
 	dirs       map[string][]repositories.DirEntry
+	files      map[string]string
 }
 
 func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return m.currentDir, nil }
@@ -39,7 +40,13 @@ func (m *mockFileRepo) ReadDirectory(path string) ([]repositories.DirEntry, erro
 	}
 	return entries, nil
 }
-func (m *mockFileRepo) ReadFile(string) ([]byte, error)              { return nil, nil }
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
 func (m *mockFileRepo) GetRelativePath(string, string) (string, error) { return "", nil }
 func (m *mockFileRepo) WriteToClipboard(string) error                  { return nil }
 
@@ -278,3 +285,203 @@ func TestMoveToNextDirectory_NodeNotInList(t *testing.T) {
 		t.Fatal("should return the same node when not found in visible list")
 	}
 }
+
+// mockIgnoreMatcher ignores a fixed set of paths
+type mockIgnoreMatcher map[string]bool
+
+func (m mockIgnoreMatcher) IsIgnored(path string, isDir bool) bool { return m[path] }
+
+func (m mockIgnoreMatcher) Forget(dir string) {}
+
+// Ignored entries must be flagged when built, inherited by their children,
+// and hidden from the visible list until the user asks to see them.
+func TestBuildTree_HidesIgnoredNodes(t *testing.T) {
+	repo := &mockFileRepo{
+		currentDir: "/root",
+		dirs: map[string][]repositories.DirEntry{
+			"/root":      {mockDirEntry{"dirA", true}, mockDirEntry{"file3.go", false}},
+			"/root/dirA": {mockDirEntry{"file1.go", false}},
+		},
+	}
+	nav := NewFileNavigator(repo)
+	nav.SetIgnoreMatcher(mockIgnoreMatcher{"/root/dirA": true})
+	root, _ := nav.BuildRootNode()
+	root.Expanded = true
+
+	dirA := root.Children[0]
+	if !dirA.Ignored || root.Children[1].Ignored {
+		t.Fatal("only dirA should be flagged as ignored")
+	}
+
+	visible := nav.GetVisibleNodes(root)
+	if len(visible) != 2 {
+		t.Fatalf("expected root and file3.go to be visible, got %d nodes", len(visible))
+	}
+
+	nav.SetShowIgnored(true)
+	nav.ToggleExpand(dirA)
+	if !dirA.Children[0].Ignored {
+		t.Fatal("children of an ignored directory should inherit the ignored flag")
+	}
+	visible = nav.GetVisibleNodes(root)
+	if len(visible) != 4 {
+		t.Fatalf("expected all 4 nodes when showing ignored entries, got %d", len(visible))
+	}
+
+	// Hiding again must move a cursor on a hidden node to its visible ancestor
+	nav.SetShowIgnored(false)
+	if nav.NearestVisible(dirA.Children[0]) != root {
+		t.Fatal("nearest visible node of a hidden file should be the root")
+	}
+}
+
+// The fuzzy finder searches paths in directories the user never opened.
+// AllNodes must load them without expanding anything, and Reveal must then
+// expand exactly the ancestors of the chosen node so the cursor can land on it.
+func TestAllNodesAndReveal(t *testing.T) {
+	root, nav := buildTestTree()
+
+	all := nav.AllNodes(root)
+	var names []string
+	for _, node := range all {
+		names = append(names, node.Name)
+	}
+	want := "dirA file1.go file2.go dirB file3.go"
+	if got := fmt.Sprint(names); got != "["+want+"]" {
+		t.Fatalf("expected depth-first order %s, got %s", want, got)
+	}
+	if root.Expanded || root.Children[0].Expanded {
+		t.Fatal("AllNodes must not expand directories")
+	}
+
+	file1 := root.Children[0].Children[0]
+	nav.Reveal(file1)
+	if !root.Expanded || !root.Children[0].Expanded || root.Children[1].Expanded {
+		t.Fatal("Reveal should expand only the ancestors of the node")
+	}
+	visible := nav.GetVisibleNodes(root)
+	found := false
+	for _, node := range visible {
+		found = found || node == file1
+	}
+	if !found {
+		t.Fatal("revealed node should be visible")
+	}
+}
+
+// Expanding a Go file lists its declarations below it, so they can be selected
+// one by one; a file that does not parse stays a plain, collapsed file.
+func TestToggleExpand_Symbols(t *testing.T) {
+	root, nav := buildTestTree()
+	nav.repo.(*mockFileRepo).files = map[string]string{
+		"/root/file3.go":      "package x\n\n// A is a type\ntype A struct{}\n\nfunc (a A) B() {}\n",
+		"/root/dirA/file1.go": "package x\nfunc {",
+	}
+	root.Expanded = true
+	file3 := root.Children[2]
+
+	nav.ToggleExpand(file3)
+	if !file3.Expanded || len(file3.Children) != 2 {
+		t.Fatalf("expected two declarations under the expanded file, got %d", len(file3.Children))
+	}
+	method := file3.Children[1]
+	if method.Name != "func (a A) B" || method.Path != file3.Path || method.Symbol == nil ||
+		method.Symbol.Lines != (entities.LineRange{Start: 6, End: 6}) {
+		t.Fatalf("unexpected declaration node %+v", method)
+	}
+	if nav.HasSymbols(method) {
+		t.Fatal("a declaration must not expand any further")
+	}
+	visible := nav.GetVisibleNodes(root)
+	if len(visible) != 6 || visible[4] != file3.Children[0] || nav.GetNodeLevel(method) != 2 {
+		t.Fatalf("declarations should be visible below their file, got %d nodes", len(visible))
+	}
+
+	nav.ToggleExpand(file3)
+	if file3.Expanded || len(nav.GetVisibleNodes(root)) != 4 {
+		t.Fatal("collapsing the file should hide its declarations")
+	}
+
+	nav.ToggleExpand(root.Children[0])
+	file1 := root.Children[0].Children[0]
+	nav.ToggleExpand(file1)
+	if file1.Expanded {
+		t.Fatal("a file without declarations should stay collapsed")
+	}
+}
+
+type loadRecorder []string
+
+func (r *loadRecorder) DirectoryLoaded(node *entities.FileNode) { *r = append(*r, node.Path) }
+
+// Files created or deleted while the UI runs must show up without losing what
+// the user expanded and selected: Refresh keeps the nodes of entries that still
+// exist, adds the new ones, and returns the removed ones so they can be
+// deselected. Every directory read is reported so it can be watched.
+func TestRefresh_KeepsExistingNodes(t *testing.T) {
+	root, nav := buildTestTree()
+	var loads loadRecorder
+	nav.SetLoadObserver(&loads)
+	root.Expanded = true
+	dirA := root.Children[0]
+	nav.ToggleExpand(dirA)
+	dirA.Children[0].Selected = true
+
+	repo := nav.repo.(*mockFileRepo)
+	repo.dirs["/root"] = []repositories.DirEntry{
+		mockDirEntry{"dirA", true},
+		mockDirEntry{"file3.go", true},
+		mockDirEntry{"new.go", false},
+	}
+	removed := nav.Refresh(root)
+
+	var names []string
+	for _, child := range root.Children {
+		names = append(names, child.Name)
+	}
+	if got := fmt.Sprint(names); got != "[dirA file3.go new.go]" {
+		t.Fatalf("unexpected children after refresh: %s", got)
+	}
+	if root.Children[0] != dirA || !dirA.Expanded || !dirA.Children[0].Selected {
+		t.Fatal("an existing directory should keep its node, expansion, and selected children")
+	}
+	if !root.Children[1].IsDir || root.Children[2].Parent != root {
+		t.Fatal("an entry that became a directory, and a new entry, should get new nodes")
+	}
+	if len(removed) != 2 || removed[0].Name != "dirB" || removed[1].Name != "file3.go" || removed[1].IsDir {
+		t.Fatalf("expected dirB and the old file3.go to be removed, got %v", removed)
+	}
+	if got := fmt.Sprint(loads); got != "[/root/dirA /root]" {
+		t.Fatalf("expected the loaded directories to be reported, got %s", got)
+	}
+
+	if len(nav.Refresh(&entities.FileNode{Path: "/missing", IsDir: true})) != 0 {
+		t.Fatal("a directory that cannot be read should be left as it is")
+	}
+}
+
+// After a .gitignore changes, nodes already loaded must be flagged with the
+// new rules, or entries stay hidden or shown until the tool restarts.
+func TestReloadIgnoreRules(t *testing.T) {
+	repo := &mockFileRepo{
+		currentDir: "/root",
+		dirs: map[string][]repositories.DirEntry{
+			"/root":      {mockDirEntry{"dirA", true}, mockDirEntry{"file3.go", false}},
+			"/root/dirA": {mockDirEntry{"file1.go", false}},
+		},
+	}
+	matcher := mockIgnoreMatcher{"/root/file3.go": true}
+	nav := NewFileNavigator(repo)
+	nav.SetIgnoreMatcher(matcher)
+	root, _ := nav.BuildRootNode()
+	dirA, file3 := root.Children[0], root.Children[1]
+	nav.ToggleExpand(dirA)
+
+	delete(matcher, "/root/file3.go")
+	matcher["/root/dirA"] = true
+	nav.ReloadIgnoreRules(root)
+
+	if file3.Ignored || !dirA.Ignored || !dirA.Children[0].Ignored {
+		t.Fatal("loaded nodes should be flagged again with the changed rules")
+	}
+}
@@ -0,0 +1,145 @@
+package outline
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// maxGroupNames is how many names of a grouped declaration ("const (A, B, C, …)") are shown
+const maxGroupNames = 3
+
+// Supported reports whether an outline can be made of the file at path
+func Supported(path string) bool {
+	return filepath.Ext(path) == ".go"
+}
+
+// Go returns the outline of a Go source file: the package clause, imports,
+// constant, variable, and type declarations, and the signatures of functions
+// and methods, each with its doc comment. Function bodies, and the comments
+// inside them, are left out. filename is only used in error messages.
+func Go(filename string, src []byte) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	var bodies []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			bodies = append(bodies, fn.Body)
+			fn.Body = nil
+		}
+	}
+
+	// The printer places every comment of the file, so drop the ones of the removed bodies
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		if !within(group, bodies) {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// within reports whether node lies inside one of the blocks
+func within(node ast.Node, blocks []*ast.BlockStmt) bool {
+	for _, block := range blocks {
+		if node.Pos() >= block.Pos() && node.End() <= block.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// Declarations returns the top-level declarations of a Go source file in source
+// order: functions, methods, types, constants, and variables, each with the
+// lines it spans including its doc comment and the number of lines of the
+// file. Imports are left out. filename is only used in error messages.
+func Declarations(filename string, src []byte) ([]entities.Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	fileLines := fset.File(file.Pos()).LineCount()
+	var symbols []entities.Symbol
+	for _, decl := range file.Decls {
+		var name string
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name, doc = funcName(d), d.Doc
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			name, doc = genName(d), d.Doc
+		default:
+			continue
+		}
+
+		start := decl.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		symbols = append(symbols, entities.Symbol{
+			Name:      name,
+			Lines:     entities.LineRange{Start: fset.Position(start).Line, End: fset.Position(decl.End()).Line},
+			FileLines: fileLines,
+		})
+	}
+	return symbols, nil
+}
+
+// funcName names a function as "func Name", or a method as "func (fn *FileNavigator) Name"
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return "func " + fn.Name.Name
+	}
+	recv := fn.Recv.List[0]
+	receiver := types.ExprString(recv.Type)
+	if len(recv.Names) > 0 {
+		receiver = recv.Names[0].Name + " " + receiver
+	}
+	return "func (" + receiver + ") " + fn.Name.Name
+}
+
+// genName names a type, constant, or variable declaration as "type FileNode",
+// or a grouped one by its first names, as "const (Unselected, PartiallySelected, …)"
+func genName(decl *ast.GenDecl) string {
+	var names []string
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, ident := range s.Names {
+				names = append(names, ident.Name)
+			}
+		}
+	}
+
+	if !decl.Lparen.IsValid() && len(names) == 1 {
+		return decl.Tok.String() + " " + names[0]
+	}
+	if len(names) > maxGroupNames {
+		names = append(names[:maxGroupNames], "…")
+	}
+	return decl.Tok.String() + " (" + strings.Join(names, ", ") + ")"
+}
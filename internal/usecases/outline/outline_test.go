@@ -0,0 +1,135 @@
+package outline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test the outline?
+//
+// The outline is handed to a model as the API surface of a package. A
+// signature or doc comment lost on the way misleads it, and a body or body
+// comment that slips through defeats the point of the outline.
+
+const source = `// Package shapes computes areas.
+package shapes
+
+import "math"
+
+// Pi is used for circles
+const Pi = math.Pi
+
+// Shape has an area
+type Shape interface {
+	Area() float64
+}
+
+// Circle is a round Shape
+type Circle struct {
+	R float64 // Radius
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64 {
+	// The classic formula
+	return Pi * c.R * c.R
+}
+
+func helper(x int) (int, error) {
+	return x, nil
+}
+`
+
+// Declarations, signatures, and doc comments stay; bodies and their comments go.
+func TestGo(t *testing.T) {
+	got, err := Go("shapes.go", []byte(source))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"// Package shapes computes areas.\npackage shapes",
+		`import "math"`,
+		"// Pi is used for circles\nconst Pi = math.Pi",
+		"type Shape interface {\n\tArea() float64\n}",
+		"R float64 // Radius",
+		"// Area returns the area of the circle\nfunc (c Circle) Area() float64\n",
+		"func helper(x int) (int, error)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("outline should contain %q, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"return Pi", "return x", "classic formula"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("outline should not contain %q, got:\n%s", unwanted, got)
+		}
+	}
+}
+
+// A file that does not parse cannot be outlined; the caller decides what to do instead.
+func TestGo_SyntaxError(t *testing.T) {
+	if _, err := Go("broken.go", []byte("package x\nfunc {")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if Supported("README.md") || !Supported("internal/app/app.go") {
+		t.Fatal("only .go files are supported")
+	}
+}
+
+// Each declaration becomes a selectable entry whose lines, doc comment included,
+// are what gets copied, so an off-by-one loses a line of code or of its comment.
+func TestDeclarations(t *testing.T) {
+	got, err := Declarations("shapes.go", []byte(source))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []entities.Symbol{
+		{Name: "const Pi", Lines: entities.LineRange{Start: 6, End: 7}, FileLines: 27},
+		{Name: "type Shape", Lines: entities.LineRange{Start: 9, End: 12}, FileLines: 27},
+		{Name: "type Circle", Lines: entities.LineRange{Start: 14, End: 17}, FileLines: 27},
+		{Name: "func (c Circle) Area", Lines: entities.LineRange{Start: 19, End: 23}, FileLines: 27},
+		{Name: "func helper", Lines: entities.LineRange{Start: 25, End: 27}, FileLines: 27},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// Grouped declarations are listed once, named by their first few names, and
+// generic or unnamed receivers still name the method's type.
+func TestDeclarations_GroupsAndReceivers(t *testing.T) {
+	src := `package x
+
+const (
+	A = iota
+	B
+	C
+	D
+)
+
+var one, two int
+
+func (*List[T]) Len() int { return 0 }
+`
+	got, err := Declarations("x.go", []byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, symbol := range got {
+		names = append(names, symbol.Name)
+	}
+	want := []string{"const (A, B, C, …)", "var (one, two)", "func (*List[T]) Len"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+	if got[0].Lines != (entities.LineRange{Start: 3, End: 8}) {
+		t.Errorf("the group should span its parentheses, got %v", got[0].Lines)
+	}
+}
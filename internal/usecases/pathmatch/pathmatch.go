@@ -0,0 +1,62 @@
+package pathmatch
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash-separated path name matches pattern.
+//
+// Each pattern segment is matched against one path segment with path.Match
+// semantics ('*', '?', and character classes never cross a '/'). A segment
+// consisting of "**" matches zero or more whole path segments, so
+// "internal/**/*.go" matches both "internal/a.go" and "internal/x/y/b.go".
+// A malformed pattern never matches.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Valid reports whether pattern is well-formed
+func Valid(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegments matches pattern segments against name segments, expanding "**"
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive "**" segments
+			rest := pattern[1:]
+			for len(rest) > 0 && rest[0] == "**" {
+				rest = rest[1:]
+			}
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,46 @@
+package pathmatch
+
+import "testing"
+
+// Why test Match?
+//
+// Glob patterns decide which files are hidden by .gitignore and which files
+// a scripted copy picks up. "**" handling is the subtle part: it must match
+// zero segments as well as many, and "*" must never cross a directory
+// boundary, otherwise "*.go" would silently pull in every nested package.
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c/main.go", true},
+		{"internal/**/*.go", "internal/app.go", true},
+		{"internal/**/*.go", "internal/a/b/app.go", true},
+		{"internal/**/*.go", "cmd/app.go", false},
+		{"**/*_test.go", "internal/x/file_test.go", true},
+		{"node_modules/**", "node_modules/a/b.js", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b", "a/b", true},
+		{"src/?.go", "src/a.go", true},
+		{"src/[ab].go", "src/c.go", false},
+		{"src/[", "src/[", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.name); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid("internal/**/*.go") {
+		t.Error("pattern with ** should be valid")
+	}
+	if Valid("src/[") {
+		t.Error("unterminated character class should be invalid")
+	}
+}
@@ -0,0 +1,65 @@
+package preview
+
+import (
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+)
+
+// MaxBytes is the number of bytes of a file read into a preview, which keeps previews of large files cheap to render
+const MaxBytes = 256 << 10
+
+// Preview is the content of a file prepared for display
+type Preview struct {
+	Path      string   // Full path of the file
+	Lines     []string // Lines of the file without line endings; empty for binary files
+	Binary    bool     // The file looks binary and has no text to show
+	Encoding  string   // Encoding the text was decoded from, e.g. "shift_jis"; empty for binary files
+	Truncated bool     // The file is longer than MaxBytes; Lines holds its beginning
+}
+
+// Previewer reads files for the preview pane
+type Previewer struct {
+	repo repositories.FileRepository
+}
+
+// NewPreviewer creates a new Previewer
+func NewPreviewer(repo repositories.FileRepository) *Previewer {
+	return &Previewer{repo: repo}
+}
+
+// Load reads the file at path, decodes it to UTF-8, and splits it into lines
+func (p *Previewer) Load(path string) (Preview, error) {
+	content, err := p.repo.ReadFile(path)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Preview{Path: path}
+	binary, enc := classify.Detect(content, false)
+	if binary {
+		preview.Binary = true
+		return preview, nil
+	}
+	preview.Encoding = enc
+
+	decoded, err := classify.Decode(content, enc)
+	if err != nil {
+		preview.Binary = true
+		return preview, nil
+	}
+	if len(decoded) > MaxBytes {
+		// Cut at the last complete line so no partial line is shown
+		decoded = decoded[:MaxBytes]
+		if i := strings.LastIndexByte(decoded, '\n'); i >= 0 {
+			decoded = decoded[:i]
+		}
+		preview.Truncated = true
+	}
+
+	text := strings.ReplaceAll(decoded, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	preview.Lines = strings.Split(text, "\n")
+	return preview, nil
+}
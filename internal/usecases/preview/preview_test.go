@@ -0,0 +1,101 @@
+package preview
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+)
+
+// Why test Previewer?
+//
+// The TUI preview numbers lines from what Load returns, so an extra empty
+// line or a stray carriage return shifts or garbles the display. Binary and
+// huge files must not be dumped into the terminal.
+
+// --- mock repository ---
+
+type mockFileRepo struct {
+	files map[string]string
+}
+
+func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return "/repo", nil }
+func (m *mockFileRepo) ReadDirectory(string) ([]repositories.DirEntry, error) {
+	return nil, nil
+}
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
+func (m *mockFileRepo) GetRelativePath(string, string) (string, error) { return "", nil }
+func (m *mockFileRepo) WriteToClipboard(string) error                  { return nil }
+
+// Lines are split without endings, and the final newline does not add an empty line.
+func TestLoad_SplitsLines(t *testing.T) {
+	p := NewPreviewer(&mockFileRepo{files: map[string]string{
+		"/repo/unix.go": "package main\n\nfunc main() {}\n",
+		"/repo/dos.txt": "one\r\ntwo\r\n",
+	}})
+
+	preview, err := p.Load("/repo/unix.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Lines) != 3 || preview.Lines[2] != "func main() {}" {
+		t.Fatalf("unexpected lines %q", preview.Lines)
+	}
+
+	preview, _ = p.Load("/repo/dos.txt")
+	if strings.Join(preview.Lines, "|") != "one|two" {
+		t.Fatalf("CRLF endings should be removed, got %q", preview.Lines)
+	}
+
+	if _, err := p.Load("/repo/missing"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+// Binary files have no lines, and large files are cut at a line boundary.
+func TestLoad_BinaryAndTruncated(t *testing.T) {
+	big := strings.Repeat("0123456789abcdef\n", MaxBytes/17+10)
+	p := NewPreviewer(&mockFileRepo{files: map[string]string{
+		"/repo/logo.png": "\x89PNG\x00\x01",
+		"/repo/big.txt":  big,
+	}})
+
+	preview, _ := p.Load("/repo/logo.png")
+	if !preview.Binary || len(preview.Lines) != 0 {
+		t.Fatalf("expected a binary preview, got %+v", preview)
+	}
+
+	preview, _ = p.Load("/repo/big.txt")
+	if !preview.Truncated {
+		t.Fatal("expected the preview to be truncated")
+	}
+	if last := preview.Lines[len(preview.Lines)-1]; last != "0123456789abcdef" {
+		t.Fatalf("truncated preview should end with a complete line, got %q", last)
+	}
+}
+
+// Text in legacy encodings is shown decoded, and UTF-16 is not mistaken for binary.
+func TestLoad_DecodesLegacyEncodings(t *testing.T) {
+	p := NewPreviewer(&mockFileRepo{files: map[string]string{
+		"/repo/sjis.txt":  "\x93\xfa\x96\x7b\x8c\xea\n\x82\xa0\n",
+		"/repo/utf16.txt": "\xff\xfeh\x00i\x00\n\x00",
+	}})
+
+	preview, err := p.Load("/repo/sjis.txt")
+	if err != nil || preview.Encoding != classify.ShiftJIS || strings.Join(preview.Lines, "|") != "日本語|あ" {
+		t.Fatalf("unexpected Shift_JIS preview %+v, %v", preview, err)
+	}
+
+	preview, err = p.Load("/repo/utf16.txt")
+	if err != nil || preview.Binary || preview.Encoding != classify.UTF16LE || strings.Join(preview.Lines, "|") != "hi" {
+		t.Fatalf("unexpected UTF-16 preview %+v, %v", preview, err)
+	}
+}
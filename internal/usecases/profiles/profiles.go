@@ -0,0 +1,67 @@
+package profiles
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+)
+
+// validName restricts profile names to safe file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName returns an error when name cannot be used as a profile name
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// LoadResult describes the outcome of loading a profile
+type LoadResult struct {
+	Selected int      // Number of files selected
+	Missing  []string // Root-relative paths of the profile that no longer exist as files
+}
+
+// Manager saves the current selection as a named profile and restores it later
+type Manager struct {
+	repo     repositories.ProfileRepository
+	selector *selector.FileSelector
+}
+
+// NewManager creates a new Manager
+func NewManager(repo repositories.ProfileRepository, selector *selector.FileSelector) *Manager {
+	return &Manager{repo: repo, selector: selector}
+}
+
+// Names returns the names of all saved profiles
+func (m *Manager) Names() ([]string, error) {
+	return m.repo.ListProfiles()
+}
+
+// Save stores the current selection, relative to root, under name
+func (m *Manager) Save(name string, root *entities.FileNode) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	return m.repo.SaveProfile(name, m.selector.RelativePaths(root))
+}
+
+// Load replaces the current selection with the files of the profile called name.
+// Paths that no longer exist are reported in the result instead of being dropped silently.
+func (m *Manager) Load(name string, root *entities.FileNode) (LoadResult, error) {
+	if err := ValidateName(name); err != nil {
+		return LoadResult{}, err
+	}
+	paths, err := m.repo.LoadProfile(name)
+	if err != nil {
+		return LoadResult{}, err
+	}
+
+	m.selector.ClearSelection()
+	missing := m.selector.SelectPaths(root, paths)
+	return LoadResult{Selected: len(paths) - len(missing), Missing: missing}, nil
+}
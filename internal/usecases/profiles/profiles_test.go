@@ -0,0 +1,103 @@
+package profiles
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/selector"
+)
+
+// Why test the profile manager?
+//
+// Profiles replace re-selecting the same files every day. Saving and loading
+// must restore exactly the same selection, report files that disappeared in
+// the meantime, and refuse names that would escape the profile directory.
+
+// mockProfileRepo keeps profiles in memory
+type mockProfileRepo struct {
+	profiles map[string][]string
+}
+
+func (m *mockProfileRepo) SaveProfile(name string, paths []string) error {
+	m.profiles[name] = paths
+	return nil
+}
+
+func (m *mockProfileRepo) LoadProfile(name string) ([]string, error) {
+	paths, ok := m.profiles[name]
+	if !ok {
+		return nil, repositories.ErrProfileNotFound
+	}
+	return paths, nil
+}
+
+func (m *mockProfileRepo) ListProfiles() ([]string, error) { return nil, nil }
+
+// buildTree creates /p with a.go and b.go
+func buildTree() *entities.FileNode {
+	root := entities.NewFileNode("p", "/p", true, nil)
+	root.Children = []*entities.FileNode{
+		entities.NewFileNode("a.go", "/p/a.go", false, root),
+		entities.NewFileNode("b.go", "/p/b.go", false, root),
+	}
+	return root
+}
+
+// A saved profile must replace whatever is selected when it is loaded, and
+// paths that no longer exist must be reported.
+func TestManager_SaveAndLoad(t *testing.T) {
+	repo := &mockProfileRepo{profiles: map[string][]string{}}
+	sel := selector.NewFileSelector()
+	manager := NewManager(repo, sel)
+	root := buildTree()
+
+	sel.ToggleSelect(root.Children[0])
+	if err := manager.Save("morning", root); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(repo.profiles["morning"], []string{"a.go"}) {
+		t.Fatalf("saved paths = %v", repo.profiles["morning"])
+	}
+
+	// Switch to another file, then restore the profile with a deleted file added
+	sel.ToggleSelect(root.Children[0])
+	sel.ToggleSelect(root.Children[1])
+	repo.profiles["morning"] = []string{"a.go", "deleted.go"}
+
+	result, err := manager.Load("morning", root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Selected != 1 || !reflect.DeepEqual(result.Missing, []string{"deleted.go"}) {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !root.Children[0].Selected || root.Children[1].Selected {
+		t.Fatal("loading should select a.go and deselect b.go")
+	}
+}
+
+// Unknown profiles must surface ErrProfileNotFound so callers can tell a
+// typo from a broken file.
+func TestManager_LoadUnknownProfile(t *testing.T) {
+	manager := NewManager(&mockProfileRepo{profiles: map[string][]string{}}, selector.NewFileSelector())
+	if _, err := manager.Load("nope", buildTree()); !errors.Is(err, repositories.ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+// Names are used as file names, so separators and dot-dot must be rejected.
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"daily", "review-2", "v1.2_x"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", "a/b", ".hidden", "a b"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("%q should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/usecases/pathmatch"
+)
+
+// Sensitive file policies
+const (
+	SensitiveWarn  = "warn"  // Copy sensitive files, redacted, and warn about them
+	SensitiveBlock = "block" // Refuse to copy while sensitive files are selected
+)
+
+// ErrSensitiveFiles is wrapped by the error Check returns when sensitive files are blocked
+var ErrSensitiveFiles = errors.New("sensitive files selected")
+
+// placeholderPrefix starts the text a secret is replaced with, e.g. "[REDACTED:aws-access-key]"
+const placeholderPrefix = "[REDACTED:"
+
+// Rules configures a Redactor. File patterns use pathmatch syntax; a pattern
+// without '/' is matched against the base name. Value patterns are regular
+// expressions; when one has a group named "secret", only that group is hidden.
+type Rules struct {
+	Disabled       bool     `json:"disabled,omitempty"`       // Copy contents verbatim
+	SensitiveFiles string   `json:"sensitiveFiles,omitempty"` // SensitiveWarn (default) or SensitiveBlock
+	Allow          []string `json:"allow,omitempty"`          // Values that are kept although they look like secrets
+	Deny           []string `json:"deny,omitempty"`           // Values that are always hidden
+	AllowFiles     []string `json:"allowFiles,omitempty"`     // Files that are neither redacted nor reported as sensitive
+	DenyFiles      []string `json:"denyFiles,omitempty"`      // More file names to treat as sensitive
+}
+
+// detector finds one kind of secret
+type detector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// builtinDetectors find common credentials. Assignments only hide the quoted value.
+var builtinDetectors = []detector{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-key", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[sr]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"password", regexp.MustCompile(`(?i)(?:password|passwd|secret|api_?key|access_?token|auth_?token)["']?\s*(?::=|=>|[:=])\s*["'](?P<secret>[^"'\s]{8,})["']`)},
+}
+
+// entropyCandidate finds the strings the high-entropy detector inspects
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+
+// minEntropy is the bits per character of a generated key; identifiers and hex digests stay below
+const minEntropy = 4.5
+
+// defaultSensitiveFiles are names that usually hold credentials
+var defaultSensitiveFiles = []string{
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "*.jks", "*.keystore",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519", ".npmrc", ".pypirc", ".netrc", ".htpasswd",
+	"credentials", "credentials.json", "*.tfstate",
+}
+
+// defaultAllowedFiles are templates of sensitive files that hold no real values
+var defaultAllowedFiles = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+
+// checksumFiles are full of hashes the high-entropy detector would hide
+var checksumFiles = []string{"go.sum", "*.lock", "package-lock.json", "pnpm-lock.yaml", "yarn.lock"}
+
+// Redactor replaces secrets in copied contents with placeholders and flags sensitive files
+type Redactor struct {
+	disabled   bool
+	block      bool
+	detectors  []detector
+	allow      []*regexp.Regexp
+	allowFiles []string
+	denyFiles  []string
+}
+
+// NewRedactor creates a Redactor with the built-in detectors and file names extended by rules
+func NewRedactor(rules Rules) (*Redactor, error) {
+	switch rules.SensitiveFiles {
+	case "", SensitiveWarn, SensitiveBlock:
+	default:
+		return nil, fmt.Errorf("unknown sensitive file policy %q (available: %s, %s)", rules.SensitiveFiles, SensitiveWarn, SensitiveBlock)
+	}
+
+	r := &Redactor{
+		disabled:   rules.Disabled,
+		block:      rules.SensitiveFiles == SensitiveBlock,
+		detectors:  append([]detector{}, builtinDetectors...),
+		allowFiles: append(append([]string{}, defaultAllowedFiles...), rules.AllowFiles...),
+		denyFiles:  append(append([]string{}, defaultSensitiveFiles...), rules.DenyFiles...),
+	}
+	for _, pattern := range append(append([]string{}, rules.AllowFiles...), rules.DenyFiles...) {
+		if !pathmatch.Valid(pattern) {
+			return nil, fmt.Errorf("invalid file pattern %q", pattern)
+		}
+	}
+	for _, expr := range rules.Allow {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow rule %q: %w", expr, err)
+		}
+		r.allow = append(r.allow, re)
+	}
+	for _, expr := range rules.Deny {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny rule %q: %w", expr, err)
+		}
+		r.detectors = append(r.detectors, detector{name: "deny", re: re})
+	}
+	return r, nil
+}
+
+// Redact returns content with every secret found replaced by a placeholder
+// naming its kind, and the number of secrets replaced. A placeholder keeps the
+// line breaks of the secret, so line numbers and ranges stay valid. path is
+// the slash-separated path of the file, relative to the root.
+func (r *Redactor) Redact(path, content string) (string, int) {
+	if r.disabled || matchesFile(r.allowFiles, path) {
+		return content, 0
+	}
+
+	count := 0
+	for _, d := range r.detectors {
+		content = r.replace(d.re, content, d.name, &count)
+	}
+	if !matchesFile(checksumFiles, path) {
+		content = entropyCandidate.ReplaceAllStringFunc(content, func(value string) string {
+			if !looksRandom(value) || r.allowed(value) {
+				return value
+			}
+			count++
+			return placeholderPrefix + "high-entropy]"
+		})
+	}
+	return content, count
+}
+
+// replace hides the matches of re in content, or only their "secret" group when re has one
+func (r *Redactor) replace(re *regexp.Regexp, content, name string, count *int) string {
+	group := re.SubexpIndex("secret")
+	var s strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		if group >= 0 && match[2*group] >= 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		value := content[start:end]
+		if strings.HasPrefix(value, placeholderPrefix) || r.allowed(value) {
+			continue
+		}
+		*count++
+		s.WriteString(content[last:start])
+		s.WriteString(placeholderPrefix + name + "]")
+		s.WriteString(strings.Repeat("\n", strings.Count(value, "\n")))
+		last = end
+	}
+	s.WriteString(content[last:])
+	return s.String()
+}
+
+// allowed reports whether an allow rule keeps value
+func (r *Redactor) allowed(value string) bool {
+	for _, re := range r.allow {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sensitive reports whether the file at the slash-separated path is named like one holding credentials
+func (r *Redactor) Sensitive(path string) bool {
+	return matchesFile(r.denyFiles, path) && !matchesFile(r.allowFiles, path)
+}
+
+// Check returns the sensitive files among paths. When the policy blocks them,
+// the error wraps ErrSensitiveFiles and names them.
+func (r *Redactor) Check(paths []string) ([]string, error) {
+	var sensitive []string
+	for _, p := range paths {
+		if r.Sensitive(p) {
+			sensitive = append(sensitive, p)
+		}
+	}
+	if r.block && len(sensitive) > 0 {
+		return sensitive, fmt.Errorf("%w: %s", ErrSensitiveFiles, strings.Join(sensitive, ", "))
+	}
+	return sensitive, nil
+}
+
+// matchesFile reports whether the slash-separated path matches a pattern; patterns without '/' match the base name
+func matchesFile(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		name := p
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(p)
+		}
+		if pathmatch.Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandom reports whether value mixes cases and digits with the entropy of a generated key
+func looksRandom(value string) bool {
+	var upper, lower, digit bool
+	freq := make(map[rune]int)
+	for _, c := range value {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+		freq[c]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, n := range freq {
+		p := float64(n) / float64(len(value))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
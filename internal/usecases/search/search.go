@@ -0,0 +1,254 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+)
+
+// Limits that keep a search over a large tree responsive
+const (
+	MaxFiles       = 500  // Files reported before the results are truncated
+	MaxPreviews    = 3    // Matching lines kept per file as previews
+	headSize       = 8000 // Bytes read to skip binary files before reading all of them
+	previewLength  = 120  // Bytes of a line kept around the first match
+	previewContext = 30   // Bytes kept before the first match when a line is cut
+)
+
+// ErrEmptyPattern is returned when searching for an empty pattern
+var ErrEmptyPattern = errors.New("search pattern is empty")
+
+// Repository reads the directories and files searched, and the beginning of a
+// file to skip oversized and binary ones without loading them
+type Repository interface {
+	repositories.FileRepository
+	classify.HeadReader
+}
+
+// IgnoreMatcher decides whether a path is excluded by ignore rules
+type IgnoreMatcher interface {
+	IsIgnored(path string, isDir bool) bool
+}
+
+// Query describes what to search for.
+// Matching is case-insensitive unless the pattern contains an upper-case letter.
+type Query struct {
+	Pattern string // Text to find, or a regular expression when Regex is set
+	Regex   bool   // Interpret Pattern as a Go regular expression (RE2 syntax)
+}
+
+// Match is a line of a file that matches the query
+type Match struct {
+	Line  int    // 1-based line number
+	Text  string // The line, without surrounding whitespace and cut around the match when long
+	Start int    // Byte offset of the first match in Text
+	End   int    // Byte offset just after the first match in Text
+}
+
+// FileHit lists the matches in one file
+type FileHit struct {
+	Path    string  // Slash-separated path relative to the root
+	Count   int     // Number of matching lines
+	Matches []Match // The first MaxPreviews matching lines
+}
+
+// Results are the files that match a query, in tree order
+type Results struct {
+	Files     []FileHit
+	Truncated bool // More than MaxFiles files matched; only the first MaxFiles are listed
+}
+
+// Paths returns the root-relative paths of the matching files
+func (r Results) Paths() []string {
+	paths := make([]string, len(r.Files))
+	for i, file := range r.Files {
+		paths[i] = file.Path
+	}
+	return paths
+}
+
+// Searcher scans the contents of the files under a root directory.
+// Ignored entries are skipped, as are binary and oversized files.
+type Searcher struct {
+	repo    Repository
+	ignore  IgnoreMatcher
+	root    string
+	maxSize int64 // Larger files are skipped; 0 means no limit
+}
+
+// NewSearcher creates a Searcher for the files under root, skipping files over classify.DefaultMaxSize
+func NewSearcher(repo Repository, root string) *Searcher {
+	return &Searcher{repo: repo, root: root, maxSize: classify.DefaultMaxSize}
+}
+
+// SetMaxFileSize sets the size above which files are skipped, as they are
+// copied as a stub; 0 means no limit
+func (s *Searcher) SetMaxFileSize(size int64) {
+	s.maxSize = max(size, 0)
+}
+
+// oversized reports whether a file of size bytes is skipped
+func (s *Searcher) oversized(size int64) bool {
+	return s.maxSize > 0 && size > s.maxSize
+}
+
+// SetIgnoreMatcher sets the matcher of entries to leave out of searches
+func (s *Searcher) SetIgnoreMatcher(matcher IgnoreMatcher) {
+	s.ignore = matcher
+}
+
+// Search returns the files whose contents match the query
+func (s *Searcher) Search(query Query) (Results, error) {
+	re, err := compile(query)
+	if err != nil {
+		return Results{}, err
+	}
+
+	var results Results
+	var walk func(dir, rel string) bool
+	walk = func(dir, rel string) bool {
+		entries, err := s.repo.ReadDirectory(dir)
+		if err != nil {
+			return true
+		}
+		for _, entry := range entries {
+			fullPath := filepath.Join(dir, entry.Name())
+			if s.ignore != nil && s.ignore.IsIgnored(fullPath, entry.IsDir()) {
+				continue
+			}
+			childRel := entry.Name()
+			if rel != "" {
+				childRel = rel + "/" + entry.Name()
+			}
+
+			if entry.IsDir() {
+				if !walk(fullPath, childRel) {
+					return false
+				}
+				continue
+			}
+
+			hit, ok := s.searchFile(re, fullPath, childRel)
+			if !ok {
+				continue
+			}
+			if len(results.Files) == MaxFiles {
+				results.Truncated = true
+				return false
+			}
+			results.Files = append(results.Files, hit)
+		}
+		return true
+	}
+
+	walk(s.root, "")
+	return results, nil
+}
+
+// searchFile matches every line of the file at fullPath, decoded to UTF-8.
+// Unreadable, oversized, and binary files never match; oversized and binary
+// files are told from their size and first bytes, without reading all of them.
+func (s *Searcher) searchFile(re *regexp.Regexp, fullPath, rel string) (FileHit, bool) {
+	head, size, err := s.repo.ReadHead(fullPath, headSize)
+	if err != nil || s.oversized(size) {
+		return FileHit{}, false
+	}
+	if binary, _ := classify.Detect(head, int64(len(head)) < size); binary {
+		return FileHit{}, false
+	}
+
+	content, err := s.repo.ReadFile(fullPath)
+	if err != nil || s.oversized(int64(len(content))) {
+		return FileHit{}, false
+	}
+	binary, enc := classify.Detect(content, false)
+	if binary {
+		return FileHit{}, false
+	}
+	text, err := classify.Decode(content, enc)
+	if err != nil {
+		return FileHit{}, false
+	}
+
+	hit := FileHit{Path: rel}
+	for i, line := range strings.Split(text, "\n") {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		hit.Count++
+		if len(hit.Matches) < MaxPreviews {
+			hit.Matches = append(hit.Matches, preview(i+1, line, loc[0], loc[1]))
+		}
+	}
+	return hit, hit.Count > 0
+}
+
+// compile turns a query into a regular expression, applying smart case
+func compile(query Query) (*regexp.Regexp, error) {
+	if query.Pattern == "" {
+		return nil, ErrEmptyPattern
+	}
+
+	expr := query.Pattern
+	if !query.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !strings.ContainsFunc(query.Pattern, unicode.IsUpper) {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// preview trims a matching line and cuts it around the match when it is long
+func preview(number int, line string, start, end int) Match {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	shift := len(line) - len(trimmed)
+	text := strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	start, end = start-shift, end-shift
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	if end < start {
+		end = start
+	}
+
+	if len(text) > previewLength {
+		from := start - previewContext
+		if from < 0 {
+			from = 0
+		}
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from--
+		}
+		to := from + previewLength
+		if to > len(text) {
+			to = len(text)
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to--
+		}
+		text = text[from:to]
+		start, end = start-from, end-from
+		if end > len(text) {
+			end = len(text)
+		}
+	}
+
+	return Match{Line: number, Text: text, Start: start, End: end}
+}
@@ -0,0 +1,236 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+)
+
+// Why test Searcher?
+//
+// Content search turns "every file that mentions X" into a selection, so a
+// file it misses is silently left out of the copy and a file it wrongly
+// reports pads the payload. The walk must honour ignore rules like the tree
+// does, skip binary files, and report line numbers and match offsets the UIs
+// use to highlight previews.
+
+// --- mock repository ---
+
+type mockDirEntry struct {
+	name  string
+	isDir bool
+}
+
+func (m mockDirEntry) Name() string { return m.name }
+func (m mockDirEntry) IsDir() bool  { return m.isDir }
+
+// mockFileRepo serves an in-memory tree built from full file paths, recording the files read whole
+type mockFileRepo struct {
+	files map[string]string
+	read  []string
+}
+
+func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return "/repo", nil }
+func (m *mockFileRepo) ReadDirectory(path string) ([]repositories.DirEntry, error) {
+	seen := map[string]bool{}
+	var entries []repositories.DirEntry
+	for file := range m.files {
+		rel, err := filepath.Rel(path, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		name, _, nested := strings.Cut(rel, "/")
+		if !seen[name] {
+			seen[name] = true
+			entries = append(entries, mockDirEntry{name, nested})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	m.read = append(m.read, path)
+	return []byte(content), nil
+}
+func (m *mockFileRepo) ReadHead(path string, n int) ([]byte, int64, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, 0, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content[:min(n, len(content))]), int64(len(content)), nil
+}
+func (m *mockFileRepo) GetRelativePath(target, base string) (string, error) {
+	return filepath.Rel(base, target)
+}
+func (m *mockFileRepo) WriteToClipboard(string) error { return nil }
+
+type mockIgnoreMatcher map[string]bool
+
+func (m mockIgnoreMatcher) IsIgnored(path string, isDir bool) bool { return m[path] }
+
+func newTestSearcher() *Searcher {
+	repo := &mockFileRepo{files: map[string]string{
+		"/repo/main.go":                 "package main\n\n// uses FileRepository\nfunc main() {}\n",
+		"/repo/internal/repo.go":        "type FileRepository interface {\n\tReadFile()\n}\n\n\tvar _ FileRepository = nil\n",
+		"/repo/internal/other.go":       "package internal\n// Filerepository in odd case\n",
+		"/repo/vendor/lib.go":           "FileRepository\n",
+		"/repo/assets/logo.png":         "FileRepository\x00\x01",
+		"/repo/docs/notes.md":           "nothing to see\n",
+		"/repo/internal/deep/x_test.go": "func TestFileRepository(t *testing.T) {}\n",
+	}}
+	searcher := NewSearcher(repo, "/repo")
+	searcher.SetIgnoreMatcher(mockIgnoreMatcher{"/repo/vendor": true})
+	return searcher
+}
+
+// A literal search must find every readable text file in tree order and skip
+// ignored directories and binary files.
+func TestSearch_LiteralRespectsIgnoreAndBinary(t *testing.T) {
+	results, err := newTestSearcher().Search(Query{Pattern: "FileRepository"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(results.Paths(), " ")
+	want := "internal/deep/x_test.go internal/repo.go main.go"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+// A tree holding large assets must not be loaded into memory file by file just
+// to skip them: only the text files searched are read whole.
+func TestSearch_SkipsWithoutReadingWhole(t *testing.T) {
+	repo := &mockFileRepo{files: map[string]string{
+		"/repo/big.txt":   strings.Repeat("FileRepository\n", classify.DefaultMaxSize/10),
+		"/repo/image.bin": "\x00\x01FileRepository" + strings.Repeat("x", 2*headSize),
+		"/repo/main.go":   "FileRepository\n",
+	}}
+
+	results, err := NewSearcher(repo, "/repo").Search(Query{Pattern: "FileRepository"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results.Paths(), " "); got != "main.go" {
+		t.Fatalf("expected only main.go to match, got %q", got)
+	}
+	if !reflect.DeepEqual(repo.read, []string{"/repo/main.go"}) {
+		t.Fatalf("expected only main.go to be read whole, got %v", repo.read)
+	}
+}
+
+// The size limit follows --max-file-size, so a file copied whole can also be
+// found, and no limit searches every text file.
+func TestSearch_MaxFileSize(t *testing.T) {
+	repo := &mockFileRepo{files: map[string]string{
+		"/repo/big.txt": strings.Repeat("FileRepository\n", 100),
+		"/repo/main.go": "FileRepository\n",
+	}}
+	searcher := NewSearcher(repo, "/repo")
+
+	for _, tc := range []struct {
+		size int64
+		want string
+	}{
+		{1000, "main.go"},
+		{2000, "big.txt main.go"},
+		{0, "big.txt main.go"},
+	} {
+		searcher.SetMaxFileSize(tc.size)
+		results, err := searcher.Search(Query{Pattern: "FileRepository"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results.Paths(), " "); got != tc.want {
+			t.Errorf("max size %d: expected %q, got %q", tc.size, tc.want, got)
+		}
+	}
+}
+
+// The preview shows the trimmed line and the offsets of the match within it,
+// and the count covers lines beyond the preview.
+func TestSearch_MatchPreview(t *testing.T) {
+	results, err := newTestSearcher().Search(Query{Pattern: "FileRepository"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hit := results.Files[1]
+	if hit.Path != "internal/repo.go" || hit.Count != 2 || len(hit.Matches) != 2 {
+		t.Fatalf("unexpected hit %+v", hit)
+	}
+	second := hit.Matches[1]
+	if second.Line != 5 || second.Text != "var _ FileRepository = nil" {
+		t.Fatalf("unexpected preview %+v", second)
+	}
+	if second.Text[second.Start:second.End] != "FileRepository" {
+		t.Fatalf("offsets should cover the match, got %q", second.Text[second.Start:second.End])
+	}
+}
+
+// Lower-case patterns ignore case; patterns with an upper-case letter do not.
+func TestSearch_SmartCase(t *testing.T) {
+	searcher := newTestSearcher()
+
+	results, _ := searcher.Search(Query{Pattern: "filerepository"})
+	if len(results.Files) != 4 {
+		t.Fatalf("lower-case pattern should match all 4 text files, got %v", results.Paths())
+	}
+	results, _ = searcher.Search(Query{Pattern: "Filerepository"})
+	if len(results.Files) != 1 || results.Files[0].Path != "internal/other.go" {
+		t.Fatalf("expected only internal/other.go, got %v", results.Paths())
+	}
+}
+
+// Regex mode interprets the pattern; literal mode must not.
+func TestSearch_RegexAndErrors(t *testing.T) {
+	searcher := newTestSearcher()
+
+	results, err := searcher.Search(Query{Pattern: `^func \w+\(`, Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results.Paths(), " "); got != "internal/deep/x_test.go main.go" {
+		t.Fatalf("unexpected regex results %q", got)
+	}
+
+	results, _ = searcher.Search(Query{Pattern: `^func \w+\(`})
+	if len(results.Files) != 0 {
+		t.Fatalf("literal search must not interpret regex syntax, got %v", results.Paths())
+	}
+
+	if _, err := searcher.Search(Query{Pattern: "(", Regex: true}); err == nil {
+		t.Fatal("expected an error for an invalid regular expression")
+	}
+	if _, err := searcher.Search(Query{}); !errors.Is(err, ErrEmptyPattern) {
+		t.Fatalf("expected ErrEmptyPattern, got %v", err)
+	}
+}
+
+// Long lines are cut around the match without splitting a multi-byte rune.
+func TestPreview_CutsLongLines(t *testing.T) {
+	line := strings.Repeat("é", 100) + "needle" + strings.Repeat("x", 200)
+	start := strings.Index(line, "needle")
+	match := preview(1, line, start, start+len("needle"))
+
+	if len(match.Text) > previewLength {
+		t.Fatalf("preview should be at most %d bytes, got %d", previewLength, len(match.Text))
+	}
+	if match.Text[match.Start:match.End] != "needle" {
+		t.Fatalf("offsets should still cover the match, got %q", match.Text[match.Start:match.End])
+	}
+	if !strings.HasPrefix(match.Text, "é") {
+		t.Fatalf("preview must start on a rune boundary, got %q", match.Text[:4])
+	}
+}
@@ -0,0 +1,104 @@
+package selector
+
+import (
+	"path/filepath"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// CompanionMatcher pairs files with their companions, such as their tests.
+// Paths are slash-separated and relative to the root; exists reports whether a path names a selectable file.
+type CompanionMatcher interface {
+	// Follow returns the existing companions of the files at paths
+	Follow(paths []string, exists func(path string) bool) []string
+
+	// Pair returns the files to add to complete the pairs of a selection, or the companions to remove when they are complete
+	Pair(selected []string, exists func(path string) bool) (add, remove []string)
+}
+
+// SetCompanions sets the matcher of companion files. With auto set, toggling a
+// file or directory also selects or deselects the companions of its files.
+func (fs *FileSelector) SetCompanions(matcher CompanionMatcher, auto bool) {
+	fs.companions = matcher
+	fs.autoCompanions = auto
+}
+
+// AutoCompanions reports whether companions follow the files toggled
+func (fs *FileSelector) AutoCompanions() bool {
+	return fs.companions != nil && fs.autoCompanions
+}
+
+// SetAutoCompanions sets whether companions follow the files toggled
+func (fs *FileSelector) SetAutoCompanions(auto bool) {
+	fs.autoCompanions = auto
+}
+
+// PairCompanions adds the companions of the selected files and the files selected
+// companions belong to. When nothing is missing, it removes the selected companions
+// of selected files instead, so pairing twice restores the selection. It returns
+// the number of files added and removed.
+func (fs *FileSelector) PairCompanions(root *entities.FileNode) (added, removed int) {
+	if fs.companions == nil {
+		return 0, 0
+	}
+	add, remove := fs.companions.Pair(fs.relativeFiles(root, fs.GetSelectedNodes()), fs.existsFunc(root))
+	for _, path := range add {
+		fs.setSelected(fs.FindNode(root, path), true)
+	}
+	for _, path := range remove {
+		fs.setSelected(fs.FindNode(root, path), false)
+	}
+	return len(add), len(remove)
+}
+
+// SelectCompanions selects the companions of every selected file and returns how many were added
+func (fs *FileSelector) SelectCompanions(root *entities.FileNode) int {
+	if fs.companions == nil {
+		return 0
+	}
+	found := fs.companions.Follow(fs.relativeFiles(root, fs.GetSelectedNodes()), fs.existsFunc(root))
+	for _, path := range found {
+		fs.setSelected(fs.FindNode(root, path), true)
+	}
+	return len(found)
+}
+
+// followCompanions gives the companions of the files under a toggled node the node's new selection state
+func (fs *FileSelector) followCompanions(node *entities.FileNode) {
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	files := []*entities.FileNode{node}
+	if node.IsDir {
+		files = nil
+		fs.forEachFile(node, func(file *entities.FileNode) {
+			files = append(files, file)
+		})
+	}
+
+	selected := fs.SelectionState(node) == FullySelected
+	for _, path := range fs.companions.Follow(fs.relativeFiles(root, files), fs.existsFunc(root)) {
+		fs.setSelected(fs.FindNode(root, path), selected)
+	}
+}
+
+// relativeFiles returns the slash-separated paths of files relative to root
+func (fs *FileSelector) relativeFiles(root *entities.FileNode, files []*entities.FileNode) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		if rel, err := filepath.Rel(root.Path, file.Path); err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	return paths
+}
+
+// existsFunc reports whether a root-relative path names a visible file, loading directories as needed
+func (fs *FileSelector) existsFunc(root *entities.FileNode) func(path string) bool {
+	return func(path string) bool {
+		node := fs.FindNode(root, path)
+		return node != nil && !node.IsDir && !fs.isHidden(node)
+	}
+}
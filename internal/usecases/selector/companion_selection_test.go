@@ -0,0 +1,106 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test companion selection?
+//
+// Tests are paired with their implementation on nodes the tree may not have
+// loaded yet. A companion left out of the selection, or one that stays
+// selected after its source is dropped, changes what gets reviewed.
+
+// mockCompanionMatcher pairs each source path with one companion path
+type mockCompanionMatcher struct {
+	pairs map[string]string
+}
+
+func (m *mockCompanionMatcher) Follow(paths []string, exists func(string) bool) []string {
+	var found []string
+	for _, path := range paths {
+		if companion, ok := m.pairs[path]; ok && exists(companion) {
+			found = append(found, companion)
+		}
+	}
+	return found
+}
+
+func (m *mockCompanionMatcher) Pair(selected []string, exists func(string) bool) (add, remove []string) {
+	chosen := map[string]bool{}
+	for _, path := range selected {
+		chosen[path] = true
+	}
+	for _, path := range selected {
+		if companion, ok := m.pairs[path]; ok && !chosen[companion] && exists(companion) {
+			add = append(add, companion)
+		}
+	}
+	if add != nil {
+		return add, nil
+	}
+	for source, companion := range m.pairs {
+		if chosen[source] && chosen[companion] {
+			remove = append(remove, companion)
+		}
+	}
+	return nil, remove
+}
+
+// newCompanionSelector returns a selector over buildGlobTree pairing internal/app.go with its test
+func newCompanionSelector(auto bool) (*FileSelector, *entities.FileNode) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+	sel.SetCompanions(&mockCompanionMatcher{pairs: map[string]string{
+		"internal/app.go": "internal/app_test.go",
+		"main.go":         "main_test.go",
+	}}, auto)
+	return sel, root
+}
+
+// With automatic companions, toggling a source selects and deselects its unloaded test.
+func TestToggleSelect_AutoCompanions(t *testing.T) {
+	sel, root := newCompanionSelector(true)
+	app := sel.FindNode(root, "internal/app.go")
+
+	sel.ToggleSelect(app)
+	if test := sel.FindNode(root, "internal/app_test.go"); !test.Selected {
+		t.Fatal("selecting app.go should select app_test.go")
+	}
+
+	sel.ToggleSelect(app)
+	if len(sel.GetSelection()) != 0 {
+		t.Fatalf("deselecting app.go should deselect its test, got %d selected", len(sel.GetSelection()))
+	}
+
+	// A missing companion is skipped
+	sel.ToggleSelect(sel.FindNode(root, "main.go"))
+	if len(sel.GetSelection()) != 1 {
+		t.Fatalf("main.go has no test; want 1 selected file, got %d", len(sel.GetSelection()))
+	}
+}
+
+// Without automatic companions, toggling leaves tests alone until they are paired.
+func TestPairCompanions(t *testing.T) {
+	sel, root := newCompanionSelector(false)
+	sel.ToggleSelect(sel.FindNode(root, "internal/app.go"))
+	if len(sel.GetSelection()) != 1 {
+		t.Fatalf("want only app.go selected, got %d files", len(sel.GetSelection()))
+	}
+
+	if added, removed := sel.PairCompanions(root); added != 1 || removed != 0 {
+		t.Fatalf("PairCompanions = %d added, %d removed; want 1, 0", added, removed)
+	}
+	if !sel.FindNode(root, "internal/app_test.go").Selected {
+		t.Fatal("pairing should select app_test.go")
+	}
+
+	if added, removed := sel.PairCompanions(root); added != 0 || removed != 1 {
+		t.Fatalf("second PairCompanions = %d added, %d removed; want 0, 1", added, removed)
+	}
+	if sel.FindNode(root, "internal/app_test.go").Selected {
+		t.Fatal("pairing twice should deselect app_test.go again")
+	}
+}
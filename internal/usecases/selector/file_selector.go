@@ -1,12 +1,43 @@
 package selector
 
 import (
+	"slices"
+
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
 )
 
+// SelectionState describes how much of a node is selected
+type SelectionState int
+
+const (
+	Unselected        SelectionState = iota // No file under the node is selected
+	PartiallySelected                       // Some, but not all, files under the directory are selected
+	FullySelected                           // The file, or every file under the directory, is selected
+)
+
+// TreeLoader loads the children of directory nodes on demand
+type TreeLoader interface {
+	// LoadChildren populates the children of a directory node if they have not been loaded yet
+	LoadChildren(node *entities.FileNode)
+
+	// IsHidden reports whether a node is hidden (e.g. ignored) and must not be selected with its directory
+	IsHidden(node *entities.FileNode) bool
+}
+
+// DeletionSource tells the files deleted from disk whose deletion diff can still be copied
+type DeletionSource interface {
+	// IsDeleted reports whether the file at the slash-separated, root-relative path was deleted
+	IsDeleted(path string) bool
+}
+
 // FileSelector handles the selection of files in the tree
 type FileSelector struct {
-	selection map[string]*entities.FileNode
+	selection      map[string]*entities.FileNode
+	loader         TreeLoader
+	deletions      DeletionSource // Deleted files that paths may still select; nil selects none
+	companions     CompanionMatcher
+	autoCompanions bool // Toggling a file also toggles its companions
 }
 
 // NewFileSelector creates a new FileSelector
@@ -16,18 +47,238 @@ func NewFileSelector() *FileSelector {
 	}
 }
 
-// ToggleSelect toggles the selection state of a file node
+// SetTreeLoader sets the loader used to reach unexpanded files when a directory is selected
+func (fs *FileSelector) SetTreeLoader(loader TreeLoader) {
+	fs.loader = loader
+}
+
+// SetDeletionSource lets SelectPaths select deleted files reported by deletions
+func (fs *FileSelector) SetDeletionSource(deletions DeletionSource) {
+	fs.deletions = deletions
+}
+
+// ToggleSelect toggles the selection state of a node.
+// Toggling a directory selects every visible file under it, loading unexpanded
+// directories as needed, or clears all of its descendants when it is already fully selected.
+// Toggling a declaration of a source file selects or deselects its lines.
+// With automatic companions, the companions of the toggled files follow them.
 func (fs *FileSelector) ToggleSelect(node *entities.FileNode) {
+	if node.Symbol != nil {
+		fs.toggleSymbol(node)
+		return
+	}
+	fs.toggle(node)
+	if fs.AutoCompanions() {
+		fs.followCompanions(node)
+	}
+}
+
+// toggle selects a file or every file under a directory, or deselects them when fully selected
+func (fs *FileSelector) toggle(node *entities.FileNode) {
+	if !node.IsDir {
+		fs.setSelected(node, !node.Selected)
+		return
+	}
+
+	if fs.SelectionState(node) == FullySelected {
+		fs.clearDescendants(node)
+		return
+	}
+
+	fs.loadDescendants(node)
+	fs.forEachFile(node, func(file *entities.FileNode) {
+		fs.setSelected(file, true)
+	})
+}
+
+// SelectionState returns whether none, some, or all of the files under a node are selected.
+// Only directories that have already been loaded are inspected. A declaration is
+// fully selected when all of its lines are.
+func (fs *FileSelector) SelectionState(node *entities.FileNode) SelectionState {
+	if node.Symbol != nil {
+		return symbolState(node)
+	}
 	if !node.IsDir {
-		node.Selected = !node.Selected
 		if node.Selected {
-			fs.selection[node.Path] = node
+			return FullySelected
+		}
+		return Unselected
+	}
+
+	total, selected := 0, 0
+	fs.forEachFile(node, func(file *entities.FileNode) {
+		total++
+		if file.Selected {
+			selected++
+		}
+	})
+
+	switch {
+	case selected == 0:
+		return Unselected
+	case selected == total:
+		return FullySelected
+	default:
+		return PartiallySelected
+	}
+}
+
+// symbolState returns whether all, some, or none of the lines of a declaration are selected
+func symbolState(node *entities.FileNode) SelectionState {
+	file, lines := node.Parent, node.Symbol.Lines
+	if !file.Selected {
+		return Unselected
+	}
+	if len(file.Ranges) == 0 {
+		return FullySelected
+	}
+
+	switch linerange.Covered(file.Ranges, lines) {
+	case 0:
+		return Unselected
+	case lines.End - lines.Start + 1:
+		return FullySelected
+	default:
+		return PartiallySelected
+	}
+}
+
+// toggleSymbol adds the lines of a declaration to the selected lines of its file,
+// or removes them when the declaration is fully selected. Removing a declaration
+// from a file selected as a whole keeps every other line of the file.
+func (fs *FileSelector) toggleSymbol(node *entities.FileNode) {
+	file, lines := node.Parent, node.Symbol.Lines
+	if symbolState(node) != FullySelected {
+		fs.AddRange(file, lines)
+		return
+	}
+
+	ranges := file.Ranges
+	if len(ranges) == 0 {
+		last := max(node.Symbol.FileLines, file.Children[len(file.Children)-1].Symbol.Lines.End)
+		ranges = []entities.LineRange{{Start: 1, End: last}}
+	}
+	ranges = linerange.Subtract(ranges, lines)
+	if len(ranges) == 0 {
+		fs.setSelected(file, false)
+		return
+	}
+	fs.SetRanges(file, ranges)
+}
+
+// RemapSymbols moves the selected lines of a file whose declarations were read
+// again, after the file changed, from the declarations in old to the
+// declarations now listed under the file. Declarations are matched by name,
+// which starts with their kind, and by order among those with the same name.
+// Lines outside wholly selected declarations are kept as they are. It returns
+// the names of the selected declarations that are gone, whose lines are
+// dropped; a file left without lines is deselected.
+func (fs *FileSelector) RemapSymbols(file *entities.FileNode, old []entities.Symbol) []string {
+	if !file.Selected || len(file.Ranges) == 0 {
+		return nil
+	}
+
+	current := make(map[string][]entities.LineRange)
+	for _, child := range file.Children {
+		if child.Symbol != nil {
+			current[child.Symbol.Name] = append(current[child.Symbol.Name], child.Symbol.Lines)
+		}
+	}
+
+	kept := file.Ranges
+	var moved []entities.LineRange
+	var dropped []string
+	seen := make(map[string]int)
+	for _, symbol := range old {
+		nth := seen[symbol.Name]
+		seen[symbol.Name]++
+		lines := symbol.Lines
+		if linerange.Covered(file.Ranges, lines) != lines.End-lines.Start+1 {
+			continue
+		}
+		kept = linerange.Subtract(kept, lines)
+		if nth < len(current[symbol.Name]) {
+			moved = append(moved, current[symbol.Name][nth])
+		} else {
+			dropped = append(dropped, symbol.Name)
+		}
+	}
+
+	ranges := linerange.Normalize(append(slices.Clone(kept), moved...))
+	if len(ranges) == 0 {
+		fs.setSelected(file, false)
+	} else {
+		fs.SetRanges(file, ranges)
+	}
+	return dropped
+}
+
+// setSelected updates the selection flag of a file node and the selection map.
+// Deselecting a file drops its line ranges.
+func (fs *FileSelector) setSelected(node *entities.FileNode, selected bool) {
+	node.Selected = selected
+	if selected {
+		fs.selection[node.Path] = node
+	} else {
+		node.Ranges = nil
+		delete(fs.selection, node.Path)
+	}
+}
+
+// clearDescendants deselects every file under a directory, including hidden ones
+func (fs *FileSelector) clearDescendants(node *entities.FileNode) {
+	for _, child := range node.Children {
+		if child.IsDir {
+			fs.clearDescendants(child)
+		} else if child.Selected {
+			fs.setSelected(child, false)
+		}
+	}
+}
+
+// loadDescendants loads every visible directory under node
+func (fs *FileSelector) loadDescendants(node *entities.FileNode) {
+	if fs.loader == nil {
+		return
+	}
+	fs.loader.LoadChildren(node)
+	for _, child := range node.Children {
+		if child.IsDir && !fs.isHidden(child) {
+			fs.loadDescendants(child)
+		}
+	}
+}
+
+// forEachFile calls fn for every loaded, visible file under node
+func (fs *FileSelector) forEachFile(node *entities.FileNode, fn func(file *entities.FileNode)) {
+	for _, child := range node.Children {
+		if fs.isHidden(child) {
+			continue
+		}
+		if child.IsDir {
+			fs.forEachFile(child, fn)
 		} else {
-			delete(fs.selection, node.Path)
+			fn(child)
 		}
 	}
 }
 
+// isHidden reports whether the loader hides node
+func (fs *FileSelector) isHidden(node *entities.FileNode) bool {
+	return fs.loader != nil && fs.loader.IsHidden(node)
+}
+
+// Forget deselects a node that was removed from the tree, and every file under it
+func (fs *FileSelector) Forget(node *entities.FileNode) {
+	if node.IsDir {
+		fs.clearDescendants(node)
+		return
+	}
+	if fs.selection[node.Path] == node {
+		fs.setSelected(node, false)
+	}
+}
+
 // GetSelection returns the current selection map
 func (fs *FileSelector) GetSelection() map[string]*entities.FileNode {
 	return fs.selection
@@ -4,14 +4,16 @@ import (
 	"testing"
 
 	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/outline"
 )
 
 // Why test FileSelector?
 //
 // FileSelector manages which files the user has picked. It is the single
 // source of truth for selection state — if ToggleSelect has a bug (e.g.
-// double-toggle doesn't deselect, or directories slip through), the user
-// copies the wrong set of files. The sorting logic also matters: clipboard
+// double-toggle doesn't deselect, or a directory toggle misses files), the
+// user copies the wrong set of files. The sorting logic also matters: clipboard
 // output order depends on it, so a broken sort means unpredictable output.
 
 // The most basic contract: pressing Space on a file marks it as selected and
@@ -47,10 +49,10 @@ func TestToggleSelect_DeselectsFile(t *testing.T) {
 	}
 }
 
-// Directories must not be selectable — they can't be "copied" as file content.
-// If a directory slips into the selection map, CopySelectionToClipboard would
-// try to read a directory as a file and produce garbage or an error.
-func TestToggleSelect_IgnoresDirectory(t *testing.T) {
+// Toggling an empty directory must not put the directory itself into the
+// selection map. Only files can be copied; if a directory slipped in,
+// CopySelectionToClipboard would try to read it as a file.
+func TestToggleSelect_EmptyDirectorySelectsNothing(t *testing.T) {
 	sel := NewFileSelector()
 	dir := entities.NewFileNode("src", "/src", true, nil)
 
@@ -60,7 +62,139 @@ func TestToggleSelect_IgnoresDirectory(t *testing.T) {
 		t.Fatal("directory should not become selected")
 	}
 	if len(sel.GetSelection()) != 0 {
-		t.Fatal("selection map should be empty after toggling a directory")
+		t.Fatal("selection map should be empty after toggling an empty directory")
+	}
+}
+
+// mockTreeLoader serves children from a fixed map and hides some paths,
+// recording which directories were loaded.
+type mockTreeLoader struct {
+	children map[string][]*entities.FileNode
+	hidden   map[string]bool
+	loaded   []string
+}
+
+func (m *mockTreeLoader) LoadChildren(node *entities.FileNode) {
+	if len(node.Children) == 0 {
+		node.Children = m.children[node.Path]
+		m.loaded = append(m.loaded, node.Path)
+	}
+}
+func (m *mockTreeLoader) IsHidden(node *entities.FileNode) bool { return m.hidden[node.Path] }
+
+// buildDirTree creates /src with a.go, an unexpanded sub/ holding b.go, and
+// an ignored vendor/ holding c.go.
+func buildDirTree() (*entities.FileNode, *mockTreeLoader) {
+	src := entities.NewFileNode("src", "/src", true, nil)
+	a := entities.NewFileNode("a.go", "/src/a.go", false, src)
+	sub := entities.NewFileNode("sub", "/src/sub", true, src)
+	vendor := entities.NewFileNode("vendor", "/src/vendor", true, src)
+	src.Children = []*entities.FileNode{a, sub, vendor}
+
+	loader := &mockTreeLoader{
+		children: map[string][]*entities.FileNode{
+			"/src/sub":    {entities.NewFileNode("b.go", "/src/sub/b.go", false, sub)},
+			"/src/vendor": {entities.NewFileNode("c.go", "/src/vendor/c.go", false, vendor)},
+		},
+		hidden: map[string]bool{"/src/vendor": true},
+	}
+	return src, loader
+}
+
+// Selecting a directory must include every file below it, loading unexpanded
+// subdirectories on the way, while skipping ignored entries. This replaces
+// expanding each package and pressing Space on every file.
+func TestToggleSelect_DirectorySelectsDescendants(t *testing.T) {
+	src, loader := buildDirTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	sel.ToggleSelect(src)
+
+	selection := sel.GetSelection()
+	if len(selection) != 2 {
+		t.Fatalf("expected a.go and sub/b.go to be selected, got %d files", len(selection))
+	}
+	if _, ok := selection["/src/sub/b.go"]; !ok {
+		t.Fatal("file in unexpanded subdirectory should be selected")
+	}
+	if _, ok := selection["/src/vendor/c.go"]; ok {
+		t.Fatal("file in ignored directory should not be selected")
+	}
+	for _, path := range loader.loaded {
+		if path == "/src/vendor" {
+			t.Fatal("ignored directory should not be loaded")
+		}
+	}
+	if sel.SelectionState(src) != FullySelected {
+		t.Fatal("directory should be fully selected")
+	}
+}
+
+// The tri-state indicator depends on SelectionState: a directory with only
+// some files selected is partial, and toggling it selects the rest.
+func TestSelectionState_Partial(t *testing.T) {
+	src, loader := buildDirTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	sel.ToggleSelect(src.Children[0]) // a.go only
+	loader.LoadChildren(src.Children[1])
+
+	if sel.SelectionState(src) != PartiallySelected {
+		t.Fatalf("expected partial state, got %v", sel.SelectionState(src))
+	}
+	if sel.SelectionState(src.Children[1]) != Unselected {
+		t.Fatal("sub/ should be unselected")
+	}
+
+	sel.ToggleSelect(src)
+	if sel.SelectionState(src) != FullySelected {
+		t.Fatal("toggling a partial directory should select everything")
+	}
+}
+
+// Deselecting a fully selected directory must clear every descendant,
+// including files that were selected before their directory was hidden.
+func TestToggleSelect_DirectoryDeselectClearsDescendants(t *testing.T) {
+	src, loader := buildDirTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	loader.LoadChildren(src.Children[2])
+	sel.ToggleSelect(src.Children[2].Children[0]) // vendor/c.go, selected explicitly
+	sel.ToggleSelect(src)                         // selects a.go and sub/b.go
+	sel.ToggleSelect(src)                         // deselects everything
+
+	if len(sel.GetSelection()) != 0 {
+		t.Fatalf("expected empty selection, got %d files", len(sel.GetSelection()))
+	}
+}
+
+// A file deleted while selected must leave the selection, or the next copy
+// reports it as missing. Forgetting a removed directory clears everything
+// selected under it, and a node that was replaced keeps its successor selected.
+func TestForget_DeselectsRemovedNodes(t *testing.T) {
+	src, loader := buildDirTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+	sel.ToggleSelect(src)
+	selected := len(sel.GetSelection())
+
+	sel.Forget(src.Children[0])
+	if len(sel.GetSelection()) != selected-1 || src.Children[0].Selected {
+		t.Fatal("forgetting a removed file should deselect it")
+	}
+
+	stale := entities.NewFileNode(src.Children[1].Children[0].Name, src.Children[1].Children[0].Path, false, nil)
+	sel.Forget(stale)
+	if len(sel.GetSelection()) != selected-1 {
+		t.Fatal("forgetting a node should not deselect another node at the same path")
+	}
+
+	sel.Forget(src)
+	if len(sel.GetSelection()) != 0 {
+		t.Fatalf("forgetting a removed directory should deselect its files, got %d", len(sel.GetSelection()))
 	}
 }
 
@@ -127,3 +261,100 @@ func TestGetSelection_EmptyByDefault(t *testing.T) {
 		t.Fatal("new selector should have empty selection")
 	}
 }
+
+// Declarations of a Go file are selected by their lines. Picking two of three
+// must copy exactly those lines, and dropping one from a whole-file selection
+// must keep everything else, including what follows the last declaration,
+// instead of clearing the file.
+func TestToggleSelect_Symbols(t *testing.T) {
+	sel := NewFileSelector()
+	file := entities.NewFileNode("app.go", "/src/app.go", false, nil)
+	var decls []*entities.FileNode
+	for _, lines := range []entities.LineRange{{Start: 3, End: 5}, {Start: 7, End: 12}, {Start: 14, End: 20}} {
+		decl := entities.NewFileNode("func", file.Path, false, file)
+		decl.Symbol = &entities.Symbol{Name: "func", Lines: lines, FileLines: 24}
+		file.Children = append(file.Children, decl)
+		decls = append(decls, decl)
+	}
+
+	sel.ToggleSelect(decls[0])
+	sel.ToggleSelect(decls[2])
+	if !file.Selected || len(sel.GetSelection()) != 1 {
+		t.Fatal("selecting a declaration should select its file")
+	}
+	if got := linerange.Format(file.Ranges); got != "3-5,14-20" {
+		t.Fatalf("expected the lines of both declarations, got %s", got)
+	}
+	if sel.SelectionState(decls[0]) != FullySelected || sel.SelectionState(decls[1]) != Unselected {
+		t.Fatal("only the toggled declarations should show as selected")
+	}
+
+	sel.ToggleSelect(decls[0])
+	sel.ToggleSelect(decls[2])
+	if file.Selected {
+		t.Fatal("deselecting every declaration should deselect the file")
+	}
+
+	sel.ToggleSelect(file)
+	sel.ToggleSelect(decls[1])
+	if got := linerange.Format(file.Ranges); got != "1-6,13-24" {
+		t.Fatalf("expected the whole file without the declaration, got %s", got)
+	}
+
+	// A selected range that only overlaps a declaration is completed by toggling it
+	sel.SetRanges(file, []entities.LineRange{{Start: 8, End: 9}})
+	if sel.SelectionState(decls[1]) != PartiallySelected {
+		t.Fatal("a declaration with some selected lines should be partially selected")
+	}
+	sel.ToggleSelect(decls[1])
+	if got := linerange.Format(file.Ranges); got != "7-12" {
+		t.Fatalf("expected the whole declaration, got %s", got)
+	}
+}
+
+// listDeclarations lists the declarations of src under file, as the navigator does
+func listDeclarations(t *testing.T, file *entities.FileNode, src string) []entities.Symbol {
+	t.Helper()
+	symbols, err := outline.Declarations(file.Path, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Children = nil
+	for i := range symbols {
+		decl := entities.NewFileNode(symbols[i].Name, file.Path, false, file)
+		decl.Symbol = &symbols[i]
+		file.Children = append(file.Children, decl)
+	}
+	return symbols
+}
+
+// Editing a file moves its declarations; the selected ones must follow them,
+// or the next copy quietly emits whatever now sits at the old line numbers.
+// A selected declaration that was removed must be reported, not copied.
+func TestRemapSymbols_AfterEdit(t *testing.T) {
+	sel := NewFileSelector()
+	file := entities.NewFileNode("app.go", "/src/app.go", false, nil)
+	old := listDeclarations(t, file, "package app\n\nfunc A() {}\n\nfunc B() {\n}\n\nfunc C() {}\n")
+	sel.ToggleSelect(file.Children[1])
+	sel.ToggleSelect(file.Children[2])
+	if got := linerange.Format(file.Ranges); got != "5-6,8" {
+		t.Fatalf("expected the lines of B and C, got %s", got)
+	}
+
+	edited := listDeclarations(t, file, "package app\n\n// New is new\nfunc New() {}\n\nfunc A() {}\n\nfunc B() {\n\treturn\n}\n")
+	dropped := sel.RemapSymbols(file, old)
+
+	if got := linerange.Format(file.Ranges); got != "8-10" {
+		t.Fatalf("expected the new lines of B, got %s", got)
+	}
+	if len(dropped) != 1 || dropped[0] != "func C" {
+		t.Fatalf("expected func C to be reported as gone, got %v", dropped)
+	}
+
+	// A file left without any selected declaration is deselected
+	listDeclarations(t, file, "package app\n\nfunc A() {}\n")
+	sel.RemapSymbols(file, edited)
+	if file.Selected {
+		t.Fatal("the file should be deselected once its only selected declaration is gone")
+	}
+}
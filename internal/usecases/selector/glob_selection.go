@@ -0,0 +1,55 @@
+package selector
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/pathmatch"
+)
+
+// SelectMatching selects every visible file under root whose root-relative
+// path matches at least one include pattern and no exclude pattern.
+// Patterns use pathmatch syntax ("internal/**/*.go"); with no include
+// patterns every file is included. Unexpanded directories are loaded as needed.
+// It returns the number of files that match.
+func (fs *FileSelector) SelectMatching(root *entities.FileNode, include, exclude []string) (int, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if !pathmatch.Valid(pattern) {
+			return 0, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+
+	fs.loadDescendants(root)
+
+	matched := 0
+	fs.forEachFile(root, func(file *entities.FileNode) {
+		rel, err := filepath.Rel(root.Path, file.Path)
+		if err != nil {
+			return
+		}
+		rel = filepath.ToSlash(rel)
+
+		if len(include) > 0 && !matchesAny(include, rel) {
+			return
+		}
+		if matchesAny(exclude, rel) {
+			return
+		}
+
+		fs.setSelected(file, true)
+		matched++
+	})
+
+	return matched, nil
+}
+
+// matchesAny reports whether rel matches any of the patterns
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if pathmatch.Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
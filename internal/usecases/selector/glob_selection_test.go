@@ -0,0 +1,81 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test SelectMatching?
+//
+// The headless copy command is driven entirely by --include/--exclude, so a
+// script's output depends on these rules being exact: excludes win over
+// includes, ignored files stay out, and unexpanded directories are searched.
+
+// buildGlobTree creates /p with main.go, internal/app.go, internal/app_test.go,
+// and an ignored vendor/dep.go.
+func buildGlobTree() (*entities.FileNode, *mockTreeLoader) {
+	root := entities.NewFileNode("p", "/p", true, nil)
+	internal := entities.NewFileNode("internal", "/p/internal", true, root)
+	vendor := entities.NewFileNode("vendor", "/p/vendor", true, root)
+	root.Children = []*entities.FileNode{
+		entities.NewFileNode("main.go", "/p/main.go", false, root),
+		internal,
+		vendor,
+	}
+
+	loader := &mockTreeLoader{
+		children: map[string][]*entities.FileNode{
+			"/p/internal": {
+				entities.NewFileNode("app.go", "/p/internal/app.go", false, internal),
+				entities.NewFileNode("app_test.go", "/p/internal/app_test.go", false, internal),
+			},
+			"/p/vendor": {entities.NewFileNode("dep.go", "/p/vendor/dep.go", false, vendor)},
+		},
+		hidden: map[string]bool{"/p/vendor": true},
+	}
+	return root, loader
+}
+
+func TestSelectMatching_IncludeAndExclude(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	n, err := sel.SelectMatching(root, []string{"**/*.go"}, []string{"**/*_test.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected main.go and internal/app.go, got %d matches", n)
+	}
+	for _, path := range []string{"/p/main.go", "/p/internal/app.go"} {
+		if _, ok := sel.GetSelection()[path]; !ok {
+			t.Errorf("%s should be selected", path)
+		}
+	}
+}
+
+// Without include patterns every visible file is selected; ignored ones are not.
+func TestSelectMatching_NoIncludeSelectsAllVisible(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	n, err := sel.SelectMatching(root, nil, nil)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 visible files, got %d (err=%v)", n, err)
+	}
+}
+
+// A malformed pattern must be reported instead of silently matching nothing,
+// otherwise a typo looks like "nothing matched".
+func TestSelectMatching_InvalidPattern(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	if _, err := sel.SelectMatching(root, []string{"src/["}, nil); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
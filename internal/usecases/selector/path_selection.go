@@ -0,0 +1,127 @@
+package selector
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+)
+
+// RelativePaths returns the selected files as sorted, slash-separated paths relative
+// to root. Files selected by line ranges carry them as a suffix ("app.go:120-180").
+func (fs *FileSelector) RelativePaths(root *entities.FileNode) []string {
+	paths := make([]string, 0, len(fs.selection))
+	for path, node := range fs.selection {
+		rel, err := filepath.Rel(root.Path, path)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, linerange.Join(filepath.ToSlash(rel), node.Ranges))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SelectPaths selects the files at the given slash-separated paths relative
+// to root, loading directories along the way. A path may end with line ranges
+// ("app.go:120-180") to select only those lines. Files the deletion source
+// reports as deleted are selected outside the tree (see DeletedNode). It
+// returns the paths that do not name an existing or deleted file.
+func (fs *FileSelector) SelectPaths(root *entities.FileNode, paths []string) []string {
+	var missing []string
+	for _, spec := range paths {
+		node, ranges := fs.FindSpec(root, spec)
+		if node == nil {
+			node = fs.DeletedNode(root, spec)
+		}
+		if node == nil || node.IsDir {
+			missing = append(missing, spec)
+			continue
+		}
+		fs.SetRanges(node, ranges)
+	}
+	return missing
+}
+
+// DeletedNode returns a node outside the tree for the file at the
+// slash-separated path relative to root when the deletion source reports it
+// as deleted, so its deletion diff can be copied, or nil otherwise
+func (fs *FileSelector) DeletedNode(root *entities.FileNode, relPath string) *entities.FileNode {
+	if fs.deletions == nil || !fs.deletions.IsDeleted(relPath) {
+		return nil
+	}
+	node := entities.NewFileNode(path.Base(relPath), filepath.Join(root.Path, filepath.FromSlash(relPath)), false, nil)
+	node.Deleted = true
+	return node
+}
+
+// SetRanges selects a file, copying only the given line ranges; no ranges select the whole file
+func (fs *FileSelector) SetRanges(node *entities.FileNode, ranges []entities.LineRange) {
+	fs.setSelected(node, true)
+	node.Ranges = linerange.Normalize(ranges)
+}
+
+// AddRange selects a line range of a file in addition to the ranges already selected.
+// A file selected as a whole is narrowed to the range.
+func (fs *FileSelector) AddRange(node *entities.FileNode, r entities.LineRange) {
+	ranges := []entities.LineRange{r}
+	if node.Selected {
+		ranges = append(ranges, node.Ranges...)
+	}
+	fs.SetRanges(node, ranges)
+}
+
+// FindSpec finds the node named by a slash-separated path relative to root with
+// optional line ranges, loading directories along the way, or returns nil when
+// there is none. A file whose name merely looks like a range suffix is matched
+// by its full name first.
+func (fs *FileSelector) FindSpec(root *entities.FileNode, spec string) (*entities.FileNode, []entities.LineRange) {
+	if node := fs.FindNode(root, spec); node != nil {
+		return node, nil
+	}
+	path, ranges, err := linerange.Split(spec)
+	if err != nil || ranges == nil {
+		return nil, nil
+	}
+	return fs.FindNode(root, path), ranges
+}
+
+// ClearSelection deselects every selected file
+func (fs *FileSelector) ClearSelection() {
+	for _, node := range fs.selection {
+		fs.setSelected(node, false)
+	}
+}
+
+// FindNode walks from root along the segments of the slash-separated path, loading
+// directories as needed, or returns nil when a segment does not exist
+func (fs *FileSelector) FindNode(root *entities.FileNode, path string) *entities.FileNode {
+	node := root
+	for _, name := range strings.Split(path, "/") {
+		if name == "" || name == "." {
+			continue
+		}
+		if !node.IsDir {
+			return nil
+		}
+		if fs.loader != nil {
+			fs.loader.LoadChildren(node)
+		}
+
+		var next *entities.FileNode
+		for _, child := range node.Children {
+			if child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
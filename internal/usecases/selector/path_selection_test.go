@@ -0,0 +1,120 @@
+package selector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test path selection?
+//
+// Saved profiles are plain lists of relative paths. They must round-trip
+// through RelativePaths and SelectPaths, line ranges included, reach files
+// inside directories that were never expanded, and report paths that no
+// longer exist.
+
+// Paths saved from one selection must restore the same selection, even when
+// the directories holding the files have not been loaded yet.
+func TestSelectPaths_RoundTrip(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	missing := sel.SelectPaths(root, []string{"main.go", "internal/app.go"})
+	if len(missing) != 0 {
+		t.Fatalf("nothing should be missing, got %v", missing)
+	}
+
+	got := sel.RelativePaths(root)
+	want := []string{"internal/app.go", "main.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("RelativePaths = %v, want %v", got, want)
+	}
+}
+
+// Line ranges selected on a file must be saved with its path and restored from it.
+func TestSelectPaths_LineRanges(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	missing := sel.SelectPaths(root, []string{"main.go:20-30,1-5", "internal/app.go:9-"})
+	if !reflect.DeepEqual(missing, []string{"internal/app.go:9-"}) {
+		t.Fatalf("a malformed range should be reported as missing, got %v", missing)
+	}
+
+	main := sel.FindNode(root, "main.go")
+	sel.AddRange(main, entities.LineRange{Start: 6, End: 8})
+	want := []entities.LineRange{{Start: 1, End: 8}, {Start: 20, End: 30}}
+	if !reflect.DeepEqual(main.Ranges, want) {
+		t.Fatalf("ranges = %v, want %v", main.Ranges, want)
+	}
+	if got := sel.RelativePaths(root); !reflect.DeepEqual(got, []string{"main.go:1-8,20-30"}) {
+		t.Fatalf("RelativePaths = %v", got)
+	}
+
+	// Deselecting forgets the ranges, so selecting again takes the whole file
+	sel.ToggleSelect(main)
+	sel.ToggleSelect(main)
+	if main.Ranges != nil {
+		t.Fatalf("ranges should be dropped on deselect, got %v", main.Ranges)
+	}
+}
+
+// Deleted files, and paths that now name a directory, must be reported
+// instead of silently shrinking the selection.
+func TestSelectPaths_ReportsMissing(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+
+	missing := sel.SelectPaths(root, []string{"main.go", "internal/gone.go", "internal", "main.go/x"})
+
+	want := []string{"internal/gone.go", "internal", "main.go/x"}
+	if !reflect.DeepEqual(missing, want) {
+		t.Fatalf("missing = %v, want %v", missing, want)
+	}
+	if len(sel.GetSelection()) != 1 {
+		t.Fatalf("only main.go should be selected, got %d files", len(sel.GetSelection()))
+	}
+}
+
+// mockDeletions reports a fixed set of root-relative paths as deleted
+type mockDeletions map[string]bool
+
+func (m mockDeletions) IsDeleted(path string) bool { return m[path] }
+
+// A file deleted from disk but reported by git can still be selected, so the
+// changed files copied as diffs include their deletions.
+func TestSelectPaths_SelectsDeletedFiles(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+	sel.SetDeletionSource(mockDeletions{"internal/gone.go": true})
+
+	missing := sel.SelectPaths(root, []string{"internal/gone.go", "internal/other.go"})
+
+	if !reflect.DeepEqual(missing, []string{"internal/other.go"}) {
+		t.Fatalf("only the path git does not know should be missing, got %v", missing)
+	}
+	node := sel.GetSelection()[root.Path+"/internal/gone.go"]
+	if node == nil || !node.Deleted || node.Parent != nil {
+		t.Fatalf("the deleted file should be selected outside the tree, got %+v", node)
+	}
+}
+
+// ClearSelection must reset both the selection map and the node flags the
+// tree view renders.
+func TestClearSelection(t *testing.T) {
+	root, loader := buildGlobTree()
+	sel := NewFileSelector()
+	sel.SetTreeLoader(loader)
+	sel.SelectPaths(root, []string{"main.go"})
+
+	sel.ClearSelection()
+
+	if len(sel.GetSelection()) != 0 || root.Children[0].Selected {
+		t.Fatal("selection should be empty after ClearSelection")
+	}
+}
@@ -0,0 +1,142 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/classify"
+	"github.com/makinzm/partial-tree-copy/internal/usecases/linerange"
+)
+
+// ErrBudgetExceeded is returned when a copy is refused because it exceeds the token budget
+var ErrBudgetExceeded = errors.New("token budget exceeded")
+
+// Budget limits the number of tokens in a copied payload
+type Budget struct {
+	Max    int  // Maximum number of tokens; 0 means unlimited
+	Refuse bool // Refuse to copy instead of only warning when Max is exceeded
+}
+
+// Exceeded reports whether total is over the budget
+func (b Budget) Exceeded(total int) bool {
+	return b.Max > 0 && total > b.Max
+}
+
+// Check returns an error wrapping ErrBudgetExceeded when total is over a refusing budget
+func (b Budget) Check(total int) error {
+	if b.Refuse && b.Exceeded(total) {
+		return fmt.Errorf("%w: %d tokens > %d", ErrBudgetExceeded, total, b.Max)
+	}
+	return nil
+}
+
+// FileEstimate is the token count of a single file
+type FileEstimate struct {
+	Path   string // Full path of the file
+	Tokens int    // Estimated number of tokens in the file content, or in its selected line ranges
+}
+
+// Estimate is the token count of a selection
+type Estimate struct {
+	Files []FileEstimate // Per-file counts in the order the nodes were given
+	Total int            // Sum of all per-file counts
+}
+
+// Classifier turns files into the text that is copied
+type Classifier interface {
+	// ReadText returns the file at path decoded to UTF-8, or a stub for a binary or oversized file,
+	// with its class, without loading binary and oversized files into memory
+	ReadText(path string) (string, classify.Class, error)
+}
+
+// Estimator counts the tokens of selected files, caching results per path
+type Estimator struct {
+	repo       repositories.FileRepository
+	tokenizer  Tokenizer
+	classifier Classifier     // Counts the stubs of binary and oversized files instead of their bytes
+	cache      map[string]int // Counts by path, with the line ranges appended for partial files
+}
+
+// NewEstimator creates a new Estimator reading files through repo
+func NewEstimator(repo repositories.FileRepository, tokenizer Tokenizer) *Estimator {
+	return &Estimator{
+		repo:      repo,
+		tokenizer: tokenizer,
+		cache:     make(map[string]int),
+	}
+}
+
+// SetClassifier counts files as the copier renders them through classifier; nil counts raw contents
+func (e *Estimator) SetClassifier(classifier Classifier) {
+	e.classifier = classifier
+	e.cache = make(map[string]int)
+}
+
+// Tokenizer returns the tokenizer used for counting
+func (e *Estimator) Tokenizer() Tokenizer {
+	return e.tokenizer
+}
+
+// Estimate returns the token counts of the given file nodes, counting only
+// the selected line ranges of partial files. Files that cannot be read count as zero tokens.
+func (e *Estimator) Estimate(nodes []*entities.FileNode) Estimate {
+	var estimate Estimate
+	for _, node := range nodes {
+		tokens := e.countFile(node)
+		estimate.Files = append(estimate.Files, FileEstimate{Path: node.Path, Tokens: tokens})
+		estimate.Total += tokens
+	}
+	return estimate
+}
+
+// Forget drops the cached counts for path so it is recounted on the next estimate
+func (e *Estimator) Forget(path string) {
+	for key := range e.cache {
+		if key == path || strings.HasPrefix(key, path+":") {
+			delete(e.cache, key)
+		}
+	}
+}
+
+// Reset drops every cached count so each file is recounted on its next estimate
+func (e *Estimator) Reset() {
+	e.cache = make(map[string]int)
+}
+
+// countFile returns the token count of a single file, or of its line ranges,
+// reading it at most once per selection of ranges
+func (e *Estimator) countFile(node *entities.FileNode) int {
+	key := linerange.Join(node.Path, node.Ranges)
+	if tokens, ok := e.cache[key]; ok {
+		return tokens
+	}
+	var text string
+	stub := false
+	if e.classifier != nil {
+		var class classify.Class
+		var err error
+		if text, class, err = e.classifier.ReadText(node.Path); err != nil {
+			return 0
+		}
+		stub = class.Kind != classify.Text
+	} else {
+		content, err := e.repo.ReadFile(node.Path)
+		if err != nil {
+			return 0
+		}
+		text = string(content)
+	}
+	if len(node.Ranges) > 0 && !stub {
+		var sections strings.Builder
+		for _, section := range linerange.Extract(text, node.Ranges) {
+			sections.WriteString(section.Content)
+		}
+		text = sections.String()
+	}
+	tokens := e.tokenizer.Count(text)
+	e.cache[key] = tokens
+	return tokens
+}
@@ -0,0 +1,115 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// Why test Estimator and Budget?
+//
+// The TUI re-renders on every keypress and asks for the selection's token
+// count each time, so the estimator must cache reads. The budget decides
+// whether a copy is refused, so its boundary (exactly at the limit) and its
+// "0 means unlimited" rule must not drift.
+
+// --- mock repository ---
+
+type mockFileRepo struct {
+	files map[string][]byte
+	reads int
+}
+
+func (m *mockFileRepo) GetCurrentDirectory() (string, error) { return "/project", nil }
+func (m *mockFileRepo) ReadDirectory(string) ([]repositories.DirEntry, error) {
+	return nil, nil
+}
+func (m *mockFileRepo) ReadFile(path string) ([]byte, error) {
+	m.reads++
+	content, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return content, nil
+}
+func (m *mockFileRepo) GetRelativePath(target, base string) (string, error) { return target, nil }
+func (m *mockFileRepo) WriteToClipboard(string) error                       { return nil }
+
+// Per-file counts must follow the given order and sum to the total; missing
+// files count as zero instead of failing the whole estimate.
+func TestEstimate_PerFileAndTotal(t *testing.T) {
+	repo := &mockFileRepo{files: map[string][]byte{
+		"/project/a.go": []byte("hello world"),
+		"/project/b.go": []byte("hello"),
+	}}
+	est := NewEstimator(repo, NewApproxTokenizer())
+
+	nodes := []*entities.FileNode{
+		entities.NewFileNode("a.go", "/project/a.go", false, nil),
+		entities.NewFileNode("gone.go", "/project/gone.go", false, nil),
+		entities.NewFileNode("b.go", "/project/b.go", false, nil),
+	}
+	got := est.Estimate(nodes)
+
+	if len(got.Files) != 3 || got.Files[0].Tokens != 2 || got.Files[1].Tokens != 0 || got.Files[2].Tokens != 1 {
+		t.Fatalf("unexpected per-file counts: %+v", got.Files)
+	}
+	if got.Total != 3 {
+		t.Fatalf("expected total 3, got %d", got.Total)
+	}
+}
+
+// Repeated estimates must not re-read files; Forget must force a re-read.
+func TestEstimate_CachesReads(t *testing.T) {
+	repo := &mockFileRepo{files: map[string][]byte{"/project/a.go": []byte("x")}}
+	est := NewEstimator(repo, NewApproxTokenizer())
+	nodes := []*entities.FileNode{entities.NewFileNode("a.go", "/project/a.go", false, nil)}
+
+	est.Estimate(nodes)
+	est.Estimate(nodes)
+	if repo.reads != 1 {
+		t.Fatalf("expected 1 read, got %d", repo.reads)
+	}
+
+	est.Forget("/project/a.go")
+	est.Estimate(nodes)
+	if repo.reads != 2 {
+		t.Fatalf("expected a re-read after Forget, got %d reads", repo.reads)
+	}
+}
+
+// A file selected by line ranges costs only the tokens of those lines.
+func TestEstimate_LineRanges(t *testing.T) {
+	repo := &mockFileRepo{files: map[string][]byte{"/project/a.go": []byte("one\ntwo three\nfour\n")}}
+	est := NewEstimator(repo, NewApproxTokenizer())
+	node := entities.NewFileNode("a.go", "/project/a.go", false, nil)
+
+	tokenizer := NewApproxTokenizer()
+	if got, want := est.Estimate([]*entities.FileNode{node}).Total, tokenizer.Count("one\ntwo three\nfour\n"); got != want {
+		t.Fatalf("whole file: expected %d tokens, got %d", want, got)
+	}
+	node.Ranges = []entities.LineRange{{Start: 2, End: 2}}
+	if got, want := est.Estimate([]*entities.FileNode{node}).Total, tokenizer.Count("two three\n"); got != want {
+		t.Fatalf("line 2: expected %d tokens, got %d", want, got)
+	}
+}
+
+// A payload exactly at the limit fits; one token over is refused only when
+// the budget refuses, and a zero budget never triggers.
+func TestBudget_Check(t *testing.T) {
+	if (Budget{Max: 10}).Exceeded(10) {
+		t.Fatal("total equal to the budget should fit")
+	}
+	if err := (Budget{Max: 10}).Check(11); err != nil {
+		t.Fatalf("warning-only budget should not refuse, got %v", err)
+	}
+	if err := (Budget{Max: 10, Refuse: true}).Check(11); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("strict budget should refuse with ErrBudgetExceeded, got %v", err)
+	}
+	if (Budget{}).Exceeded(1 << 30) {
+		t.Fatal("zero budget should be unlimited")
+	}
+}
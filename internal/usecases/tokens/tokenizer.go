@@ -0,0 +1,159 @@
+package tokens
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens a language model would see for a piece of text
+type Tokenizer interface {
+	// Name returns a short description of the tokenizer
+	Name() string
+
+	// Count returns the number of tokens in text
+	Count(text string) int
+}
+
+// ApproxTokenizer estimates token counts the way BPE tokenizers split text,
+// without needing a vocabulary file or network access.
+//
+// Text is first split into pre-tokens (words, digit groups, punctuation, and
+// whitespace) like GPT-style tokenizers do. Words are further split at
+// camelCase and snake_case boundaries and long pieces are charged one token
+// per few characters, since rare sub-words are rarely in the vocabulary.
+// The estimate is usually within 10-20% of real tokenizers for source code.
+type ApproxTokenizer struct{}
+
+// NewApproxTokenizer creates a new ApproxTokenizer
+func NewApproxTokenizer() *ApproxTokenizer {
+	return &ApproxTokenizer{}
+}
+
+// Name returns a short description of the tokenizer
+func (t *ApproxTokenizer) Name() string {
+	return "approx-bpe"
+}
+
+// commonOperators are multi-character punctuation sequences that BPE vocabularies store as one token
+var commonOperators = map[string]bool{
+	"==": true, "!=": true, ":=": true, "<=": true, ">=": true, "&&": true, "||": true,
+	"->": true, "=>": true, "<-": true, "++": true, "--": true, "//": true, "/*": true,
+	"*/": true, "::": true, "...": true, "===": true, "!==": true, "+=": true, "-=": true,
+	"){": true, "()": true, "{}": true, "[]": true, "\":": true, "\",": true, "();": true,
+}
+
+// maxWordPiece is the longest run of letters assumed to fit in a single token
+const maxWordPiece = 8
+
+// Count returns the estimated number of tokens in text
+func (t *ApproxTokenizer) Count(text string) int {
+	count := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n' || r == '\r':
+			// Consecutive line breaks and the indentation that follows merge into one token
+			j := i
+			for j < len(text) && (text[j] == '\n' || text[j] == '\r') {
+				j++
+			}
+			for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+				j++
+			}
+			count++
+			i = j
+
+		case r == ' ' || r == '\t':
+			// A single space is absorbed by the following token; longer runs cost about one token per 4 columns
+			j := i
+			for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+				j++
+			}
+			if run := j - i; run > 1 || j == len(text) {
+				count += (run + 3) / 4
+			}
+			i = j
+
+		case r == '.' && i+1 < len(text) && isWordByte(text[i+1]) && !isDigit(text[i+1]):
+			// A dot before an identifier merges with it (".Path")
+			i++
+
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || r == '_'):
+			j := i
+			for j < len(text) && isWordByte(text[j]) && !isDigit(text[j]) {
+				j++
+			}
+			count += countWord(text[i:j])
+			i = j
+
+		case r < utf8.RuneSelf && isDigit(text[i]):
+			// Digits are grouped in runs of up to three
+			j := i
+			for j < len(text) && isDigit(text[j]) {
+				j++
+			}
+			count += (j - i + 2) / 3
+			i = j
+
+		case r < utf8.RuneSelf:
+			// Punctuation: try the longest known operator first
+			matched := 1
+			for n := 3; n >= 2; n-- {
+				if i+n <= len(text) && commonOperators[text[i:i+n]] {
+					matched = n
+					break
+				}
+			}
+			count++
+			i += matched
+
+		default:
+			// Non-ASCII characters (CJK, emoji, accents) are usually one token or more each
+			count++
+			if size > 3 {
+				count++
+			}
+			i += size
+		}
+	}
+	return count
+}
+
+// countWord estimates the tokens in a run of letters and underscores
+func countWord(word string) int {
+	count := 0
+	start := 0
+	for k := 1; k <= len(word); k++ {
+		// Split at snake_case and camelCase boundaries
+		boundary := k == len(word) || word[k] == '_' ||
+			(isUpper(word[k]) && !isUpper(word[k-1]))
+		if !boundary {
+			continue
+		}
+		piece := len(word[start:k])
+		if piece > 0 {
+			count += (piece + maxWordPiece - 1) / maxWordPiece
+		}
+		start = k
+		if k < len(word) && word[k] == '_' {
+			// The underscore usually merges with the following piece
+			start = k + 1
+		}
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || isDigit(b) || (b|0x20 >= 'a' && b|0x20 <= 'z')
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isUpper(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
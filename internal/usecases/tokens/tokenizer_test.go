@@ -0,0 +1,65 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+)
+
+// Why test ApproxTokenizer?
+//
+// The estimate drives the budget check that can refuse a copy, so it must be
+// stable and roughly right. Wildly low counts let oversized payloads through;
+// wildly high counts block copies that would have fit. These tests check
+// orders of magnitude against known tokenizer behavior, not exact values.
+
+// Empty input must cost nothing, and a single common word one token.
+func TestApproxTokenizer_Basics(t *testing.T) {
+	tok := NewApproxTokenizer()
+	if got := tok.Count(""); got != 0 {
+		t.Fatalf("empty text should be 0 tokens, got %d", got)
+	}
+	if got := tok.Count("hello"); got != 1 {
+		t.Fatalf("a short word should be 1 token, got %d", got)
+	}
+	if got := tok.Count("hello world"); got != 2 {
+		t.Fatalf("two words should be 2 tokens, got %d", got)
+	}
+}
+
+// Identifiers split at case and underscore boundaries the way BPE splits them,
+// so long names cost more than one token.
+func TestApproxTokenizer_SplitsIdentifiers(t *testing.T) {
+	tok := NewApproxTokenizer()
+	if got := tok.Count("GetSelectedNodesInTreeOrder"); got < 5 {
+		t.Fatalf("camelCase identifier should cost several tokens, got %d", got)
+	}
+	if got := tok.Count("max_visible_rows"); got != 3 {
+		t.Fatalf("snake_case identifier should cost 3 tokens, got %d", got)
+	}
+}
+
+// For typical Go source the estimate should land near the common rule of
+// thumb of ~3-4 characters per token.
+func TestApproxTokenizer_SourceCodeRatio(t *testing.T) {
+	src := strings.Repeat(`func (fs *FileSelector) ToggleSelect(node *entities.FileNode) {
+	if !node.IsDir {
+		node.Selected = !node.Selected
+		if node.Selected {
+			fs.selection[node.Path] = node
+		}
+	}
+}
+`, 10)
+	got := NewApproxTokenizer().Count(src)
+	ratio := float64(len(src)) / float64(got)
+	if ratio < 2.5 || ratio > 5 {
+		t.Fatalf("expected 2.5-5 chars per token, got %.2f (%d tokens for %d chars)", ratio, got, len(src))
+	}
+}
+
+// CJK text has no spaces; each character is roughly one token or more.
+func TestApproxTokenizer_NonASCII(t *testing.T) {
+	if got := NewApproxTokenizer().Count("日本語のテキスト"); got < 8 {
+		t.Fatalf("expected at least one token per CJK character, got %d", got)
+	}
+}
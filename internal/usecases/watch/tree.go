@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// TreeRefresher rereads the parts of a file tree that changed on disk
+type TreeRefresher interface {
+	// Refresh rereads the entries of a directory, keeping the nodes of those
+	// that still exist, and returns the nodes removed
+	Refresh(node *entities.FileNode) []*entities.FileNode
+
+	// LoadSymbols rereads the declarations listed under a source file
+	LoadSymbols(node *entities.FileNode)
+
+	// ReloadIgnoreRules rereads the ignore rules of a directory and flags the nodes under it again
+	ReloadIgnoreRules(node *entities.FileNode)
+}
+
+// Apply updates the tree under root with a burst of changes: the directories
+// whose .gitignore changed flag their entries again with the new rules, the
+// directories whose entries changed are reread, or every loaded directory when
+// changes were lost, and expanded source files that were written list their
+// declarations afresh. Directories never loaded are left alone, as they are
+// read when first expanded. Expansion, selection, and the children of nodes
+// that still exist are kept. It returns the nodes removed from the tree.
+func Apply(tree TreeRefresher, root *entities.FileNode, batch Batch) []*entities.FileNode {
+	dirs := batch.Dirs
+	if batch.Lost {
+		dirs = loadedDirs(root)
+	}
+
+	for _, dir := range batch.Ignores {
+		if node := find(root, dir); node != nil && node.IsDir {
+			tree.ReloadIgnoreRules(node)
+		}
+	}
+
+	// Parents sort before their subdirectories, so a directory removed with its parent is not reread
+	var removed []*entities.FileNode
+	for _, dir := range dirs {
+		node := find(root, dir)
+		if node == nil || !node.IsDir || !isLoaded(node) {
+			continue
+		}
+		removed = append(removed, tree.Refresh(node)...)
+	}
+	for _, path := range batch.Written {
+		if node := find(root, path); node != nil && !node.IsDir && node.Expanded {
+			tree.LoadSymbols(node)
+		}
+	}
+	return removed
+}
+
+// find returns the node at a full path under root, or nil when it is not in the loaded tree
+func find(root *entities.FileNode, path string) *entities.FileNode {
+	rel, err := filepath.Rel(root.Path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	node := root
+	if rel == "." {
+		return node
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		var next *entities.FileNode
+		for _, child := range node.Children {
+			if child.Symbol == nil && child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// loadedDirs returns root and every directory under it whose entries were read, parents first
+func loadedDirs(root *entities.FileNode) []string {
+	var dirs []string
+	var traverse func(node *entities.FileNode)
+	traverse = func(node *entities.FileNode) {
+		if !node.IsDir || !isLoaded(node) {
+			return
+		}
+		dirs = append(dirs, node.Path)
+		for _, child := range node.Children {
+			traverse(child)
+		}
+	}
+	traverse(root)
+	return dirs
+}
+
+// isLoaded reports whether the entries of a directory were read: the root
+// always is, and other directories once expanded or given children
+func isLoaded(node *entities.FileNode) bool {
+	return node.Parent == nil || node.Expanded || len(node.Children) > 0
+}
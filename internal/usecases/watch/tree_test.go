@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// mockTree records the directories refreshed or flagged again and the files whose declarations were reloaded
+type mockTree struct {
+	refreshed []string
+	symbols   []string
+	ignores   []string
+	removed   map[string][]*entities.FileNode
+}
+
+func (m *mockTree) Refresh(node *entities.FileNode) []*entities.FileNode {
+	m.refreshed = append(m.refreshed, node.Path)
+	removed := m.removed[node.Path]
+	for _, gone := range removed {
+		var kept []*entities.FileNode
+		for _, child := range node.Children {
+			if child != gone {
+				kept = append(kept, child)
+			}
+		}
+		node.Children = kept
+	}
+	return removed
+}
+
+func (m *mockTree) LoadSymbols(node *entities.FileNode) {
+	m.symbols = append(m.symbols, node.Path)
+}
+
+func (m *mockTree) ReloadIgnoreRules(node *entities.FileNode) {
+	m.ignores = append(m.ignores, node.Path)
+}
+
+// buildTree creates /p with a loaded src/ holding an expanded main.go and a
+// subdirectory gen/, and an unloaded docs/
+func buildTree() (root, src, gen, main *entities.FileNode) {
+	root = entities.NewFileNode("p", "/p", true, nil)
+	src = entities.NewFileNode("src", "/p/src", true, root)
+	docs := entities.NewFileNode("docs", "/p/docs", true, root)
+	root.Children = []*entities.FileNode{src, docs}
+	main = entities.NewFileNode("main.go", "/p/src/main.go", false, src)
+	main.Expanded = true
+	gen = entities.NewFileNode("gen", "/p/src/gen", true, src)
+	gen.Expanded = true
+	src.Children = []*entities.FileNode{main, gen}
+	return root, src, gen, main
+}
+
+// Only directories already read are reread; a directory removed with its
+// parent is not reread, and only expanded files reload their declarations.
+func TestApply(t *testing.T) {
+	root, src, gen, main := buildTree()
+	tree := &mockTree{removed: map[string][]*entities.FileNode{"/p/src": {gen}}}
+
+	removed := Apply(tree, root, Batch{
+		Dirs:    []string{"/p/docs", "/p/src", "/p/src/gen", "/elsewhere"},
+		Written: []string{"/p/src/main.go", "/p/README.md"},
+	})
+
+	if !reflect.DeepEqual(tree.refreshed, []string{"/p/src"}) {
+		t.Fatalf("expected only src to be reread, got %v", tree.refreshed)
+	}
+	if len(removed) != 1 || removed[0] != gen {
+		t.Fatalf("expected gen to be removed, got %v", removed)
+	}
+	if !reflect.DeepEqual(tree.symbols, []string{main.Path}) || len(src.Children) != 1 {
+		t.Fatalf("expected main.go to reload its declarations, got %v", tree.symbols)
+	}
+}
+
+// When events were lost, every loaded directory is reread, parents first.
+func TestApply_LostRefreshesLoadedDirectories(t *testing.T) {
+	root, _, _, _ := buildTree()
+	tree := &mockTree{}
+
+	Apply(tree, root, Batch{Lost: true})
+
+	if want := []string{"/p", "/p/src", "/p/src/gen"}; !reflect.DeepEqual(tree.refreshed, want) {
+		t.Fatalf("expected %v to be reread, got %v", want, tree.refreshed)
+	}
+}
+
+// A changed .gitignore rereads the rules of its directory, before new entries
+// are flagged with them; unloaded directories have nothing to flag.
+func TestApply_ReloadsChangedIgnoreRules(t *testing.T) {
+	root, _, _, _ := buildTree()
+	tree := &mockTree{}
+
+	Apply(tree, root, Batch{Dirs: []string{"/p/src"}, Ignores: []string{"/p/docs/api", "/p/src"}})
+
+	if !reflect.DeepEqual(tree.ignores, []string{"/p/src"}) {
+		t.Fatalf("expected the rules of src to be reread, got %v", tree.ignores)
+	}
+}
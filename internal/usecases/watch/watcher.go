@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+	"github.com/makinzm/partial-tree-copy/internal/domain/repositories"
+)
+
+// DefaultSettle is how long the file system must be quiet before a burst of changes is reported
+const DefaultSettle = 100 * time.Millisecond
+
+// ignoreFile is the name of the files holding the ignore rules of their directory
+const ignoreFile = ".gitignore"
+
+// maxWait bounds how long a burst is collected while changes keep coming, e.g. during a build
+const maxWait = time.Second
+
+// Batch is a burst of changes to the watched directories
+type Batch struct {
+	Dirs    []string // Directories whose entries were created or removed, sorted
+	Written []string // Files whose contents were written, sorted
+	Ignores []string // Directories whose .gitignore was created, removed, or written, sorted
+	Lost    bool     // Changes were dropped, so any watched directory may have changed
+}
+
+// Watcher watches the directories of a file tree as they are read, and
+// reports their changes in bursts, so that a checkout or a code generator
+// touching many files refreshes the tree once
+type Watcher struct {
+	source  repositories.FileWatcher
+	settle  time.Duration
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+// NewWatcher creates a Watcher receiving changes from source
+func NewWatcher(source repositories.FileWatcher) *Watcher {
+	return &Watcher{
+		source:  source,
+		settle:  DefaultSettle,
+		watched: make(map[string]bool),
+	}
+}
+
+// SetSettle sets how long the file system must be quiet before a burst of changes is reported
+func (w *Watcher) SetSettle(settle time.Duration) {
+	w.settle = settle
+}
+
+// Watch starts watching the entries of the directory at path, unless it is
+// watched already. Git directories change with every git command, including
+// the status behind the change badges, so they are not watched.
+func (w *Watcher) Watch(path string) error {
+	if slices.Contains(strings.Split(filepath.ToSlash(path), "/"), ".git") {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[path] {
+		return nil
+	}
+	if err := w.source.Add(path); err != nil {
+		return err
+	}
+	w.watched[path] = true
+	return nil
+}
+
+// DirectoryLoaded watches a directory whose entries the navigator read.
+// A directory that cannot be watched, e.g. past the system's limit on
+// watches, is not refreshed.
+func (w *Watcher) DirectoryLoaded(node *entities.FileNode) {
+	_ = w.Watch(node.Path)
+}
+
+// Next waits for the next burst of changes. ok is false once the watcher is closed.
+func (w *Watcher) Next() (batch Batch, ok bool) {
+	events := w.source.Events()
+	event, ok := <-events
+	if !ok {
+		return Batch{}, false
+	}
+
+	dirs := make(map[string]bool)
+	written := make(map[string]bool)
+	ignores := make(map[string]bool)
+	lost := false
+	add := func(event entities.FileEvent) {
+		if filepath.Base(event.Path) == ignoreFile {
+			ignores[filepath.Dir(event.Path)] = true
+		}
+		switch event.Op {
+		case entities.FileCreated:
+			dirs[filepath.Dir(event.Path)] = true
+		case entities.FileRemoved:
+			dirs[filepath.Dir(event.Path)] = true
+			w.forget(event.Path)
+		case entities.FileWritten:
+			written[event.Path] = true
+		case entities.EventsLost:
+			lost = true
+		}
+	}
+	add(event)
+
+	quiet := time.NewTimer(w.settle)
+	defer quiet.Stop()
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+collect:
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				// Report what was collected; the next call reports the close
+				break collect
+			}
+			add(event)
+			quiet.Reset(w.settle)
+		case <-quiet.C:
+			break collect
+		case <-deadline.C:
+			break collect
+		}
+	}
+	return Batch{Dirs: sortedKeys(dirs), Written: sortedKeys(written), Ignores: sortedKeys(ignores), Lost: lost}, true
+}
+
+// forget drops the watches of a removed entry and of everything under it, as
+// the system drops them too, so the directory is watched again if it comes back
+func (w *Watcher) forget(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	prefix := path + string(os.PathSeparator)
+	for dir := range w.watched {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(w.watched, dir)
+		}
+	}
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.source.Close()
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
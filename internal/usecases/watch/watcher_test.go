@@ -0,0 +1,119 @@
+package watch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/makinzm/partial-tree-copy/internal/domain/entities"
+)
+
+// Why test the watcher?
+//
+// A checkout or a code generator touches hundreds of files at once. The tree
+// must be refreshed once per burst, not once per file, every directory read
+// must be watched exactly once, and a directory that is deleted and created
+// again must be watched again, or new files under it never show up.
+
+// mockSource delivers the events written to its channel
+type mockSource struct {
+	events chan entities.FileEvent
+	added  []string
+	fail   map[string]bool
+}
+
+func newMockSource() *mockSource {
+	return &mockSource{events: make(chan entities.FileEvent, 16)}
+}
+
+func (m *mockSource) Add(path string) error {
+	if m.fail[path] {
+		return errors.New("no space left on device")
+	}
+	m.added = append(m.added, path)
+	return nil
+}
+func (m *mockSource) Events() <-chan entities.FileEvent { return m.events }
+func (m *mockSource) Close() error {
+	close(m.events)
+	return nil
+}
+
+// A burst of events becomes one batch of the directories that gained or lost
+// entries and the files written, and closing the source ends the watch.
+func TestWatcher_Next_CollectsBurst(t *testing.T) {
+	source := newMockSource()
+	watcher := NewWatcher(source)
+	watcher.SetSettle(10 * time.Millisecond)
+	source.events <- entities.FileEvent{Op: entities.FileCreated, Path: "/p/src/new.go"}
+	source.events <- entities.FileEvent{Op: entities.FileWritten, Path: "/p/src/new.go"}
+	source.events <- entities.FileEvent{Op: entities.FileRemoved, Path: "/p/old.go"}
+	source.events <- entities.FileEvent{Op: entities.FileCreated, Path: "/p/src/other.go"}
+	watcher.Close()
+
+	batch, ok := watcher.Next()
+	want := Batch{Dirs: []string{"/p", "/p/src"}, Written: []string{"/p/src/new.go"}}
+	if !ok || !reflect.DeepEqual(batch, want) {
+		t.Fatalf("expected %+v, got %+v (ok=%v)", want, batch, ok)
+	}
+	if _, ok := watcher.Next(); ok {
+		t.Fatal("Next should report the end of a closed watcher")
+	}
+}
+
+// Lost events must be reported, so the whole loaded tree is reread.
+func TestWatcher_Next_ReportsLostEvents(t *testing.T) {
+	source := newMockSource()
+	watcher := NewWatcher(source)
+	watcher.SetSettle(10 * time.Millisecond)
+	source.events <- entities.FileEvent{Op: entities.EventsLost}
+
+	batch, ok := watcher.Next()
+	if !ok || !batch.Lost || batch.Dirs != nil {
+		t.Fatalf("expected a lost batch, got %+v", batch)
+	}
+}
+
+// A created, written, or removed .gitignore is reported with its directory,
+// so the rules read from it are dropped.
+func TestWatcher_Next_ReportsIgnoreFiles(t *testing.T) {
+	source := newMockSource()
+	watcher := NewWatcher(source)
+	watcher.SetSettle(10 * time.Millisecond)
+	source.events <- entities.FileEvent{Op: entities.FileWritten, Path: "/p/src/.gitignore"}
+	source.events <- entities.FileEvent{Op: entities.FileRemoved, Path: "/p/.gitignore"}
+	source.events <- entities.FileEvent{Op: entities.FileWritten, Path: "/p/src/main.go"}
+
+	batch, ok := watcher.Next()
+	if want := []string{"/p", "/p/src"}; !ok || !reflect.DeepEqual(batch.Ignores, want) {
+		t.Fatalf("expected ignore files in %v, got %+v", want, batch)
+	}
+}
+
+// Directories are watched once, git directories never; a removed directory and those under it are
+// forgotten so they are watched again when they come back, and a directory
+// that cannot be watched is tried again the next time it is read.
+func TestWatcher_Watch(t *testing.T) {
+	source := newMockSource()
+	source.fail = map[string]bool{"/p/full": true}
+	watcher := NewWatcher(source)
+	watcher.SetSettle(10 * time.Millisecond)
+
+	for _, dir := range []string{"/p", "/p/gen", "/p/gen/sub", "/p/generated", "/p", "/p/.git/refs"} {
+		watcher.DirectoryLoaded(&entities.FileNode{Path: dir, IsDir: true})
+	}
+	if err := watcher.Watch("/p/full"); err == nil {
+		t.Fatal("a failed watch should return its error")
+	}
+	source.events <- entities.FileEvent{Op: entities.FileRemoved, Path: "/p/gen"}
+	watcher.Next()
+	for _, dir := range []string{"/p", "/p/gen", "/p/gen/sub", "/p/generated"} {
+		watcher.DirectoryLoaded(&entities.FileNode{Path: dir, IsDir: true})
+	}
+
+	want := []string{"/p", "/p/gen", "/p/gen/sub", "/p/generated", "/p/gen", "/p/gen/sub"}
+	if !reflect.DeepEqual(source.added, want) {
+		t.Fatalf("expected watches %v, got %v", want, source.added)
+	}
+}